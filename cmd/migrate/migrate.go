@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/database"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/migrations"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up            apply all pending migrations
+  down          roll back the most recent migration
+  status        list migrations and whether they are applied
+  to <version>  migrate up or down to the given version, 0 rolls back everything
+
+The database is selected with the same STORAGE_DRIVER, POSTGRES_DSN and
+SQLITE_PATH variables as the server.
+`
+
+func main() {
+	command, err := parseCommand(os.Args[1:])
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n%s", err, usage)
+		os.Exit(2)
+	}
+
+	if err := run(command); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func parseCommand(args []string) (func(context.Context, *migrations.Migrator) error, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing command")
+	}
+
+	switch command := args[0]; {
+	case command == "up" && len(args) == 1:
+		return func(ctx context.Context, migrator *migrations.Migrator) error {
+			return migrator.Up(ctx)
+		}, nil
+	case command == "down" && len(args) == 1:
+		return func(ctx context.Context, migrator *migrations.Migrator) error {
+			return migrator.Down(ctx)
+		}, nil
+	case command == "status" && len(args) == 1:
+		return printStatus, nil
+	case command == "to" && len(args) == 2:
+		version, err := strconv.Atoi(args[1])
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", args[1])
+		}
+
+		return func(ctx context.Context, migrator *migrations.Migrator) error {
+			return migrator.To(ctx, version)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown command %q", strings.Join(args, " "))
+	}
+}
+
+func run(command func(context.Context, *migrations.Migrator) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	config := database.NewConfigFromEnv()
+	db, err := database.Open(config)
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, config.Driver)
+
+	if err != nil {
+		return err
+	}
+
+	return command(ctx, migrator)
+}
+
+func printStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			fmt.Fprintf(w, "%d\t%s\tpending\t-\n", status.Version, status.Name)
+			continue
+		}
+
+		fmt.Fprintf(w, "%d\t%s\tapplied\t%s\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	return w.Flush()
+}
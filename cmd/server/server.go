@@ -2,15 +2,21 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	_ "github.com/alekseyshevchenko93/go-crud-api-example/docs"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/database"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/handlers"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/idempotency"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/middlewares"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/migrations"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	_ "github.com/joho/godotenv/autoload"
@@ -20,8 +26,17 @@ import (
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
+const (
+	defaultIdempotencyTTL = 24 * time.Hour
+	defaultRetention      = 30 * 24 * time.Hour
+	defaultPurgeInterval  = time.Hour
+	// defaultShutdownTimeout is how long requests in flight may take to finish after a signal.
+	defaultShutdownTimeout = 10 * time.Second
+)
+
 // @title           Example CRUD API
 // @version         0.1
+// @description     Example CRUD API for portfolios entity
 // @termsOfService  http://swagger.io/terms/
 
 // @contact.name   API Support
@@ -34,6 +49,11 @@ import (
 // @host      localhost:8080
 // @BasePath  /api/v1
 
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 JWT bearer token, as "Bearer <token>"
+
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
@@ -48,16 +68,55 @@ func main() {
 		LogLevel:  log.ERROR,
 	}))
 
-	portfolioRepository := repository.NewPortfolioRepository()
-	portfolioService := services.NewPortfolioService(portfolioRepository)
+	authConfig := auth.NewConfigFromEnv()
+	authenticate, err := newAuthenticator(authConfig)
+
+	if err != nil {
+		panic(err)
+	}
+
+	e.Use(authenticate)
+	e.Use(middlewares.Actor)
+	e.Use(middlewares.Tenant)
+
+	portfolioStore, closeStore, err := newPortfolioStore(ctx, database.NewConfigFromEnv())
+
+	if err != nil {
+		panic(err)
+	}
+
+	portfolioService, err := newPortfolioService(portfolioStore, authConfig)
+
+	if err != nil {
+		panic(err)
+	}
+
+	idempotencyStore := idempotency.NewMemoryStore(idempotencyTTL())
 
 	e.GET("/portfolios/:id", handlers.NewGetPortfolioByIdHandler(portfolioService))
 	e.GET("/portfolios", handlers.NewGetPortfoliosHandler(portfolioService))
 	e.PUT("/portfolios/:id", handlers.NewUpdatePortfolioHandler(portfolioService))
-	e.POST("/portfolios", handlers.NewCreatePortfolioHandler(portfolioService))
+	e.PATCH("/portfolios/:id", handlers.NewPatchPortfolioHandler(portfolioService))
+	e.POST("/portfolios", handlers.NewCreatePortfolioHandler(portfolioService, idempotencyStore))
 	e.DELETE("/portfolios/:id", handlers.NewDeletePortfolioHandler(portfolioService))
+	e.POST("/portfolios/:id/restore", handlers.NewRestorePortfolioHandler(portfolioService))
+	e.GET("/portfolios/:id/history", handlers.NewGetPortfolioHistoryHandler(portfolioService))
+	e.GET("/portfolios/:id/diff", handlers.NewDiffPortfolioHandler(portfolioService))
+	e.GET("/portfolios/export", handlers.NewExportPortfoliosHandler(portfolioService))
+	e.POST("/portfolios/import", handlers.NewImportPortfoliosHandler(portfolioService))
+	e.POST("/portfolios\\:batch", handlers.NewBatchPortfoliosHandler(portfolioService))
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
+	e.GET("/health", handlers.NewHealthHandler())
+
+	purger := services.NewPortfolioPurger(portfolioStore, durationFromEnv("DELETED_RETENTION", defaultRetention), durationFromEnv("PURGE_INTERVAL", defaultPurgeInterval))
+	purgerCtx, stopPurger := context.WithCancel(ctx)
+	purgerDone := make(chan struct{})
+
+	go func() {
+		defer close(purgerDone)
+		purger.Run(purgerCtx)
+	}()
 
 	go func() {
 		httpPort := os.Getenv("HTTP_PORT")
@@ -70,5 +129,119 @@ func main() {
 
 	<-ctx.Done()
 
+	// The store is closed last, once no request or purge can write to it anymore.
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), durationFromEnv("SHUTDOWN_TIMEOUT", defaultShutdownTimeout))
+
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		e.Logger.Errorf("shutdown: %v", err)
+	}
+
+	cancelShutdown()
+	stopPurger()
+	<-purgerDone
+	closeStore()
+
 	os.Exit(0)
 }
+
+// newPortfolioStore picks the storage backend from STORAGE_DRIVER:
+// memory (default, durable when MEMORY_DATA_DIR is set), sqlite or postgres.
+func newPortfolioStore(ctx context.Context, config *database.Config) (repository.PortfolioStore, func(), error) {
+	if config.Driver == database.DriverMemory && config.MemoryDataDir == "" {
+		return repository.NewPortfolioStore(), func() {}, nil
+	}
+
+	if config.Driver == database.DriverMemory {
+		portfolioStore, err := repository.NewDurablePortfolioStore(config.MemoryDataDir, repository.DefaultSnapshotEvery)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return portfolioStore, func() { portfolioStore.Close() }, nil
+	}
+
+	db, err := database.Open(config)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if config.MigrateOnStartup {
+		if err := runMigrations(ctx, db, config.Driver); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+	}
+
+	closeDb := func() { db.Close() }
+
+	if config.Driver == database.DriverPostgres {
+		return repository.NewPostgresPortfolioStore(db), closeDb, nil
+	}
+
+	return repository.NewSQLitePortfolioStore(db), closeDb, nil
+}
+
+// newAuthenticator requires a valid bearer token on every route except the swagger UI
+// and the health check, unless AUTH_DISABLED is set.
+func newAuthenticator(config *auth.Config) (echo.MiddlewareFunc, error) {
+	if config.Disabled {
+		log.Warn("authentication is disabled, every request is let through")
+
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }, nil
+	}
+
+	verifier, err := auth.NewVerifier(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return middlewares.Authenticate(verifier, "/swagger/*", "/health"), nil
+}
+
+// newPortfolioService runs every operation on the portfolios of the tenant of the request, and only the
+// operations that the RBAC policy allows the roles of the authenticated principal, unless authentication is disabled.
+func newPortfolioService(portfolioStore repository.PortfolioStore, config *auth.Config) (services.PortfolioService, error) {
+	portfolioService := services.NewTenantPortfolioService(portfolioStore)
+
+	if config.Disabled {
+		return portfolioService, nil
+	}
+
+	policy, err := auth.LoadPolicyFile(config.PolicyFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return services.NewAuthorizedPortfolioService(portfolioService, policy), nil
+}
+
+// idempotencyTTL reads IDEMPOTENCY_TTL, for how long responses to requests
+// with an Idempotency-Key are replayed, as a Go duration such as 1h30m.
+func idempotencyTTL() time.Duration {
+	return durationFromEnv("IDEMPOTENCY_TTL", defaultIdempotencyTTL)
+}
+
+// durationFromEnv reads a positive Go duration such as 1h30m from the environment variable.
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+func runMigrations(ctx context.Context, db *sql.DB, driver string) error {
+	migrator, err := migrations.NewMigrator(db, driver)
+
+	if err != nil {
+		return err
+	}
+
+	return migrator.Up(ctx)
+}
@@ -25,28 +25,170 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/portfolios": {
+        "/health": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/portfolios": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "With Accept: application/x-ndjson all matching portfolios are streamed instead, one JSON object per line.\nThe stream ignores limit and withTotalCount and has no ETag.",
+                "produces": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
                 "tags": [
                     "Portfolios"
                 ],
-                "summary": "Get portfolios array",
+                "summary": "Get page of portfolios",
+                "parameters": [
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page, only valid with the same sort",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id",
+                        "description": "Comma separated fields, prefixed with - for descending order, e.g. -updatedAt,name. Ties are ordered by id",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching portfolios",
+                        "name": "withTotalCount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "List the portfolios as they were at this time, RFC 3339",
+                        "name": "asOf",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only active or inactive portfolios",
+                        "name": "isActive",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only finance or non-finance portfolios",
+                        "name": "isFinance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only internal or external portfolios",
+                        "name": "isInternal",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Created at or after, RFC 3339",
+                        "name": "createdFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Created before, RFC 3339",
+                        "name": "createdTo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Updated at or after, RFC 3339",
+                        "name": "updatedFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Updated before, RFC 3339",
+                        "name": "updatedTo",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 20,
+                        "type": "string",
+                        "description": "Name starts with, case-sensitive",
+                        "name": "namePrefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response, responds with 304 if the page did not change",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Portfolio"
+                            "$ref": "#/definitions/responses.PortfoliosResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Changes whenever any portfolio on the page changes"
                             }
                         }
+                    },
+                    "304": {
+                        "description": "Not Modified"
                     }
                 }
             },
             "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
@@ -70,6 +212,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/requests.UpdatePortfolioRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the portfolio, responds with 412 if it was changed",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -77,11 +231,23 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.Portfolio"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Portfolio version"
+                            }
                         }
                     }
                 }
             },
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "A retry with the same Idempotency-Key and body responds with the original response\nand the Idempotent-Replayed header instead of creating another portfolio.",
                 "produces": [
                     "application/json"
                 ],
@@ -98,6 +264,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/requests.CreatePortfolioRequest"
                         }
+                    },
+                    {
+                        "maxLength": 255,
+                        "type": "string",
+                        "description": "Unique key of the request, responds with 422 if it was used with a different body",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -105,6 +284,168 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.Portfolio"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Portfolio version"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/portfolios/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Streams all portfolios that match the filters, ordered by id. The file can be imported back.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Portfolios"
+                ],
+                "summary": "Export portfolios",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only active or inactive portfolios",
+                        "name": "isActive",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only finance or non-finance portfolios",
+                        "name": "isFinance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only internal or external portfolios",
+                        "name": "isInternal",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Created at or after, RFC 3339",
+                        "name": "createdFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Created before, RFC 3339",
+                        "name": "createdTo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Updated at or after, RFC 3339",
+                        "name": "updatedFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Updated before, RFC 3339",
+                        "name": "updatedTo",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 20,
+                        "type": "string",
+                        "description": "Name starts with, case-sensitive",
+                        "name": "namePrefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "id,name,isInternal,isFinance,isActive,createdAt,updatedAt,version",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/portfolios/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The file is sent as the body or as the field file of a multipart form. It starts with a header row,\nname is required, isInternal, isFinance and isActive are optional and empty values are false.\nThe read-only columns of an export are ignored. Rows are validated like a created portfolio,\ninvalid rows are reported with their line number and skipped. With mode upsert existing\nportfolios with the same name are updated, otherwise they fail with 409.",
+                "consumes": [
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Portfolios"
+                ],
+                "summary": "Import portfolios",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file, up to 10000 rows and 10 MB",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "create",
+                            "upsert"
+                        ],
+                        "type": "string",
+                        "default": "create",
+                        "description": "create or upsert",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate and report what would be done",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ImportPortfoliosResponse"
                         }
                     }
                 }
@@ -112,6 +453,11 @@ const docTemplate = `{
         },
         "/portfolios/{id}": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
@@ -126,6 +472,31 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Responds with the portfolio as it was at this time, RFC 3339, or 404 if it did not exist then",
+                        "name": "asOf",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response, responds with 304 if it still matches",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of a previous response, ignored with If-None-Match",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -133,12 +504,30 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.Portfolio"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Portfolio version, send it in If-Match to update or delete"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "Time of the last update"
+                            }
                         }
+                    },
+                    "304": {
+                        "description": "Not Modified"
                     }
                 }
             },
             "delete": {
-                "description": "Deletes portfolio",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft-deletes the portfolio, it can be restored until it is purged after the retention period.\nWith hard=true the portfolio is deleted permanently, deleted or not. This is meant for admins.",
                 "produces": [
                     "application/json"
                 ],
@@ -153,23 +542,405 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Delete permanently",
+                        "name": "hard",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the portfolio, responds with 412 if it was changed",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies a JSON merge patch (RFC 7396) or a JSON patch (RFC 6902) to name, isInternal, isFinance and isActive.\nWith a merge patch fields missing from the body keep their values, null resets a flag to false.\nA JSON patch is applied atomically, a failed test operation responds with 409.\nPatches that change id, createdAt, updatedAt or version are rejected.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Portfolios"
+                ],
+                "summary": "Partially updates portfolio",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Portfolio ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch object or array of JSON patch operations",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the portfolio, responds with 412 if it was changed",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Portfolio"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Portfolio version"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/portfolios/{id}/diff": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the name, flags and deletedAt of both versions where they differ, with the time each field got its new value.\nWith format=json-patch responds with a JSON patch (RFC 6902) that turns version from into version to instead.\nVersions of deleted and purged portfolios can be compared too.",
+                "produces": [
+                    "application/json",
+                    "application/json-patch+json"
+                ],
+                "tags": [
+                    "Portfolios"
+                ],
+                "summary": "Diff two versions of portfolio",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Portfolio ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Older version",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Newer version, at least from",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "json-patch"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.PortfolioDiffResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/portfolios/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Every create, update, delete, restore and purge is recorded with its actor, time, the portfolio before and after\nthe change and the changed fields, oldest first. The history is kept after the portfolio is purged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Portfolios"
+                ],
+                "summary": "Get history of portfolio by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Portfolio ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nextCursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response, responds with 304 if the page did not change",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.PortfolioHistoryResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Changes whenever an entry is added to the page"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    }
+                }
+            }
+        },
+        "/portfolios/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Responds with 409 if the portfolio is not deleted or its name was taken by another portfolio in the meantime.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Portfolios"
+                ],
+                "summary": "Restores deleted portfolio by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Portfolio ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Portfolio"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Portfolio version, send it in If-Match to update or delete"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/portfolios:batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Operations are validated like the single endpoints and applied in order, each result has the status\nthe single endpoint would respond with. An atomic batch applies all operations or none and responds\nwith the status of the failed operation, the other operations have the status 424.\nOtherwise the batch responds with 200 and every valid operation is applied on its own.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Portfolios"
+                ],
+                "summary": "Creates, updates and deletes portfolios in bulk",
+                "parameters": [
+                    {
+                        "description": "Up to 1000 operations",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.BatchPortfoliosRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant of the request, must match the tenant claim of the token",
+                        "name": "X-Tenant-Id",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/responses.BatchPortfoliosResponse"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "models.AuditChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "object"
+                },
+                "to": {
+                    "type": "object"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "after": {
+                    "$ref": "#/definitions/models.Portfolio"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "before": {
+                    "description": "Before is nil for created portfolios and After for purged ones.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Portfolio"
+                        }
+                    ]
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditChange"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "operation": {
+                    "$ref": "#/definitions/models.AuditOperation"
+                },
+                "portfolioId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AuditOperation": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "delete",
+                "restore",
+                "purge"
+            ],
+            "x-enum-varnames": [
+                "AuditCreate",
+                "AuditUpdate",
+                "AuditDelete",
+                "AuditRestore",
+                "AuditPurge"
+            ]
+        },
         "models.Portfolio": {
             "type": "object",
             "properties": {
                 "createdAt": {
                     "type": "string"
                 },
+                "deletedAt": {
+                    "description": "DeletedAt is set on soft-deleted portfolios, which are hidden until they are restored or purged.",
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -187,6 +958,60 @@ const docTemplate = `{
                 },
                 "updatedAt": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "requests.BatchPortfolioOperation": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "isActive": {
+                    "type": "boolean"
+                },
+                "isFinance": {
+                    "type": "boolean"
+                },
+                "isInternal": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "requests.BatchPortfoliosRequest": {
+            "type": "object",
+            "required": [
+                "operations"
+            ],
+            "properties": {
+                "atomic": {
+                    "description": "Atomic applies all operations or none of them.",
+                    "type": "boolean"
+                },
+                "operations": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/requests.BatchPortfolioOperation"
+                    }
                 }
             }
         },
@@ -235,6 +1060,162 @@ const docTemplate = `{
                     "maxLength": 20
                 }
             }
+        },
+        "responses.BatchPortfolioResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "portfolio": {
+                    "$ref": "#/definitions/models.Portfolio"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "responses.BatchPortfoliosResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/responses.BatchPortfolioResult"
+                    }
+                },
+                "succeeded": {
+                    "description": "Succeeded is false if any operation failed.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "responses.ImportPortfolioRow": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "portfolio": {
+                    "$ref": "#/definitions/models.Portfolio"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "responses.ImportPortfoliosResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "description": "Created, Updated and Failed count the rows, in a dry run the rows that would be created or updated.",
+                    "type": "integer"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/responses.ImportPortfolioRow"
+                    }
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "responses.PortfolioDiffResponse": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/responses.PortfolioFieldChange"
+                    }
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "portfolioId": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "responses.PortfolioFieldChange": {
+            "type": "object",
+            "properties": {
+                "changedAt": {
+                    "description": "ChangedAt is when the field got its value in version To.",
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "object"
+                },
+                "to": {
+                    "type": "object"
+                }
+            }
+        },
+        "responses.PortfolioHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditEntry"
+                    }
+                },
+                "nextCursor": {
+                    "description": "NextCursor is empty on the last page.",
+                    "type": "string"
+                }
+            }
+        },
+        "responses.PortfoliosResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Portfolio"
+                    }
+                },
+                "nextCursor": {
+                    "description": "NextCursor is empty on the last page.",
+                    "type": "string"
+                },
+                "totalCount": {
+                    "description": "TotalCount is only set when requested with withTotalCount=true.",
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "JWT bearer token, as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     },
     "externalDocs": {
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"os"
+	"strconv"
+)
+
+type Config struct {
+	// HS256Secret verifies HS256 tokens when set.
+	HS256Secret string
+	// JWKSFile is a JSON Web Key Set file with the RSA keys that verify RS256 tokens.
+	JWKSFile string
+	// Audience and Issuer, when set, must match the aud and iss claims.
+	Audience string
+	Issuer   string
+	// PolicyFile maps roles to permissions, see policy.json for the format and the default.
+	PolicyFile string
+	// Disabled lets every request through without a token and allows it everything.
+	Disabled bool
+}
+
+// NewConfigFromEnv reads JWT_HS256_SECRET, JWT_JWKS_FILE, JWT_AUDIENCE, JWT_ISSUER, RBAC_POLICY_FILE and AUTH_DISABLED.
+func NewConfigFromEnv() *Config {
+	disabled, _ := strconv.ParseBool(os.Getenv("AUTH_DISABLED"))
+
+	return &Config{
+		HS256Secret: os.Getenv("JWT_HS256_SECRET"),
+		JWKSFile:    os.Getenv("JWT_JWKS_FILE"),
+		Audience:    os.Getenv("JWT_AUDIENCE"),
+		Issuer:      os.Getenv("JWT_ISSUER"),
+		PolicyFile:  os.Getenv("RBAC_POLICY_FILE"),
+		Disabled:    disabled,
+	}
+}
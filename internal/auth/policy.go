@@ -0,0 +1,118 @@
+package auth
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Permission is an operation on portfolios that roles are granted.
+type Permission string
+
+const (
+	PermissionRead   Permission = "read"
+	PermissionCreate Permission = "create"
+	PermissionUpdate Permission = "update"
+	// PermissionDelete soft-deletes and restores portfolios.
+	PermissionDelete Permission = "delete"
+	// PermissionPurge deletes portfolios permanently.
+	PermissionPurge Permission = "purge"
+)
+
+var permissions = map[Permission]bool{
+	PermissionRead:   true,
+	PermissionCreate: true,
+	PermissionUpdate: true,
+	PermissionDelete: true,
+	PermissionPurge:  true,
+}
+
+//go:embed policy.json
+var defaultPolicy []byte
+
+// Policy decides which operations principals may run by their roles.
+type Policy interface {
+	Allows(*Principal, Permission) bool
+}
+
+type rolePolicy struct {
+	roles map[string]map[Permission]bool
+}
+
+// policyFile maps role names to the permissions they grant.
+type policyFile struct {
+	Roles map[string][]Permission `json:"roles"`
+}
+
+// DefaultPolicy grants viewers read, editors read, create and update, and admins every permission.
+func DefaultPolicy() Policy {
+	policy, err := parsePolicy(defaultPolicy)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return policy
+}
+
+// LoadPolicyFile reads a policy from a JSON file in the format of policy.json,
+// or returns the default policy when path is empty.
+func LoadPolicyFile(path string) (Policy, error) {
+	if path == "" {
+		return DefaultPolicy(), nil
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := parsePolicy(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("decode policy %s: %w", path, err)
+	}
+
+	return policy, nil
+}
+
+func parsePolicy(data []byte) (*rolePolicy, error) {
+	var file policyFile
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	policy := &rolePolicy{roles: map[string]map[Permission]bool{}}
+
+	for role, granted := range file.Roles {
+		policy.roles[role] = map[Permission]bool{}
+
+		for _, permission := range granted {
+			if !permissions[permission] {
+				return nil, fmt.Errorf("role %q has unknown permission %q", role, permission)
+			}
+
+			policy.roles[role][permission] = true
+		}
+	}
+
+	return policy, nil
+}
+
+// Allows reports whether any role of the principal grants the permission. Unknown roles grant nothing.
+func (p *rolePolicy) Allows(principal *Principal, permission Permission) bool {
+	if principal == nil {
+		return false
+	}
+
+	for _, role := range principal.Roles {
+		if p.roles[role][permission] {
+			return true
+		}
+	}
+
+	return false
+}
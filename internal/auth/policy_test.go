@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := DefaultPolicy()
+
+	tt := map[string][]Permission{
+		"viewer":  {PermissionRead},
+		"editor":  {PermissionRead, PermissionCreate, PermissionUpdate},
+		"admin":   {PermissionRead, PermissionCreate, PermissionUpdate, PermissionDelete, PermissionPurge},
+		"auditor": {},
+	}
+
+	for role, granted := range tt {
+		principal := &Principal{Subject: "alice", Roles: []string{role}}
+
+		for permission := range permissions {
+			assert.Equal(t, contains(granted, permission), policy.Allows(principal, permission), "%s %s", role, permission)
+		}
+	}
+
+	assert.False(t, policy.Allows(nil, PermissionRead))
+	assert.False(t, policy.Allows(&Principal{Subject: "alice"}, PermissionRead))
+	assert.True(t, policy.Allows(&Principal{Subject: "alice", Roles: []string{"viewer", "editor"}}, PermissionUpdate))
+}
+
+func TestLoadPolicyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"roles": {"auditor": ["read"], "janitor": ["purge"]}}`), 0o644))
+
+	policy, err := LoadPolicyFile(path)
+	require.NoError(t, err)
+
+	assert.True(t, policy.Allows(&Principal{Roles: []string{"auditor"}}, PermissionRead))
+	assert.False(t, policy.Allows(&Principal{Roles: []string{"janitor"}}, PermissionDelete))
+	assert.True(t, policy.Allows(&Principal{Roles: []string{"janitor"}}, PermissionPurge))
+	assert.False(t, policy.Allows(&Principal{Roles: []string{"admin"}}, PermissionRead))
+
+	policy, err = LoadPolicyFile("")
+	require.NoError(t, err)
+	assert.True(t, policy.Allows(&Principal{Roles: []string{"admin"}}, PermissionPurge))
+}
+
+func TestLoadPolicyFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadPolicyFile(filepath.Join(dir, "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	tt := []string{`{"roles": {"viewer": ["read", "approve"]}}`, `{"roles": []}`, `not json`}
+
+	for _, data := range tt {
+		path := filepath.Join(dir, "policy.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+		_, err := LoadPolicyFile(path)
+		assert.Error(t, err, data)
+	}
+}
+
+func contains(permissions []Permission, permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,53 @@
+package auth
+
+import "context"
+
+const (
+	// ScopeFinance lets principals see and change finance portfolios.
+	ScopeFinance = "finance"
+	// ScopeInternal marks internal principals, which can see internal portfolios. Principals without it are external.
+	ScopeInternal = "internal"
+)
+
+// Principal is the authenticated caller of a request, taken from its bearer token.
+type Principal struct {
+	// Subject is the sub claim of the token.
+	Subject string
+	// Roles is the roles claim of the token, a list of role names or a single one.
+	Roles []string
+	// Scopes is the space-separated scope claim of the token, or its scp claim.
+	Scopes []string
+	// Tenant is the tenant claim of the token, empty if it has none.
+	Tenant string
+	// Claims holds all claims of the token.
+	Claims map[string]interface{}
+}
+
+// HasScope reports whether the token of the principal was granted the scope.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a context that carries the authenticated principal.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal authenticated for the context, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+
+	return principal, ok && principal != nil
+}
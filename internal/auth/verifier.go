@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MinHS256SecretLength is the length of the shortest accepted HS256 secret in bytes,
+// the size of the SHA-256 output as recommended by RFC 7518.
+const MinHS256SecretLength = 32
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrNoKeys       = errors.New("no JWT keys configured, set JWT_HS256_SECRET or JWT_JWKS_FILE")
+	ErrWeakSecret   = fmt.Errorf("JWT_HS256_SECRET must be at least %d bytes long", MinHS256SecretLength)
+)
+
+// Verifier authenticates the callers of requests by their bearer tokens.
+type Verifier interface {
+	Verify(token string) (*Principal, error)
+}
+
+type jwtVerifier struct {
+	parser  *jwt.Parser
+	secret  []byte
+	rsaKeys map[string]*rsa.PublicKey
+}
+
+// NewVerifier accepts HS256 tokens signed with the configured secret and RS256 tokens
+// signed with a key from the configured JWKS file. Each key only verifies its own algorithm.
+// Secrets shorter than MinHS256SecretLength are rejected with ErrWeakSecret.
+func NewVerifier(config *Config) (Verifier, error) {
+	verifier := &jwtVerifier{}
+	methods := make([]string, 0, 2)
+
+	if config.HS256Secret != "" {
+		if len(config.HS256Secret) < MinHS256SecretLength {
+			return nil, ErrWeakSecret
+		}
+
+		verifier.secret = []byte(config.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if config.JWKSFile != "" {
+		keys, err := readJWKSFile(config.JWKSFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		verifier.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(methods) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	// Tokens must not live forever, so exp is required and not only checked when present.
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+
+	verifier.parser = jwt.NewParser(options...)
+
+	return verifier, nil
+}
+
+// Verify checks the signature of the token, that it has not expired and is already valid,
+// and its audience and issuer when they are configured.
+func (v *jwtVerifier) Verify(tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.key); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, _ := claims["sub"].(string)
+
+	if subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+
+	principal := &Principal{Subject: subject, Roles: stringList(claims["roles"]), Claims: claims}
+
+	if tenant, ok := claims["tenant"]; ok {
+		if principal.Tenant, ok = tenant.(string); !ok || principal.Tenant == "" {
+			return nil, fmt.Errorf("%w: tenant claim is not a string", ErrInvalidToken)
+		}
+	}
+
+	if scope, ok := claims["scope"].(string); ok {
+		principal.Scopes = strings.Fields(scope)
+	} else {
+		principal.Scopes = stringList(claims["scp"])
+	}
+
+	return principal, nil
+}
+
+// stringList reads a claim that holds a string or a list of strings, other values are skipped.
+func stringList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := make([]string, 0, len(value))
+
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+
+		return list
+	default:
+		return nil
+	}
+}
+
+func (v *jwtVerifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method {
+	case jwt.SigningMethodHS256:
+		return v.secret, nil
+	case jwt.SigningMethodRS256:
+		kid, _ := token.Header["kid"].(string)
+
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+
+		// A token without a kid can only be meant for the only key there is.
+		if kid == "" && len(v.rsaKeys) == 1 {
+			for _, key := range v.rsaKeys {
+				return key, nil
+			}
+		}
+
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// readJWKSFile reads the RSA signing keys of a JSON Web Key Set by their key id.
+// Keys of other types or uses are skipped.
+func readJWKSFile(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode JWKS %s: %w", path, err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") || (key.Alg != "" && key.Alg != jwt.SigningMethodRS256.Alg()) {
+			continue
+		}
+
+		publicKey, err := rsaPublicKey(key)
+
+		if err != nil {
+			return nil, fmt.Errorf("decode JWKS %s key %q: %w", path, key.Kid, err)
+		}
+
+		keys[key.Kid] = publicKey
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS %s has no RS256 keys", path)
+	}
+
+	return keys, nil
+}
+
+func rsaPublicKey(key jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA modulus or exponent")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
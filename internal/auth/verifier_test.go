@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/suite"
+)
+
+const testSecret = "test-secret-of-at-least-32-bytes"
+
+type VerifierSuite struct {
+	suite.Suite
+	rsaKey   *rsa.PrivateKey
+	jwksFile string
+}
+
+func TestVerifierSuite(t *testing.T) {
+	suite.Run(t, new(VerifierSuite))
+}
+
+func (suite *VerifierSuite) SetupSuite() {
+	r := suite.Require()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+
+	suite.rsaKey = key
+	suite.jwksFile = suite.writeJWKS(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "EC", "kid": "ec-key", "crv": "P-256"},
+			{
+				"kty": "RSA",
+				"kid": "rsa-key",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (suite *VerifierSuite) writeJWKS(set interface{}) string {
+	data, err := json.Marshal(set)
+	suite.Require().NoError(err)
+
+	path := filepath.Join(suite.T().TempDir(), "jwks.json")
+	suite.Require().NoError(os.WriteFile(path, data, 0o644))
+
+	return path
+}
+
+func (suite *VerifierSuite) verifier(config *Config) Verifier {
+	verifier, err := NewVerifier(config)
+	suite.Require().NoError(err)
+
+	return verifier
+}
+
+// claims are valid claims that the tests override.
+func claims(overrides jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": "alice",
+		"iss": "https://auth.example.com/",
+		"aud": "portfolios-api",
+		"iat": now.Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+
+	return claims
+}
+
+func (suite *VerifierSuite) hs256(claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	suite.Require().NoError(err)
+
+	return token
+}
+
+func (suite *VerifierSuite) rs256(kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(suite.rsaKey)
+	suite.Require().NoError(err)
+
+	return signed
+}
+
+func (suite *VerifierSuite) TestVerifyHS256() {
+	r := suite.Require()
+	verifier := suite.verifier(&Config{HS256Secret: testSecret, Audience: "portfolios-api", Issuer: "https://auth.example.com/"})
+
+	principal, err := verifier.Verify(suite.hs256(claims(nil)))
+	r.NoError(err)
+	r.Equal("alice", principal.Subject)
+	r.Equal("https://auth.example.com/", principal.Claims["iss"])
+
+	r.Empty(principal.Roles)
+
+	principal, err = verifier.Verify(suite.hs256(claims(jwt.MapClaims{"aud": []string{"other-api", "portfolios-api"}})))
+	r.NoError(err)
+	r.Equal("alice", principal.Subject)
+}
+
+func (suite *VerifierSuite) TestVerifyRoles() {
+	r := suite.Require()
+	verifier := suite.verifier(&Config{HS256Secret: testSecret})
+
+	principal, err := verifier.Verify(suite.hs256(claims(jwt.MapClaims{"roles": []interface{}{"viewer", 42, "editor"}})))
+	r.NoError(err)
+	r.Equal([]string{"viewer", "editor"}, principal.Roles)
+
+	principal, err = verifier.Verify(suite.hs256(claims(jwt.MapClaims{"roles": "admin"})))
+	r.NoError(err)
+	r.Equal([]string{"admin"}, principal.Roles)
+}
+
+func (suite *VerifierSuite) TestVerifyRS256() {
+	r := suite.Require()
+	verifier := suite.verifier(&Config{JWKSFile: suite.jwksFile})
+
+	principal, err := verifier.Verify(suite.rs256("rsa-key", claims(nil)))
+	r.NoError(err)
+	r.Equal("alice", principal.Subject)
+
+	// The only RSA key of the set verifies tokens without a key id.
+	_, err = verifier.Verify(suite.rs256("", claims(nil)))
+	r.NoError(err)
+
+	_, err = verifier.Verify(suite.rs256("unknown-key", claims(nil)))
+	r.ErrorIs(err, ErrInvalidToken)
+}
+
+func (suite *VerifierSuite) TestVerifyRejectsInvalidClaims() {
+	verifier := suite.verifier(&Config{HS256Secret: testSecret, Audience: "portfolios-api", Issuer: "https://auth.example.com/"})
+	now := time.Now()
+
+	tt := map[string]jwt.MapClaims{
+		"expired":        {"exp": now.Add(-time.Minute).Unix()},
+		"without expiry": {"exp": nil},
+		"not yet valid":  {"nbf": now.Add(time.Minute).Unix()},
+		"other audience": {"aud": "other-api"},
+		"no audience":    {"aud": nil},
+		"other issuer":   {"iss": "https://evil.example.com/"},
+		"no issuer":      {"iss": nil},
+		"no subject":     {"sub": nil},
+		"numeric tenant": {"tenant": 42},
+		"empty tenant":   {"tenant": ""},
+	}
+
+	for name, overrides := range tt {
+		_, err := verifier.Verify(suite.hs256(claims(overrides)))
+		suite.ErrorIs(err, ErrInvalidToken, name)
+	}
+}
+
+func (suite *VerifierSuite) TestVerifyRejectsInvalidSignatures() {
+	r := suite.Require()
+	verifier := suite.verifier(&Config{JWKSFile: suite.jwksFile})
+
+	tampered := suite.rs256("rsa-key", claims(nil))
+	tampered = tampered[:len(tampered)-4] + "AAAA"
+	_, err := verifier.Verify(tampered)
+	r.ErrorIs(err, ErrInvalidToken)
+
+	// HS256 is not accepted without a secret, even if signed with the public key.
+	publicKey, err := json.Marshal(suite.rsaKey.PublicKey)
+	r.NoError(err)
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)).SignedString(publicKey)
+	r.NoError(err)
+	_, err = verifier.Verify(hmacToken)
+	r.ErrorIs(err, ErrInvalidToken)
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	r.NoError(err)
+	_, err = verifier.Verify(unsigned)
+	r.ErrorIs(err, ErrInvalidToken)
+
+	_, err = suite.verifier(&Config{HS256Secret: "other-secret-of-at-least-32-bytes"}).Verify(suite.hs256(claims(nil)))
+	r.ErrorIs(err, ErrInvalidToken)
+
+	_, err = verifier.Verify("not-a-token")
+	r.ErrorIs(err, ErrInvalidToken)
+}
+
+func (suite *VerifierSuite) TestNewVerifierErrors() {
+	r := suite.Require()
+
+	_, err := NewVerifier(&Config{})
+	r.ErrorIs(err, ErrNoKeys)
+
+	_, err = NewVerifier(&Config{HS256Secret: testSecret[:MinHS256SecretLength-1]})
+	r.ErrorIs(err, ErrWeakSecret)
+
+	_, err = NewVerifier(&Config{JWKSFile: filepath.Join(suite.T().TempDir(), "missing.json")})
+	r.ErrorIs(err, os.ErrNotExist)
+
+	_, err = NewVerifier(&Config{JWKSFile: suite.writeJWKS(map[string]interface{}{"keys": []interface{}{}})})
+	r.Error(err)
+
+	_, err = NewVerifier(&Config{JWKSFile: suite.writeJWKS(map[string]interface{}{
+		"keys": []map[string]string{{"kty": "RSA", "kid": "bad", "n": "!!", "e": "AQAB"}},
+	})})
+	r.Error(err)
+}
+
+func (suite *VerifierSuite) TestVerifyScopes() {
+	r := suite.Require()
+	verifier := suite.verifier(&Config{HS256Secret: testSecret})
+
+	principal, err := verifier.Verify(suite.hs256(claims(jwt.MapClaims{"scope": "internal  finance"})))
+	r.NoError(err)
+	r.Equal([]string{ScopeInternal, ScopeFinance}, principal.Scopes)
+	r.True(principal.HasScope(ScopeFinance))
+
+	principal, err = verifier.Verify(suite.hs256(claims(jwt.MapClaims{"scp": []interface{}{"internal"}})))
+	r.NoError(err)
+	r.True(principal.HasScope(ScopeInternal))
+	r.False(principal.HasScope(ScopeFinance))
+
+	principal, err = verifier.Verify(suite.hs256(claims(nil)))
+	r.NoError(err)
+	r.Empty(principal.Scopes)
+}
+
+func (suite *VerifierSuite) TestVerifyTenant() {
+	r := suite.Require()
+	verifier := suite.verifier(&Config{HS256Secret: testSecret})
+
+	principal, err := verifier.Verify(suite.hs256(claims(jwt.MapClaims{"tenant": "tenant-a"})))
+	r.NoError(err)
+	r.Equal("tenant-a", principal.Tenant)
+
+	principal, err = verifier.Verify(suite.hs256(claims(nil)))
+	r.NoError(err)
+	r.Empty(principal.Tenant)
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DriverMemory   = "memory"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+const defaultSQLitePath = "portfolios.db"
+
+type Config struct {
+	Driver           string
+	PostgresDSN      string
+	SQLitePath       string
+	MigrateOnStartup bool
+	// MemoryDataDir makes the memory driver durable when set.
+	MemoryDataDir string
+}
+
+// NewConfigFromEnv reads STORAGE_DRIVER, POSTGRES_DSN, SQLITE_PATH, MIGRATE_ON_STARTUP
+// and MEMORY_DATA_DIR. The driver defaults to in-memory storage.
+func NewConfigFromEnv() *Config {
+	migrateOnStartup, _ := strconv.ParseBool(os.Getenv("MIGRATE_ON_STARTUP"))
+
+	config := &Config{
+		Driver:           os.Getenv("STORAGE_DRIVER"),
+		PostgresDSN:      os.Getenv("POSTGRES_DSN"),
+		SQLitePath:       os.Getenv("SQLITE_PATH"),
+		MigrateOnStartup: migrateOnStartup,
+		MemoryDataDir:    os.Getenv("MEMORY_DATA_DIR"),
+	}
+
+	if config.Driver == "" {
+		config.Driver = DriverMemory
+	}
+
+	if config.SQLitePath == "" {
+		config.SQLitePath = defaultSQLitePath
+	}
+
+	return config
+}
+
+// Open connects to the SQL database described by config.
+func Open(config *Config) (*sql.DB, error) {
+	switch config.Driver {
+	case DriverPostgres:
+		return OpenPostgres(config.PostgresDSN)
+	case DriverSQLite:
+		return OpenSQLite(config.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unknown sql storage driver %q", config.Driver)
+	}
+}
+
+func OpenPostgres(dsn string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("POSTGRES_DSN is required for the %s driver", DriverPostgres)
+	}
+
+	return ping(sql.Open("postgres", dsn))
+}
+
+// OpenSQLite opens the database file at path, creating it if needed.
+// Timestamps are stored in a sortable text format, writers wait for each other
+// instead of failing with SQLITE_BUSY, and transactions take the write lock upfront.
+func OpenSQLite(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Set("_time_format", "sqlite")
+	params.Set("_txlock", "immediate")
+
+	return ping(sql.Open("sqlite", fmt.Sprintf("file:%s?%s", path, params.Encode())))
+}
+
+func ping(db *sql.DB, err error) (*sql.DB, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditOperation string
+
+const (
+	AuditCreate  AuditOperation = "create"
+	AuditUpdate  AuditOperation = "update"
+	AuditDelete  AuditOperation = "delete"
+	AuditRestore AuditOperation = "restore"
+	AuditPurge   AuditOperation = "purge"
+)
+
+// AuditEntry is the immutable record of one change of a portfolio.
+type AuditEntry struct {
+	Id          int            `json:"id"`
+	PortfolioId int            `json:"portfolioId"`
+	Actor       string         `json:"actor"`
+	Operation   AuditOperation `json:"operation"`
+	At          time.Time      `json:"at"`
+	// Before is nil for created portfolios and After for purged ones.
+	Before  *Portfolio    `json:"before"`
+	After   *Portfolio    `json:"after"`
+	Changes []AuditChange `json:"changes"`
+}
+
+// AuditChange is a changed field with its JSON values, null where the portfolio did not exist.
+type AuditChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from" swaggertype:"object"`
+	To    json.RawMessage `json:"to" swaggertype:"object"`
+}
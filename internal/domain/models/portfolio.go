@@ -10,4 +10,7 @@ type Portfolio struct {
 	IsActive   bool       `json:"isActive"`
 	CreatedAt  *time.Time `json:"createdAt"`
 	UpdatedAt  *time.Time `json:"updatedAt"`
+	Version    int        `json:"version"`
+	// DeletedAt is set on soft-deleted portfolios, which are hidden until they are restored or purged.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
@@ -1,5 +1,7 @@
 package requests
 
+import "time"
+
 type CreatePortfolioRequest struct {
 	Name       string `json:"name" validate:"required,lte=20"`
 	IsInternal bool   `json:"isInternal"`
@@ -14,3 +16,75 @@ type UpdatePortfolioRequest struct {
 	IsFinance  bool   `json:"isFinance"`
 	IsActive   bool   `json:"isActive"`
 }
+
+type BatchPortfoliosRequest struct {
+	// Atomic applies all operations or none of them.
+	Atomic     bool                      `json:"atomic"`
+	Operations []BatchPortfolioOperation `json:"operations" validate:"required,min=1,max=1000"`
+}
+
+// BatchPortfolioOperation creates, updates or deletes one portfolio.
+// Create and update take the fields of CreatePortfolioRequest and UpdatePortfolioRequest,
+// update and delete take the id and optionally the version that the portfolio must have.
+type BatchPortfolioOperation struct {
+	Op         string `json:"op" enums:"create,update,delete"`
+	Id         int    `json:"id,omitempty"`
+	Version    int    `json:"version,omitempty"`
+	Name       string `json:"name,omitempty"`
+	IsInternal bool   `json:"isInternal,omitempty"`
+	IsFinance  bool   `json:"isFinance,omitempty"`
+	IsActive   bool   `json:"isActive,omitempty"`
+}
+
+type GetPortfoliosRequest struct {
+	Limit          int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor         string `query:"cursor"`
+	Sort           string `query:"sort"`
+	WithTotalCount bool   `query:"withTotalCount"`
+	// AsOf lists the portfolios as they were at this time.
+	AsOf *time.Time `query:"asOf"`
+
+	PortfolioFilterRequest
+}
+
+type GetPortfolioRequest struct {
+	// AsOf reads the portfolio as it was at this time.
+	AsOf *time.Time `query:"asOf"`
+}
+
+type GetPortfolioHistoryRequest struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor"`
+}
+
+type GetPortfolioDiffRequest struct {
+	From int `query:"from" validate:"required,min=1"`
+	To   int `query:"to" validate:"required,min=1,gtefield=From"`
+	// Format json-patch renders the diff as a JSON patch that turns version From into version To.
+	Format string `query:"format" validate:"omitempty,oneof=json json-patch"`
+}
+
+type ExportPortfoliosRequest struct {
+	Format string `query:"format" validate:"omitempty,oneof=csv"`
+
+	PortfolioFilterRequest
+}
+
+type ImportPortfoliosRequest struct {
+	// DryRun validates the file and reports what would change without changing anything.
+	DryRun bool `query:"dryRun"`
+	// Mode create fails rows with existing names, upsert updates the portfolios with these names.
+	Mode string `query:"mode" validate:"omitempty,oneof=create upsert"`
+}
+
+// PortfolioFilterRequest holds the list filters shared by the endpoints that read many portfolios.
+type PortfolioFilterRequest struct {
+	IsActive    *bool      `query:"isActive"`
+	IsFinance   *bool      `query:"isFinance"`
+	IsInternal  *bool      `query:"isInternal"`
+	CreatedFrom *time.Time `query:"createdFrom"`
+	CreatedTo   *time.Time `query:"createdTo"`
+	UpdatedFrom *time.Time `query:"updatedFrom"`
+	UpdatedTo   *time.Time `query:"updatedTo"`
+	NamePrefix  string     `query:"namePrefix" validate:"lte=20"`
+}
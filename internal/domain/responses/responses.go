@@ -0,0 +1,75 @@
+package responses
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+type PortfoliosResponse struct {
+	Items []*models.Portfolio `json:"items"`
+	// NextCursor is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+	// TotalCount is only set when requested with withTotalCount=true.
+	TotalCount *int `json:"totalCount,omitempty"`
+}
+
+// PortfolioHistoryResponse lists audit entries oldest first.
+type PortfolioHistoryResponse struct {
+	Items []*models.AuditEntry `json:"items"`
+	// NextCursor is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// PortfolioDiffResponse lists the fields that differ between two versions of a portfolio.
+type PortfolioDiffResponse struct {
+	PortfolioId int                     `json:"portfolioId"`
+	From        int                     `json:"from"`
+	To          int                     `json:"to"`
+	Changes     []*PortfolioFieldChange `json:"changes"`
+}
+
+// PortfolioFieldChange has the JSON values of a field in both versions.
+type PortfolioFieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from" swaggertype:"object"`
+	To    json.RawMessage `json:"to" swaggertype:"object"`
+	// ChangedAt is when the field got its value in version To.
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+type BatchPortfoliosResponse struct {
+	// Succeeded is false if any operation failed.
+	Succeeded bool                    `json:"succeeded"`
+	Results   []*BatchPortfolioResult `json:"results"`
+}
+
+// BatchPortfolioResult has the status code that the single endpoint would respond with
+// and the portfolio or the error message. Operations of a failed atomic batch
+// that were not the cause of the failure have the status 424 Failed Dependency.
+type BatchPortfolioResult struct {
+	Status    int               `json:"status"`
+	Portfolio *models.Portfolio `json:"portfolio,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type ImportPortfoliosResponse struct {
+	DryRun bool `json:"dryRun"`
+	// Created, Updated and Failed count the rows, in a dry run the rows that would be created or updated.
+	Created int                   `json:"created"`
+	Updated int                   `json:"updated"`
+	Failed  int                   `json:"failed"`
+	Rows    []*ImportPortfolioRow `json:"rows"`
+}
+
+// ImportPortfolioRow reports one row of an imported file by its line number. Action is create or update
+// for valid rows, Status is what the single endpoint would respond with, and Portfolio is set unless in a dry run.
+type ImportPortfolioRow struct {
+	Line      int               `json:"line"`
+	Name      string            `json:"name"`
+	Action    string            `json:"action,omitempty"`
+	Status    int               `json:"status"`
+	Portfolio *models.Portfolio `json:"portfolio,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
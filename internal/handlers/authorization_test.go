@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/idempotency"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+// authorizationCase is a request to a handler that succeeds when the role is allowed to make it.
+type authorizationCase struct {
+	name        string
+	method      string
+	path        string
+	contentType string
+	body        string
+	// allowed are the roles of the default policy that may make the request.
+	allowed []string
+	handler func(services.PortfolioService) func(echo.Context) error
+}
+
+var (
+	everyRole  = []string{"viewer", "editor", "admin"}
+	editorRole = []string{"editor", "admin"}
+	adminRole  = []string{"admin"}
+)
+
+type AuthorizationSuite struct {
+	suite.Suite
+	e                   *echo.Echo
+	portfolioRepository repository.PortfolioRepository
+	service             services.PortfolioService
+	portfolio           *models.Portfolio
+	deleted             *models.Portfolio
+	principals          map[string]*auth.Principal
+}
+
+func TestAuthorizationSuite(t *testing.T) {
+	suite.Run(t, new(AuthorizationSuite))
+}
+
+func (suite *AuthorizationSuite) SetupTest() {
+	r := suite.Require()
+	ctx := context.Background()
+	portfolioRepository := repository.NewPortfolioRepository()
+
+	portfolio, err := portfolioRepository.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-1"})
+	r.NoError(err)
+
+	deleted, err := portfolioRepository.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-2"})
+	r.NoError(err)
+	r.NoError(portfolioRepository.DeletePortfolio(ctx, deleted.Id, 0))
+
+	suite.e = echo.New()
+	suite.portfolioRepository = portfolioRepository
+	suite.service = services.NewAuthorizedPortfolioService(services.NewPortfolioService(portfolioRepository), auth.DefaultPolicy())
+	suite.portfolio = portfolio
+	suite.deleted = deleted
+	suite.principals = map[string]*auth.Principal{
+		"viewer":  {Subject: "vera", Roles: []string{"viewer"}},
+		"editor":  {Subject: "ed", Roles: []string{"editor"}},
+		"admin":   {Subject: "ada", Roles: []string{"admin"}},
+		"unknown": {Subject: "uma", Roles: []string{"auditor"}},
+		"none":    {Subject: "nora"},
+	}
+}
+
+func (suite *AuthorizationSuite) cases() []authorizationCase {
+	id := suite.portfolio.Id
+	createHandler := func(service services.PortfolioService) func(echo.Context) error {
+		return NewCreatePortfolioHandler(service, idempotency.NewMemoryStore(time.Hour))
+	}
+
+	return []authorizationCase{
+		{"get by id", http.MethodGet, fmt.Sprintf("/portfolios/%d", id), "", "", everyRole, NewGetPortfolioByIdHandler},
+		{"list", http.MethodGet, "/portfolios", "", "", everyRole, NewGetPortfoliosHandler},
+		{"history", http.MethodGet, fmt.Sprintf("/portfolios/%d/history", id), "", "", everyRole, NewGetPortfolioHistoryHandler},
+		{"diff", http.MethodGet, fmt.Sprintf("/portfolios/%d/diff?from=1&to=1", id), "", "", everyRole, NewDiffPortfolioHandler},
+		{"export", http.MethodGet, "/portfolios/export", "", "", everyRole, NewExportPortfoliosHandler},
+		{"create", http.MethodPost, "/portfolios", echo.MIMEApplicationJSON, `{"name":"portfolio-3"}`, editorRole, createHandler},
+		{"update", http.MethodPut, fmt.Sprintf("/portfolios/%d", id), echo.MIMEApplicationJSON, fmt.Sprintf(`{"id":%d,"name":"portfolio-3"}`, id), editorRole, NewUpdatePortfolioHandler},
+		{"patch", http.MethodPatch, fmt.Sprintf("/portfolios/%d", id), MIMEApplicationMergePatchJSON, `{"isActive":true}`, editorRole, NewPatchPortfolioHandler},
+		{"import", http.MethodPost, "/portfolios/import", "text/csv", "name\nportfolio-3\n", editorRole, NewImportPortfoliosHandler},
+		{"upsert import", http.MethodPost, "/portfolios/import?mode=upsert", "text/csv", "name,isActive\nportfolio-1,true\n", editorRole, NewImportPortfoliosHandler},
+		{"create batch", http.MethodPost, "/portfolios:batch", echo.MIMEApplicationJSON, `{"operations":[{"op":"create","name":"portfolio-3"}]}`, editorRole, NewBatchPortfoliosHandler},
+		{"delete batch", http.MethodPost, "/portfolios:batch", echo.MIMEApplicationJSON, fmt.Sprintf(`{"operations":[{"op":"create","name":"portfolio-3"},{"op":"delete","id":%d}]}`, id), adminRole, NewBatchPortfoliosHandler},
+		{"delete", http.MethodDelete, fmt.Sprintf("/portfolios/%d", id), "", "", adminRole, NewDeletePortfolioHandler},
+		{"hard delete", http.MethodDelete, fmt.Sprintf("/portfolios/%d?hard=true", id), "", "", adminRole, NewDeletePortfolioHandler},
+		{"restore", http.MethodPost, fmt.Sprintf("/portfolios/%d/restore", suite.deleted.Id), "", "", adminRole, NewRestorePortfolioHandler},
+	}
+}
+
+// serve runs the handler of the case as the principal and returns the response status.
+func (suite *AuthorizationSuite) serve(tc authorizationCase, principal *auth.Principal) int {
+	code, _ := suite.run(tc, principal)
+
+	return code
+}
+
+func (suite *AuthorizationSuite) TestEveryHandlerUnderEveryRole() {
+	for i := range suite.cases() {
+		for role, principal := range suite.principals {
+			suite.SetupTest()
+			tc := suite.cases()[i]
+			allowed := map[string]bool{}
+
+			for _, role := range tc.allowed {
+				allowed[role] = true
+			}
+
+			code := suite.serve(tc, principal)
+
+			if allowed[role] {
+				suite.Less(code, http.StatusBadRequest, "%s as %s", tc.name, role)
+			} else {
+				suite.Equal(http.StatusForbidden, code, "%s as %s", tc.name, role)
+			}
+		}
+	}
+}
+
+func (suite *AuthorizationSuite) TestWithoutPrincipal() {
+	for i := range suite.cases() {
+		suite.SetupTest()
+		tc := suite.cases()[i]
+		suite.Equal(http.StatusForbidden, suite.serve(tc, nil), tc.name)
+	}
+}
+
+// run serves the request as the principal and returns the response.
+func (suite *AuthorizationSuite) run(tc authorizationCase, principal *auth.Principal) (int, string) {
+	req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+	req = req.WithContext(auth.ContextWithPrincipal(req.Context(), principal))
+	req.Header.Set(echo.HeaderContentType, tc.contentType)
+	rec := httptest.NewRecorder()
+	ctx := suite.e.NewContext(req, rec)
+
+	if path, _, _ := strings.Cut(tc.path, "?"); strings.Count(path, "/") > 1 {
+		ctx.SetParamNames("id")
+		ctx.SetParamValues(strings.Split(path, "/")[2])
+	}
+
+	err := tc.handler(suite.service)(ctx)
+
+	var he *echo.HTTPError
+
+	if errors.As(err, &he) {
+		return he.Code, fmt.Sprint(he.Message)
+	}
+
+	suite.Require().NoError(err)
+
+	return rec.Code, rec.Body.String()
+}
+
+func (suite *AuthorizationSuite) TestIdempotentReplayRequiresCreatePermission() {
+	r := suite.Require()
+	handler := NewCreatePortfolioHandler(suite.service, idempotency.NewMemoryStore(time.Hour))
+	post := func(principal *auth.Principal) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/portfolios", strings.NewReader(`{"name":"portfolio-3"}`))
+		req = req.WithContext(auth.ContextWithPrincipal(req.Context(), principal))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		rec := httptest.NewRecorder()
+
+		return rec, handler(suite.e.NewContext(req, rec))
+	}
+
+	rec, err := post(&auth.Principal{Subject: "ed", Roles: []string{"editor"}})
+	r.NoError(err)
+	r.Equal(http.StatusCreated, rec.Code)
+
+	rec, err = post(&auth.Principal{Subject: "ed", Roles: []string{"viewer"}})
+	var he *echo.HTTPError
+	r.ErrorAs(err, &he)
+	r.Equal(http.StatusForbidden, he.Code, "a revoked role must not replay the stored response")
+	r.Empty(rec.Header().Get(HeaderIdempotentReplayed))
+	r.Empty(rec.Body.String())
+}
+
+func (suite *AuthorizationSuite) TestVisibilityByScopes() {
+	r := suite.Require()
+	finance, err := suite.portfolioRepository.CreatePortfolio(context.Background(), &requests.CreatePortfolioRequest{Name: "finance", IsFinance: true})
+	r.NoError(err)
+	internal, err := suite.portfolioRepository.CreatePortfolio(context.Background(), &requests.CreatePortfolioRequest{Name: "internal", IsInternal: true})
+	r.NoError(err)
+
+	external := &auth.Principal{Subject: "vera", Roles: []string{"viewer"}}
+	insider := &auth.Principal{Subject: "ian", Roles: []string{"viewer"}, Scopes: []string{auth.ScopeInternal}}
+	accountant := &auth.Principal{Subject: "fay", Roles: []string{"viewer"}, Scopes: []string{auth.ScopeInternal, auth.ScopeFinance}}
+
+	getFinance := authorizationCase{method: http.MethodGet, path: fmt.Sprintf("/portfolios/%d", finance.Id), handler: NewGetPortfolioByIdHandler}
+	getInternal := authorizationCase{method: http.MethodGet, path: fmt.Sprintf("/portfolios/%d", internal.Id), handler: NewGetPortfolioByIdHandler}
+	list := authorizationCase{method: http.MethodGet, path: "/portfolios?withTotalCount=true", handler: NewGetPortfoliosHandler}
+	export := authorizationCase{method: http.MethodGet, path: "/portfolios/export", handler: NewExportPortfoliosHandler}
+
+	tt := []struct {
+		principal       *auth.Principal
+		financeCode     int
+		internalCode    int
+		totalCount      string
+		exportedRecords int
+	}{
+		{external, http.StatusNotFound, http.StatusNotFound, `"totalCount":1`, 1},
+		{insider, http.StatusNotFound, http.StatusOK, `"totalCount":2`, 2},
+		{accountant, http.StatusOK, http.StatusOK, `"totalCount":3`, 3},
+	}
+
+	for _, tc := range tt {
+		code, _ := suite.run(getFinance, tc.principal)
+		suite.Equal(tc.financeCode, code, tc.principal.Subject)
+
+		code, _ = suite.run(getInternal, tc.principal)
+		suite.Equal(tc.internalCode, code, tc.principal.Subject)
+
+		code, body := suite.run(list, tc.principal)
+		suite.Equal(http.StatusOK, code)
+		suite.Contains(body, tc.totalCount, tc.principal.Subject)
+
+		code, body = suite.run(export, tc.principal)
+		suite.Equal(http.StatusOK, code)
+		suite.Equal(tc.exportedRecords+1, strings.Count(body, "\n"), tc.principal.Subject)
+	}
+}
+
+func (suite *AuthorizationSuite) TestChangesByScopes() {
+	r := suite.Require()
+	finance, err := suite.portfolioRepository.CreatePortfolio(context.Background(), &requests.CreatePortfolioRequest{Name: "finance", IsFinance: true})
+	r.NoError(err)
+
+	editor := &auth.Principal{Subject: "ed", Roles: []string{"editor"}, Scopes: []string{auth.ScopeInternal}}
+	accountant := &auth.Principal{Subject: "fay", Roles: []string{"editor"}, Scopes: []string{auth.ScopeInternal, auth.ScopeFinance}}
+
+	create := authorizationCase{method: http.MethodPost, path: "/portfolios", contentType: echo.MIMEApplicationJSON, body: `{"name":"finance-2","isFinance":true}`, handler: func(service services.PortfolioService) func(echo.Context) error {
+		return NewCreatePortfolioHandler(service, idempotency.NewMemoryStore(time.Hour))
+	}}
+	flag := authorizationCase{method: http.MethodPatch, path: fmt.Sprintf("/portfolios/%d", suite.portfolio.Id), contentType: MIMEApplicationMergePatchJSON, body: `{"isFinance":true}`, handler: NewPatchPortfolioHandler}
+	rename := authorizationCase{method: http.MethodPatch, path: fmt.Sprintf("/portfolios/%d", finance.Id), contentType: MIMEApplicationMergePatchJSON, body: `{"name":"finance-renamed"}`, handler: NewPatchPortfolioHandler}
+
+	code, message := suite.run(create, editor)
+	r.Equal(http.StatusForbidden, code)
+	r.Equal("Not allowed to mark portfolios as internal or finance", message)
+
+	code, _ = suite.run(flag, editor)
+	r.Equal(http.StatusForbidden, code)
+
+	code, _ = suite.run(rename, editor)
+	r.Equal(http.StatusNotFound, code)
+
+	for _, tc := range []authorizationCase{create, flag, rename} {
+		code, _ = suite.run(tc, accountant)
+		r.Less(code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// BatchPortfolios creates, updates and deletes portfolios in one request and responds with a result per operation
+// @Summary      Creates, updates and deletes portfolios in bulk
+// @Description  Operations are validated like the single endpoints and applied in order, each result has the status
+// @Description  the single endpoint would respond with. An atomic batch applies all operations or none and responds
+// @Description  with the status of the failed operation, the other operations have the status 424.
+// @Description  Otherwise the batch responds with 200 and every valid operation is applied on its own.
+// @Tags         Portfolios
+// @Accept       json
+// @Param        batch body requests.BatchPortfoliosRequest true "Up to 1000 operations"
+// @Produce      json
+// @Success      200  {object}  responses.BatchPortfoliosResponse
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
+// @Router       /portfolios:batch [post]
+func NewBatchPortfoliosHandler(portfolioService services.PortfolioService) func(echo.Context) error {
+	return func(ctx echo.Context) error {
+		body := &requests.BatchPortfoliosRequest{}
+
+		if err := ctx.Bind(body); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid json body")
+		}
+
+		response, err := portfolioService.BatchPortfolios(ctx.Request().Context(), body)
+
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(batchStatus(body, response), response)
+	}
+}
+
+// batchStatus is the status of the operation that failed an atomic batch, and 200 otherwise.
+func batchStatus(body *requests.BatchPortfoliosRequest, response *responses.BatchPortfoliosResponse) int {
+	if !body.Atomic || response.Succeeded {
+		return http.StatusOK
+	}
+
+	for _, result := range response.Results {
+		if result.Status >= http.StatusBadRequest && result.Status != http.StatusFailedDependency {
+			return result.Status
+		}
+	}
+
+	return http.StatusFailedDependency
+}
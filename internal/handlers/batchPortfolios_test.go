@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchPortfoliosSuite struct {
+	suite.Suite
+	portfolioRepository *mocks.PortfolioRepository
+	portfolioService    services.PortfolioService
+	e                   *echo.Echo
+}
+
+func TestBatchPortfoliosSuite(t *testing.T) {
+	suite.Run(t, new(BatchPortfoliosSuite))
+}
+
+func (suite *BatchPortfoliosSuite) SetupTest() {
+	t := suite.T()
+	e := echo.New()
+	porftolioRepository := mocks.NewPortfolioRepository(t)
+	portfolioService := services.NewPortfolioService(porftolioRepository)
+
+	suite.e = e
+	suite.portfolioRepository = porftolioRepository
+	suite.portfolioService = portfolioService
+}
+
+func (suite *BatchPortfoliosSuite) post(body string) (*httptest.ResponseRecorder, *responses.BatchPortfoliosResponse, error) {
+	handler := NewBatchPortfoliosHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := handler(suite.e.NewContext(req, rec)); err != nil {
+		return rec, nil, err
+	}
+
+	response := &responses.BatchPortfoliosResponse{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), response))
+
+	return rec, response, nil
+}
+
+func (suite *BatchPortfoliosSuite) statuses(response *responses.BatchPortfoliosResponse) []int {
+	statuses := make([]int, 0, len(response.Results))
+
+	for _, result := range response.Results {
+		statuses = append(statuses, result.Status)
+	}
+
+	return statuses
+}
+
+func (suite *BatchPortfoliosSuite) TestBatchPortfoliosBestEffort() {
+	r := suite.Require()
+	created := &models.Portfolio{Id: 3, Name: "portfolio-3", Version: 1}
+	updated := &models.Portfolio{Id: 1, Name: "portfolio-1", IsActive: true, Version: 3}
+
+	expected := []repository.PortfolioOperation{
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-3"}},
+		{Type: repository.OperationUpdate, Update: &models.Portfolio{Id: 1, Name: "portfolio-1", IsActive: true, Version: 2}},
+		{Type: repository.OperationDelete, Id: 2},
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-1"}},
+	}
+
+	suite.portfolioRepository.EXPECT().ApplyPortfolioOperations(mock.Anything, expected, false).Return([]repository.PortfolioOperationResult{
+		{Portfolio: created},
+		{Portfolio: updated},
+		{},
+		{Err: repository.ErrPortfolioAlreadyExists},
+	}, nil).Once()
+
+	rec, response, err := suite.post(`{"operations": [
+		{"op": "create", "name": "portfolio-3"},
+		{"op": "create", "name": ""},
+		{"op": "update", "id": 1, "version": 2, "name": "portfolio-1", "isActive": true},
+		{"op": "update", "name": "portfolio-1"},
+		{"op": "delete", "id": 2},
+		{"op": "delete"},
+		{"op": "rename", "id": 1},
+		{"op": "create", "name": "portfolio-1"}
+	]}`)
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.False(response.Succeeded)
+	r.Equal([]int{201, 400, 200, 400, 204, 400, 400, 409}, suite.statuses(response))
+	r.Equal(created, response.Results[0].Portfolio)
+	r.Equal(updated, response.Results[2].Portfolio)
+	r.Nil(response.Results[4].Portfolio)
+	r.NotEmpty(response.Results[1].Error)
+	r.Equal("Portfolio with this name already exists", response.Results[7].Error)
+}
+
+func (suite *BatchPortfoliosSuite) TestBatchPortfoliosAtomic() {
+	r := suite.Require()
+	created := &models.Portfolio{Id: 3, Name: "portfolio-3", Version: 1}
+
+	suite.portfolioRepository.EXPECT().ApplyPortfolioOperations(mock.Anything, mock.Anything, true).Return([]repository.PortfolioOperationResult{
+		{Portfolio: created},
+		{},
+	}, nil).Once()
+
+	rec, response, err := suite.post(`{"atomic": true, "operations": [
+		{"op": "create", "name": "portfolio-3"},
+		{"op": "delete", "id": 2, "version": 1}
+	]}`)
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.True(response.Succeeded)
+	r.Equal([]int{201, 204}, suite.statuses(response))
+}
+
+func (suite *BatchPortfoliosSuite) TestBatchPortfoliosAtomicRollback() {
+	r := suite.Require()
+
+	suite.portfolioRepository.EXPECT().ApplyPortfolioOperations(mock.Anything, mock.Anything, true).Return([]repository.PortfolioOperationResult{
+		{Err: repository.ErrPortfolioBatchRolledBack},
+		{Err: repository.ErrPortfolioVersionMismatch},
+		{Err: repository.ErrPortfolioBatchRolledBack},
+	}, nil).Once()
+
+	rec, response, err := suite.post(`{"atomic": true, "operations": [
+		{"op": "create", "name": "portfolio-3"},
+		{"op": "delete", "id": 2, "version": 1},
+		{"op": "delete", "id": 1}
+	]}`)
+
+	r.NoError(err)
+	r.Equal(http.StatusPreconditionFailed, rec.Code)
+	r.False(response.Succeeded)
+	r.Equal([]int{424, 412, 424}, suite.statuses(response))
+}
+
+func (suite *BatchPortfoliosSuite) TestBatchPortfoliosAtomicInvalidOperation() {
+	r := suite.Require()
+
+	rec, response, err := suite.post(`{"atomic": true, "operations": [
+		{"op": "create", "name": "portfolio-3"},
+		{"op": "create", "name": "here-should-be-20-symbols"},
+		{"op": "delete", "id": 1}
+	]}`)
+
+	r.NoError(err)
+	r.Equal(http.StatusBadRequest, rec.Code)
+	r.False(response.Succeeded)
+	r.Equal([]int{424, 400, 424}, suite.statuses(response))
+}
+
+func (suite *BatchPortfoliosSuite) TestBatchPortfoliosBadRequests() {
+	r := suite.Require()
+
+	bodies := []string{
+		`not json`,
+		`{}`,
+		`{"operations": []}`,
+		`{"operations": [` + strings.Repeat(`{"op": "delete", "id": 1},`, 1000) + `{"op": "delete", "id": 1}]}`,
+	}
+
+	for _, body := range bodies {
+		_, _, err := suite.post(body)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he)
+		r.Equal(http.StatusBadRequest, he.Code)
+	}
+}
@@ -1,21 +1,39 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/idempotency"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	"github.com/labstack/echo/v4"
 )
 
+const maxIdempotencyKeyLength = 255
+
 // CreatePortfolio create portfolio and responds with new portfolio
 // @Summary      Creates portfolio
+// @Description  A retry with the same Idempotency-Key and body responds with the original response
+// @Description  and the Idempotent-Replayed header instead of creating another portfolio.
 // @Tags         Portfolios
 // @Param        portfolio body requests.CreatePortfolioRequest true "Portfolio Body"
+// @Param        Idempotency-Key header string false "Unique key of the request, responds with 422 if it was used with a different body" maxlength(255)
 // @Produce      json
 // @Success      200  {object}  models.Portfolio
+// @Header       200  {string}  ETag  "Portfolio version"
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
 // @Router       /portfolios [post]
-func NewCreatePortfolioHandler(portfolioService services.PortfolioService) func(echo.Context) error {
+func NewCreatePortfolioHandler(portfolioService services.PortfolioService, idempotencyStore idempotency.Store) func(echo.Context) error {
 	return func(ctx echo.Context) error {
 		body := &requests.CreatePortfolioRequest{}
 
@@ -23,12 +41,108 @@ func NewCreatePortfolioHandler(portfolioService services.PortfolioService) func(
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid json body")
 		}
 
-		portfolio, err := portfolioService.CreatePortfolio(body)
+		key := ctx.Request().Header.Get(HeaderIdempotencyKey)
+
+		if key == "" {
+			portfolio, err := portfolioService.CreatePortfolio(ctx.Request().Context(), body)
+
+			if err != nil {
+				return err
+			}
+
+			return portfolioJSON(ctx, http.StatusCreated, portfolio)
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key must not be longer than 255 characters")
+		}
+
+		// A caller that may no longer create portfolios must not read its stored responses either.
+		if err := portfolioService.AuthorizeCreatePortfolio(ctx.Request().Context()); err != nil {
+			return err
+		}
+
+		key = idempotencyScope(ctx.Request().Context()) + key
+
+		fingerprint, err := requestFingerprint(body)
 
 		if err != nil {
 			return err
 		}
 
-		return ctx.JSON(http.StatusCreated, portfolio)
+		response, err := idempotencyStore.Begin(ctx.Request().Context(), key, fingerprint)
+
+		if errors.Is(err, idempotency.ErrFingerprintMismatch) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different body")
+		}
+
+		if errors.Is(err, idempotency.ErrRequestInProgress) {
+			return echo.NewHTTPError(http.StatusConflict, "Request with this Idempotency-Key is in progress")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if response != nil {
+			portfolio := &models.Portfolio{}
+
+			if err := json.Unmarshal(response.Body, portfolio); err != nil {
+				return err
+			}
+
+			ctx.Response().Header().Set(HeaderIdempotentReplayed, "true")
+
+			return portfolioJSON(ctx, response.StatusCode, portfolio)
+		}
+
+		portfolio, err := portfolioService.CreatePortfolio(ctx.Request().Context(), body)
+
+		// The key is released or completed even when the client is gone, otherwise
+		// its retries would find the request in progress until the key expires.
+		if err != nil {
+			idempotencyStore.Abort(context.Background(), key)
+			return err
+		}
+
+		saved, err := json.Marshal(portfolio)
+
+		if err == nil {
+			err = idempotencyStore.Complete(context.Background(), key, &idempotency.Response{StatusCode: http.StatusCreated, Body: saved})
+		}
+
+		if err != nil {
+			idempotencyStore.Abort(context.Background(), key)
+			return err
+		}
+
+		return portfolioJSON(ctx, http.StatusCreated, portfolio)
+	}
+}
+
+// idempotencyScope prefixes keys with the tenant and the subject of the request, so that callers
+// cannot replay the responses of each other. Neither part contains slashes once the subject is
+// escaped, and both are always present, so the same key of another caller is a different key.
+func idempotencyScope(ctx context.Context) string {
+	tenant, _ := repository.TenantFromContext(ctx)
+	subject := ""
+
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		subject = url.PathEscape(principal.Subject)
+	}
+
+	return tenant + "/" + subject + "/"
+}
+
+// requestFingerprint identifies the request body regardless of its formatting.
+func requestFingerprint(body *requests.CreatePortfolioRequest) (string, error) {
+	data, err := json.Marshal(body)
+
+	if err != nil {
+		return "", err
 	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
 }
@@ -2,17 +2,22 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/idempotency"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
 	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	"github.com/alekseyshevchenko93/go-crud-api-example/test/factories"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -20,6 +25,7 @@ type CreatePortfolioSuite struct {
 	suite.Suite
 	portfolioRepository *mocks.PortfolioRepository
 	portfolioService    services.PortfolioService
+	idempotencyStore    idempotency.Store
 	e                   *echo.Echo
 }
 
@@ -36,11 +42,24 @@ func (suite *CreatePortfolioSuite) SetupTest() {
 	suite.e = e
 	suite.portfolioRepository = porftolioRepository
 	suite.portfolioService = portfolioService
+	suite.idempotencyStore = idempotency.NewMemoryStore(time.Hour)
+}
+
+func (suite *CreatePortfolioSuite) post(key string, body interface{}) (*httptest.ResponseRecorder, error) {
+	handler := NewCreatePortfolioHandler(suite.portfolioService, suite.idempotencyStore)
+	bodyJson, _ := json.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bodyJson))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderIdempotencyKey, key)
+	rec := httptest.NewRecorder()
+
+	return rec, handler(suite.e.NewContext(req, rec))
 }
 
 func (suite *CreatePortfolioSuite) TestCreatePortfolioSuccess() {
 	r := suite.Require()
-	handler := NewCreatePortfolioHandler(suite.portfolioService)
+	handler := NewCreatePortfolioHandler(suite.portfolioService, suite.idempotencyStore)
 	portfolio := factories.GetPortfolio()
 	requestBody := requests.CreatePortfolioRequest{
 		Name:     portfolio.Name,
@@ -49,7 +68,7 @@ func (suite *CreatePortfolioSuite) TestCreatePortfolioSuccess() {
 	responseJson, _ := json.Marshal(portfolio)
 	requestJson, _ := json.Marshal(requestBody)
 
-	suite.portfolioRepository.EXPECT().CreatePortfolio(&requestBody).Return(portfolio, nil).Once()
+	suite.portfolioRepository.EXPECT().CreatePortfolio(mock.Anything, &requestBody).Return(portfolio, nil).Once()
 
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(requestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -64,7 +83,7 @@ func (suite *CreatePortfolioSuite) TestCreatePortfolioSuccess() {
 
 func (suite *CreatePortfolioSuite) TestCreatePortfolioBadRequests() {
 	r := suite.Require()
-	handler := NewCreatePortfolioHandler(suite.portfolioService)
+	handler := NewCreatePortfolioHandler(suite.portfolioService, suite.idempotencyStore)
 
 	tt := []interface{}{
 		&requests.CreatePortfolioRequest{Name: ""},
@@ -91,11 +110,11 @@ func (suite *CreatePortfolioSuite) TestCreatePortfolioBadRequests() {
 
 func (suite *CreatePortfolioSuite) TestCreatePortfolioConflict() {
 	r := suite.Require()
-	handler := NewCreatePortfolioHandler(suite.portfolioService)
+	handler := NewCreatePortfolioHandler(suite.portfolioService, suite.idempotencyStore)
 	requestBody := factories.GetCreatePortfolioRequest()
 	bodyJson, _ := json.Marshal(requestBody)
 
-	suite.portfolioRepository.EXPECT().CreatePortfolio(requestBody).Return(nil, repository.ErrPortfolioAlreadyExists).Once()
+	suite.portfolioRepository.EXPECT().CreatePortfolio(mock.Anything, requestBody).Return(nil, repository.ErrPortfolioAlreadyExists).Once()
 
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bodyJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -109,3 +128,85 @@ func (suite *CreatePortfolioSuite) TestCreatePortfolioConflict() {
 	r.True(ok)
 	r.Equal(httpError.Code, http.StatusConflict)
 }
+
+func (suite *CreatePortfolioSuite) TestCreatePortfolioIdempotencyKeyReplay() {
+	r := suite.Require()
+	portfolio := factories.GetPortfolio()
+	requestBody := &requests.CreatePortfolioRequest{Name: portfolio.Name, IsActive: portfolio.IsActive}
+
+	suite.portfolioRepository.EXPECT().CreatePortfolio(mock.Anything, requestBody).Return(portfolio, nil).Once()
+
+	first, err := suite.post("key-1", requestBody)
+	r.NoError(err)
+	r.Equal(http.StatusCreated, first.Code)
+	r.Empty(first.Header().Get(HeaderIdempotentReplayed))
+
+	retry, err := suite.post("key-1", requestBody)
+	r.NoError(err)
+	r.Equal(http.StatusCreated, retry.Code)
+	r.JSONEq(first.Body.String(), retry.Body.String())
+	r.Equal(first.Header().Get(HeaderETag), retry.Header().Get(HeaderETag))
+	r.Equal("true", retry.Header().Get(HeaderIdempotentReplayed))
+}
+
+func (suite *CreatePortfolioSuite) TestCreatePortfolioIdempotencyKeyDifferentBody() {
+	r := suite.Require()
+	portfolio := factories.GetPortfolio()
+	requestBody := &requests.CreatePortfolioRequest{Name: portfolio.Name}
+
+	suite.portfolioRepository.EXPECT().CreatePortfolio(mock.Anything, requestBody).Return(portfolio, nil).Once()
+
+	_, err := suite.post("key-1", requestBody)
+	r.NoError(err)
+
+	_, err = suite.post("key-1", &requests.CreatePortfolioRequest{Name: portfolio.Name, IsFinance: true})
+
+	var he *echo.HTTPError
+	r.ErrorAs(err, &he)
+	r.Equal(http.StatusUnprocessableEntity, he.Code)
+}
+
+func (suite *CreatePortfolioSuite) TestCreatePortfolioIdempotencyKeyRetryAfterError() {
+	r := suite.Require()
+	portfolio := factories.GetPortfolio()
+	requestBody := &requests.CreatePortfolioRequest{Name: portfolio.Name}
+
+	suite.portfolioRepository.EXPECT().CreatePortfolio(mock.Anything, requestBody).Return(nil, repository.ErrPortfolioAlreadyExists).Once()
+	suite.portfolioRepository.EXPECT().CreatePortfolio(mock.Anything, requestBody).Return(portfolio, nil).Once()
+
+	_, err := suite.post("key-1", requestBody)
+
+	var he *echo.HTTPError
+	r.ErrorAs(err, &he)
+	r.Equal(http.StatusConflict, he.Code)
+
+	rec, err := suite.post("key-1", requestBody)
+	r.NoError(err)
+	r.Equal(http.StatusCreated, rec.Code, "failed requests are not replayed")
+}
+
+func (suite *CreatePortfolioSuite) TestCreatePortfolioIdempotencyKeyInProgress() {
+	r := suite.Require()
+	requestBody := &requests.CreatePortfolioRequest{Name: "portfolio-1"}
+	fingerprint, err := requestFingerprint(requestBody)
+	r.NoError(err)
+
+	_, err = suite.idempotencyStore.Begin(context.Background(), idempotencyScope(context.Background())+"key-1", fingerprint)
+	r.NoError(err)
+
+	_, err = suite.post("key-1", requestBody)
+
+	var he *echo.HTTPError
+	r.ErrorAs(err, &he)
+	r.Equal(http.StatusConflict, he.Code)
+}
+
+func (suite *CreatePortfolioSuite) TestCreatePortfolioIdempotencyKeyTooLong() {
+	r := suite.Require()
+
+	_, err := suite.post(strings.Repeat("k", 256), &requests.CreatePortfolioRequest{Name: "portfolio-1"})
+
+	var he *echo.HTTPError
+	r.ErrorAs(err, &he)
+	r.Equal(http.StatusBadRequest, he.Code)
+}
@@ -9,17 +9,28 @@ import (
 
 // DeletePortfolioById deletes portfolio
 // @Summary      Deletes portfolio by id
-// @Description  Deletes portfolio
+// @Description  Soft-deletes the portfolio, it can be restored until it is purged after the retention period.
+// @Description  With hard=true the portfolio is deleted permanently, deleted or not. This is meant for admins.
 // @Tags         Portfolios
 // @Produce      json
-// @Success      200
+// @Success      204
 // @Param        id path int  true "Portfolio ID"
+// @Param        hard query bool false "Delete permanently"
+// @Param        If-Match header string false "ETag of the portfolio, responds with 412 if it was changed"
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
 // @Router       /portfolios/{id} [delete]
 func NewDeletePortfolioHandler(portfolioService services.PortfolioService) func(echo.Context) error {
 	return func(ctx echo.Context) error {
 		id := ctx.Param("id")
+		hard := false
 
-		err := portfolioService.DeletePortfolio(id)
+		if err := echo.QueryParamsBinder(ctx).Bool("hard", &hard).BindError(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+		}
+
+		ifMatch := ctx.Request().Header.Get(HeaderIfMatch)
+		err := portfolioService.DeletePortfolio(ctx.Request().Context(), id, ifMatch, hard)
 
 		if err != nil {
 			return err
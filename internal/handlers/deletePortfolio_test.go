@@ -12,6 +12,7 @@ import (
 	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -43,8 +44,7 @@ func (suite *DeletePortfolioSuite) TestDeletePortfolioSuccess() {
 	portfolio := factories.GetPortfolio()
 	portfolioIdStr := fmt.Sprintf("%d", portfolio.Id)
 
-	suite.portfolioRepository.EXPECT().GetPortfolioById(portfolio.Id).Return(portfolio, nil).Once()
-	suite.portfolioRepository.EXPECT().DeletePortfolio(portfolio.Id).Return(nil).Once()
+	suite.portfolioRepository.EXPECT().DeletePortfolio(mock.Anything, portfolio.Id, 0).Return(nil).Once()
 
 	req := httptest.NewRequest(http.MethodDelete, "/", nil)
 	rec := httptest.NewRecorder()
@@ -86,7 +86,7 @@ func (suite *DeletePortfolioSuite) TestDeletePortfolioNotFound() {
 	handler := NewDeletePortfolioHandler(suite.portfolioService)
 	portfolio := factories.GetPortfolio()
 	portfolioIdStr := fmt.Sprintf("%d", portfolio.Id)
-	suite.portfolioRepository.EXPECT().GetPortfolioById(portfolio.Id).Return(nil, repository.ErrPortfolioNotFound).Once()
+	suite.portfolioRepository.EXPECT().DeletePortfolio(mock.Anything, portfolio.Id, 0).Return(repository.ErrPortfolioNotFound).Once()
 
 	req := httptest.NewRequest(http.MethodDelete, "/", nil)
 	rec := httptest.NewRecorder()
@@ -102,3 +102,94 @@ func (suite *DeletePortfolioSuite) TestDeletePortfolioNotFound() {
 	r.True(ok)
 	r.Equal(httpError.Code, http.StatusNotFound)
 }
+
+func (suite *DeletePortfolioSuite) TestDeletePortfolioIfMatch() {
+	r := suite.Require()
+	handler := NewDeletePortfolioHandler(suite.portfolioService)
+	portfolio := factories.GetPortfolio()
+	portfolioIdStr := fmt.Sprintf("%d", portfolio.Id)
+
+	tt := []struct {
+		ifMatch string
+		version int
+		err     error
+		code    int
+	}{
+		{ifMatch: `"3"`, version: 3, code: http.StatusNoContent},
+		{ifMatch: `*`, version: 0, code: http.StatusNoContent},
+		{ifMatch: `"3"`, version: 3, err: repository.ErrPortfolioVersionMismatch, code: http.StatusPreconditionFailed},
+		{ifMatch: `W/"3"`, code: http.StatusBadRequest},
+		{ifMatch: `"3", "4"`, code: http.StatusBadRequest},
+		{ifMatch: `3`, code: http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		if tc.code != http.StatusBadRequest {
+			suite.portfolioRepository.EXPECT().DeletePortfolio(mock.Anything, portfolio.Id, tc.version).Return(tc.err).Once()
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.Header.Set(HeaderIfMatch, tc.ifMatch)
+		rec := httptest.NewRecorder()
+		ctx := suite.e.NewContext(req, rec)
+		ctx.SetPath("/portfolios/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues(portfolioIdStr)
+
+		err := handler(ctx)
+
+		if tc.code == http.StatusNoContent {
+			r.NoError(err)
+			r.Equal(http.StatusNoContent, rec.Code)
+			continue
+		}
+
+		r.Error(err)
+		httpError, ok := err.(*echo.HTTPError)
+		r.True(ok)
+		r.Equal(tc.code, httpError.Code, tc.ifMatch)
+	}
+}
+
+func (suite *DeletePortfolioSuite) TestDeletePortfolioHard() {
+	r := suite.Require()
+	handler := NewDeletePortfolioHandler(suite.portfolioService)
+	portfolio := factories.GetPortfolio()
+	portfolioIdStr := fmt.Sprintf("%d", portfolio.Id)
+
+	tt := []struct {
+		target string
+		err    error
+		code   int
+	}{
+		{target: "/?hard=true", code: http.StatusNoContent},
+		{target: "/?hard=true", err: repository.ErrPortfolioNotFound, code: http.StatusNotFound},
+		{target: "/?hard=yes", code: http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		if tc.code != http.StatusBadRequest {
+			suite.portfolioRepository.EXPECT().PurgePortfolio(mock.Anything, portfolio.Id, 0).Return(tc.err).Once()
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, tc.target, nil)
+		rec := httptest.NewRecorder()
+		ctx := suite.e.NewContext(req, rec)
+		ctx.SetPath("/portfolios/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues(portfolioIdStr)
+
+		err := handler(ctx)
+
+		if tc.code == http.StatusNoContent {
+			r.NoError(err)
+			r.Equal(http.StatusNoContent, rec.Code)
+			continue
+		}
+
+		r.Error(err)
+		httpError, ok := err.(*echo.HTTPError)
+		r.True(ok)
+		r.Equal(tc.code, httpError.Code, tc.target)
+	}
+}
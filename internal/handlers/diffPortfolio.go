@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+const diffFormatJSONPatch = "json-patch"
+
+// DiffPortfolio responds with the fields that differ between two versions of a portfolio
+// @Summary      Diff two versions of portfolio
+// @Description  Lists the name, flags and deletedAt of both versions where they differ, with the time each field got its new value.
+// @Description  With format=json-patch responds with a JSON patch (RFC 6902) that turns version from into version to instead.
+// @Description  Versions of deleted and purged portfolios can be compared too.
+// @Tags         Portfolios
+// @Produce      json
+// @Produce      application/json-patch+json
+// @Param        id path int  true "Portfolio ID"
+// @Param        from query int true "Older version" minimum(1)
+// @Param        to query int true "Newer version, at least from" minimum(1)
+// @Param        format query string false "Response format" Enums(json, json-patch) default(json)
+// @Success      200  {object}  responses.PortfolioDiffResponse
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
+// @Router       /portfolios/{id}/diff [get]
+func NewDiffPortfolioHandler(portfolioService services.PortfolioService) func(echo.Context) error {
+	return func(ctx echo.Context) error {
+		query := &requests.GetPortfolioDiffRequest{}
+
+		if err := ctx.Bind(query); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+		}
+
+		diff, err := portfolioService.DiffPortfolio(ctx.Request().Context(), ctx.Param("id"), query)
+
+		if err != nil {
+			return err
+		}
+
+		if query.Format != diffFormatJSONPatch {
+			return ctx.JSON(http.StatusOK, diff)
+		}
+
+		body, err := json.Marshal(services.PortfolioDiffPatch(diff))
+
+		if err != nil {
+			return err
+		}
+
+		return ctx.Blob(http.StatusOK, MIMEApplicationJSONPatch, body)
+	}
+}
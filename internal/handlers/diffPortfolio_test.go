@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/patch"
+	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiffPortfolioSuite struct {
+	suite.Suite
+	portfolioRepository *mocks.PortfolioRepository
+	portfolioService    services.PortfolioService
+	e                   *echo.Echo
+}
+
+func TestDiffPortfolioSuite(t *testing.T) {
+	suite.Run(t, new(DiffPortfolioSuite))
+}
+
+func (suite *DiffPortfolioSuite) SetupTest() {
+	t := suite.T()
+	e := echo.New()
+	porftolioRepository := mocks.NewPortfolioRepository(t)
+	portfolioService := services.NewPortfolioService(porftolioRepository)
+
+	suite.e = e
+	suite.portfolioRepository = porftolioRepository
+	suite.portfolioService = portfolioService
+}
+
+func (suite *DiffPortfolioSuite) diff(id string, target string) (*httptest.ResponseRecorder, error) {
+	handler := NewDiffPortfolioHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	ctx := suite.e.NewContext(req, rec)
+	ctx.SetPath("/portfolios/:id/diff")
+	ctx.SetParamNames("id")
+	ctx.SetParamValues(id)
+
+	return rec, handler(ctx)
+}
+
+// diffVersions returns the versions of a portfolio that was created, renamed, activated, renamed back and deleted.
+func diffVersions() []*models.Portfolio {
+	at := func(minutes int) *time.Time {
+		t := time.Date(2023, 5, 1, 10, minutes, 0, 0, time.UTC)
+		return &t
+	}
+
+	return []*models.Portfolio{
+		{Id: 1, Name: "portfolio-1", Version: 1, CreatedAt: at(0), UpdatedAt: at(0)},
+		{Id: 1, Name: "portfolio-2", Version: 2, CreatedAt: at(0), UpdatedAt: at(1)},
+		{Id: 1, Name: "portfolio-2", IsActive: true, Version: 3, CreatedAt: at(0), UpdatedAt: at(2)},
+		{Id: 1, Name: "portfolio-1", IsActive: true, Version: 4, CreatedAt: at(0), UpdatedAt: at(3)},
+		{Id: 1, Name: "portfolio-1", IsActive: true, Version: 5, CreatedAt: at(0), UpdatedAt: at(4), DeletedAt: at(4)},
+	}
+}
+
+func (suite *DiffPortfolioSuite) TestDiffPortfolio() {
+	r := suite.Require()
+
+	suite.portfolioRepository.EXPECT().GetPortfolioVersions(mock.Anything, 1, 1, 4).Return(diffVersions()[:4], nil).Once()
+
+	rec, err := suite.diff("1", "/?from=1&to=4")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.JSONEq(`{
+		"portfolioId": 1, "from": 1, "to": 4,
+		"changes": [{"field": "isActive", "from": false, "to": true, "changedAt": "2023-05-01T10:02:00Z"}]
+	}`, rec.Body.String())
+}
+
+func (suite *DiffPortfolioSuite) TestDiffPortfolioSameVersion() {
+	r := suite.Require()
+
+	suite.portfolioRepository.EXPECT().GetPortfolioVersions(mock.Anything, 1, 2, 2).Return(diffVersions()[1:2], nil).Once()
+
+	rec, err := suite.diff("1", "/?from=2&to=2")
+
+	r.NoError(err)
+	r.JSONEq(`{"portfolioId": 1, "from": 2, "to": 2, "changes": []}`, rec.Body.String())
+}
+
+func (suite *DiffPortfolioSuite) TestDiffPortfolioJSONPatch() {
+	r := suite.Require()
+
+	suite.portfolioRepository.EXPECT().GetPortfolioVersions(mock.Anything, 1, 2, 5).Return(diffVersions()[1:], nil).Once()
+
+	rec, err := suite.diff("1", "/?from=2&to=5&format=json-patch")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal(MIMEApplicationJSONPatch, rec.Header().Get(echo.HeaderContentType))
+	r.JSONEq(`[
+		{"op": "replace", "path": "/name", "value": "portfolio-1"},
+		{"op": "replace", "path": "/isActive", "value": true},
+		{"op": "add", "path": "/deletedAt", "value": "2023-05-01T10:04:00Z"}
+	]`, rec.Body.String())
+
+	from, _ := json.Marshal(diffVersions()[1])
+	to, _ := json.Marshal(diffVersions()[4])
+	jsonPatch, err := patch.DecodeJSONPatch(rec.Body.Bytes())
+	r.NoError(err)
+	patched, err := jsonPatch.Apply(from)
+	r.NoError(err)
+
+	expected := map[string]interface{}{}
+	r.NoError(json.Unmarshal(to, &expected))
+	delete(expected, "updatedAt")
+	delete(expected, "version")
+	actual := map[string]interface{}{}
+	r.NoError(json.Unmarshal(patched, &actual))
+	delete(actual, "updatedAt")
+	delete(actual, "version")
+	r.Equal(expected, actual)
+}
+
+func (suite *DiffPortfolioSuite) TestDiffPortfolioJSONPatchRemove() {
+	r := suite.Require()
+	restored := *diffVersions()[4]
+	restored.Version = 6
+	restored.DeletedAt = nil
+
+	suite.portfolioRepository.EXPECT().GetPortfolioVersions(mock.Anything, 1, 5, 6).Return([]*models.Portfolio{diffVersions()[4], &restored}, nil).Once()
+
+	rec, err := suite.diff("1", "/?from=5&to=6&format=json-patch")
+
+	r.NoError(err)
+	r.JSONEq(`[{"op": "remove", "path": "/deletedAt"}]`, rec.Body.String())
+}
+
+func (suite *DiffPortfolioSuite) TestDiffPortfolioNotFound() {
+	r := suite.Require()
+
+	tt := []struct {
+		target   string
+		from, to int
+		versions []*models.Portfolio
+	}{
+		{target: "/?from=1&to=4", from: 1, to: 4, versions: []*models.Portfolio{}},
+		{target: "/?from=1&to=9", from: 1, to: 9, versions: diffVersions()},
+		{target: "/?from=1&to=4", from: 1, to: 4, versions: diffVersions()[1:4]},
+	}
+
+	for _, tc := range tt {
+		suite.portfolioRepository.EXPECT().GetPortfolioVersions(mock.Anything, 1, tc.from, tc.to).Return(tc.versions, nil).Once()
+
+		_, err := suite.diff("1", tc.target)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he, tc.target)
+		r.Equal(http.StatusNotFound, he.Code, tc.target)
+	}
+}
+
+func (suite *DiffPortfolioSuite) TestDiffPortfolioBadRequest() {
+	r := suite.Require()
+
+	tt := []struct {
+		id     string
+		target string
+	}{
+		{id: "#$(*)@", target: "/?from=1&to=2"},
+		{id: "1", target: "/"},
+		{id: "1", target: "/?from=1"},
+		{id: "1", target: "/?to=2"},
+		{id: "1", target: "/?from=3&to=2"},
+		{id: "1", target: "/?from=0&to=2"},
+		{id: "1", target: "/?from=a&to=2"},
+		{id: "1", target: "/?from=1&to=2&format=yaml"},
+	}
+
+	for _, tc := range tt {
+		_, err := suite.diff(tc.id, tc.target)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he, tc.target)
+		r.Equal(http.StatusBadRequest, he.Code, tc.target)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// ExportPortfolios streams all matching portfolios as a CSV file
+// @Summary      Export portfolios
+// @Description  Streams all portfolios that match the filters, ordered by id. The file can be imported back.
+// @Tags         Portfolios
+// @Produce      text/csv
+// @Param        format query string false "File format" Enums(csv) default(csv)
+// @Param        isActive query bool false "Only active or inactive portfolios"
+// @Param        isFinance query bool false "Only finance or non-finance portfolios"
+// @Param        isInternal query bool false "Only internal or external portfolios"
+// @Param        createdFrom query string false "Created at or after, RFC 3339" format(date-time)
+// @Param        createdTo query string false "Created before, RFC 3339" format(date-time)
+// @Param        updatedFrom query string false "Updated at or after, RFC 3339" format(date-time)
+// @Param        updatedTo query string false "Updated before, RFC 3339" format(date-time)
+// @Param        namePrefix query string false "Name starts with, case-sensitive" maxlength(20)
+// @Success      200  {file}  file  "id,name,isInternal,isFinance,isActive,createdAt,updatedAt,version"
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
+// @Router       /portfolios/export [get]
+func NewExportPortfoliosHandler(portfolioService services.PortfolioService) func(echo.Context) error {
+	return func(ctx echo.Context) error {
+		query := &requests.ExportPortfoliosRequest{}
+
+		if err := ctx.Bind(query); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+		}
+
+		response := ctx.Response()
+		writer := csv.NewWriter(response)
+
+		write := func(portfolios []*models.Portfolio) error {
+			// Headers are written with the first page, so that errors before it are still sent as JSON.
+			if !response.Committed {
+				response.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+				response.Header().Set(echo.HeaderContentDisposition, `attachment; filename="portfolios.csv"`)
+				response.WriteHeader(http.StatusOK)
+
+				if err := writer.Write(services.PortfolioCSVHeader); err != nil {
+					return err
+				}
+			}
+
+			for _, portfolio := range portfolios {
+				if err := writer.Write(services.PortfolioCSVRecord(portfolio)); err != nil {
+					return err
+				}
+			}
+
+			writer.Flush()
+
+			if err := writer.Error(); err != nil {
+				return err
+			}
+
+			response.Flush()
+
+			return nil
+		}
+
+		return portfolioService.ExportPortfolios(ctx.Request().Context(), query, write)
+	}
+}
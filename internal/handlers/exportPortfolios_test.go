@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExportPortfoliosSuite struct {
+	suite.Suite
+	portfolioRepository *mocks.PortfolioRepository
+	portfolioService    services.PortfolioService
+	e                   *echo.Echo
+}
+
+func TestExportPortfoliosSuite(t *testing.T) {
+	suite.Run(t, new(ExportPortfoliosSuite))
+}
+
+func (suite *ExportPortfoliosSuite) SetupTest() {
+	t := suite.T()
+	e := echo.New()
+	porftolioRepository := mocks.NewPortfolioRepository(t)
+	portfolioService := services.NewPortfolioService(porftolioRepository)
+
+	suite.e = e
+	suite.portfolioRepository = porftolioRepository
+	suite.portfolioService = portfolioService
+}
+
+func (suite *ExportPortfoliosSuite) get(target string) (*httptest.ResponseRecorder, error) {
+	handler := NewExportPortfoliosHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+
+	return rec, handler(suite.e.NewContext(req, rec))
+}
+
+func (suite *ExportPortfoliosSuite) TestExportPortfoliosSuccess() {
+	r := suite.Require()
+	createdAt := time.Date(2023, 5, 1, 10, 30, 15, 0, time.UTC)
+	first := &models.Portfolio{Id: 1, Name: "portfolio-1", IsActive: true, CreatedAt: &createdAt, UpdatedAt: &createdAt, Version: 2}
+	second := &models.Portfolio{Id: 2, Name: "with, comma", IsFinance: true, CreatedAt: &createdAt, UpdatedAt: &createdAt, Version: 1}
+	formula := &models.Portfolio{Id: 3, Name: "=HYPERLINK(A1)", CreatedAt: &createdAt, UpdatedAt: &createdAt, Version: 1}
+	isActive := true
+
+	suite.portfolioRepository.EXPECT().
+		ListPortfolios(mock.Anything, mock.MatchedBy(func(query *repository.PortfolioQuery) bool {
+			return query.After == nil && query.Filter.IsActive != nil && *query.Filter.IsActive == isActive
+		})).
+		Return(&repository.PortfolioPage{Items: []*models.Portfolio{first}, HasMore: true}, nil).
+		Once()
+	suite.portfolioRepository.EXPECT().
+		ListPortfolios(mock.Anything, mock.MatchedBy(func(query *repository.PortfolioQuery) bool {
+			return query.After == first
+		})).
+		Return(&repository.PortfolioPage{Items: []*models.Portfolio{second, formula}}, nil).
+		Once()
+
+	rec, err := suite.get("/?format=csv&isActive=true")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal("text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	r.Equal(`attachment; filename="portfolios.csv"`, rec.Header().Get(echo.HeaderContentDisposition))
+	r.Equal(strings.Join([]string{
+		"id,name,isInternal,isFinance,isActive,createdAt,updatedAt,version",
+		"1,portfolio-1,false,false,true,2023-05-01T10:30:15Z,2023-05-01T10:30:15Z,2",
+		`2,"with, comma",false,true,false,2023-05-01T10:30:15Z,2023-05-01T10:30:15Z,1`,
+		"3,'=HYPERLINK(A1),false,false,false,2023-05-01T10:30:15Z,2023-05-01T10:30:15Z,1",
+		"",
+	}, "\n"), rec.Body.String())
+}
+
+func (suite *ExportPortfoliosSuite) TestExportPortfoliosEmpty() {
+	r := suite.Require()
+
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, mock.Anything).Return(&repository.PortfolioPage{}, nil).Once()
+
+	rec, err := suite.get("/")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal("id,name,isInternal,isFinance,isActive,createdAt,updatedAt,version\n", rec.Body.String())
+}
+
+func (suite *ExportPortfoliosSuite) TestExportPortfoliosBadRequest() {
+	r := suite.Require()
+	tt := []string{
+		"/?format=xlsx",
+		"/?isActive=yes",
+		"/?createdFrom=2023-05-02T00:00:00Z&createdTo=2023-05-01T00:00:00Z",
+	}
+
+	for _, target := range tt {
+		rec, err := suite.get(target)
+
+		r.Error(err, target)
+		httpError, ok := err.(*echo.HTTPError)
+		r.True(ok)
+		r.Equal(http.StatusBadRequest, httpError.Code, target)
+		r.Empty(rec.Header().Get(echo.HeaderContentType))
+	}
+}
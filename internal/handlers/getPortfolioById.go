@@ -3,6 +3,8 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	"github.com/labstack/echo/v4"
 )
@@ -12,17 +14,38 @@ import (
 // @Tags         Portfolios
 // @Produce      json
 // @Success      200  {object}  models.Portfolio
+// @Header       200  {string}  ETag  "Portfolio version, send it in If-Match to update or delete"
+// @Header       200  {string}  Last-Modified  "Time of the last update"
+// @Success      304
 // @Param        id path int  true "Portfolio ID"
+// @Param        asOf query string false "Responds with the portfolio as it was at this time, RFC 3339, or 404 if it did not exist then" format(date-time)
+// @Param        If-None-Match header string false "ETag of a previous response, responds with 304 if it still matches"
+// @Param        If-Modified-Since header string false "Last-Modified of a previous response, ignored with If-None-Match"
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
 // @Router       /portfolios/{id} [get]
 func NewGetPortfolioByIdHandler(portfolioService services.PortfolioService) func(echo.Context) error {
 	return func(ctx echo.Context) error {
 		id := ctx.Param("id")
-		portfolio, err := portfolioService.GetPortfolioById(id)
+		query := &requests.GetPortfolioRequest{}
+
+		if err := ctx.Bind(query); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+		}
+
+		var portfolio *models.Portfolio
+		var err error
+
+		if query.AsOf != nil {
+			portfolio, err = portfolioService.GetPortfolioAsOf(ctx.Request().Context(), id, *query.AsOf)
+		} else {
+			portfolio, err = portfolioService.GetPortfolioById(ctx.Request().Context(), id)
+		}
 
 		if err != nil {
 			return err
 		}
 
-		return ctx.JSON(http.StatusOK, portfolio)
+		return portfolioJSON(ctx, http.StatusOK, portfolio)
 	}
 }
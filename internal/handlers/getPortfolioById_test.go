@@ -6,12 +6,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
 	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	"github.com/alekseyshevchenko93/go-crud-api-example/test/factories"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -43,7 +46,7 @@ func (suite *GetPortfolioByIdSuite) TestGetPortfolioByIdSuccess() {
 	portfolio := factories.GetPortfolio()
 	portfolioIdStr := fmt.Sprintf("%d", portfolio.Id)
 
-	suite.portfolioRepository.EXPECT().GetPortfolioById(portfolio.Id).Return(portfolio, nil).Once()
+	suite.portfolioRepository.EXPECT().GetPortfolioById(mock.Anything, portfolio.Id).Return(portfolio, nil).Once()
 	portfolioJson, _ := json.Marshal(portfolio)
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -57,6 +60,7 @@ func (suite *GetPortfolioByIdSuite) TestGetPortfolioByIdSuccess() {
 
 	r.NoError(err)
 	r.Contains(rec.Body.String(), string(portfolioJson))
+	r.Equal(fmt.Sprintf(`"%d"`, portfolio.Version), rec.Header().Get(HeaderETag))
 }
 
 func (suite *GetPortfolioByIdSuite) TestGetPortfolioByIdBadRequest() {
@@ -87,7 +91,7 @@ func (suite *GetPortfolioByIdSuite) TestGetPortfolioByIdNotFound() {
 	portfolio := factories.GetPortfolio()
 	portfolioIdStr := fmt.Sprintf("%d", portfolio.Id)
 
-	suite.portfolioRepository.EXPECT().GetPortfolioById(portfolio.Id).Return(nil, repository.ErrPortfolioNotFound).Once()
+	suite.portfolioRepository.EXPECT().GetPortfolioById(mock.Anything, portfolio.Id).Return(nil, repository.ErrPortfolioNotFound).Once()
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	rec := httptest.NewRecorder()
@@ -103,3 +107,113 @@ func (suite *GetPortfolioByIdSuite) TestGetPortfolioByIdNotFound() {
 	r.True(ok)
 	r.Equal(httpError.Code, http.StatusNotFound)
 }
+
+func (suite *GetPortfolioByIdSuite) TestGetPortfolioByIdAsOf() {
+	r := suite.Require()
+	handler := NewGetPortfolioByIdHandler(suite.portfolioService)
+	portfolio := factories.GetPortfolio()
+	asOf := time.Date(2023, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	suite.portfolioRepository.EXPECT().
+		GetPortfolioAsOf(mock.Anything, portfolio.Id, mock.MatchedBy(asOf.Equal)).
+		Return(portfolio, nil).Once()
+	portfolioJson, _ := json.Marshal(portfolio)
+
+	req := httptest.NewRequest(http.MethodGet, "/?asOf=2023-04-01T01:59:59%2B02:00", nil)
+	rec := httptest.NewRecorder()
+	ctx := suite.e.NewContext(req, rec)
+	ctx.SetPath("/portfolios/:id")
+	ctx.SetParamNames("id")
+	ctx.SetParamValues(fmt.Sprintf("%d", portfolio.Id))
+
+	err := handler(ctx)
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.JSONEq(string(portfolioJson), rec.Body.String())
+	r.Equal(fmt.Sprintf(`"%d"`, portfolio.Version), rec.Header().Get(HeaderETag))
+}
+
+func (suite *GetPortfolioByIdSuite) TestGetPortfolioByIdAsOfErrors() {
+	r := suite.Require()
+	handler := NewGetPortfolioByIdHandler(suite.portfolioService)
+
+	suite.portfolioRepository.EXPECT().GetPortfolioAsOf(mock.Anything, 1, mock.Anything).Return(nil, repository.ErrPortfolioNotFound).Once()
+
+	tt := []struct {
+		target string
+		code   int
+	}{
+		{target: "/?asOf=2023-01-01T00:00:00Z", code: http.StatusNotFound},
+		{target: "/?asOf=yesterday", code: http.StatusBadRequest},
+		{target: "/?asOf=2023-01-01", code: http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		rec := httptest.NewRecorder()
+		ctx := suite.e.NewContext(req, rec)
+		ctx.SetPath("/portfolios/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		err := handler(ctx)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he, tc.target)
+		r.Equal(tc.code, he.Code, tc.target)
+	}
+}
+
+func (suite *GetPortfolioByIdSuite) TestGetPortfolioByIdNotModified() {
+	r := suite.Require()
+	handler := NewGetPortfolioByIdHandler(suite.portfolioService)
+	updatedAt := time.Date(2023, 5, 1, 10, 30, 15, 500, time.UTC)
+	portfolio := &models.Portfolio{Id: 1, Name: "portfolio-1", UpdatedAt: &updatedAt, Version: 3}
+
+	tt := []struct {
+		headers map[string]string
+		code    int
+	}{
+		{headers: map[string]string{HeaderIfNoneMatch: `"3"`}, code: http.StatusNotModified},
+		{headers: map[string]string{HeaderIfNoneMatch: `W/"3"`}, code: http.StatusNotModified},
+		{headers: map[string]string{HeaderIfNoneMatch: `"1", "3"`}, code: http.StatusNotModified},
+		{headers: map[string]string{HeaderIfNoneMatch: `*`}, code: http.StatusNotModified},
+		{headers: map[string]string{HeaderIfNoneMatch: `"2"`}, code: http.StatusOK},
+		{headers: map[string]string{echo.HeaderIfModifiedSince: "Mon, 01 May 2023 10:30:15 GMT"}, code: http.StatusNotModified},
+		{headers: map[string]string{echo.HeaderIfModifiedSince: "Tue, 02 May 2023 00:00:00 GMT"}, code: http.StatusNotModified},
+		{headers: map[string]string{echo.HeaderIfModifiedSince: "Mon, 01 May 2023 10:30:14 GMT"}, code: http.StatusOK},
+		{headers: map[string]string{echo.HeaderIfModifiedSince: "yesterday"}, code: http.StatusOK},
+		{
+			headers: map[string]string{HeaderIfNoneMatch: `"2"`, echo.HeaderIfModifiedSince: "Tue, 02 May 2023 00:00:00 GMT"},
+			code:    http.StatusOK,
+		},
+	}
+
+	for _, tc := range tt {
+		suite.portfolioRepository.EXPECT().GetPortfolioById(mock.Anything, portfolio.Id).Return(portfolio, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		for name, value := range tc.headers {
+			req.Header.Set(name, value)
+		}
+
+		rec := httptest.NewRecorder()
+		ctx := suite.e.NewContext(req, rec)
+		ctx.SetPath("/portfolios/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		err := handler(ctx)
+
+		r.NoError(err)
+		r.Equal(tc.code, rec.Code, tc.headers)
+		r.Equal(`"3"`, rec.Header().Get(HeaderETag))
+		r.Equal("Mon, 01 May 2023 10:30:15 GMT", rec.Header().Get(echo.HeaderLastModified))
+
+		if tc.code == http.StatusNotModified {
+			r.Empty(rec.Body.String())
+		}
+	}
+}
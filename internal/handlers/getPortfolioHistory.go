@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// GetPortfolioHistory responds with one page of the audit history of a portfolio
+// @Summary      Get history of portfolio by id
+// @Description  Every create, update, delete, restore and purge is recorded with its actor, time, the portfolio before and after
+// @Description  the change and the changed fields, oldest first. The history is kept after the portfolio is purged.
+// @Tags         Portfolios
+// @Produce      json
+// @Param        id path int  true "Portfolio ID"
+// @Param        limit query int false "Page size" minimum(1) maximum(100) default(20)
+// @Param        cursor query string false "nextCursor of the previous page"
+// @Param        If-None-Match header string false "ETag of a previous response, responds with 304 if the page did not change"
+// @Success      200  {object}  responses.PortfolioHistoryResponse
+// @Header       200  {string}  ETag  "Changes whenever an entry is added to the page"
+// @Success      304
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
+// @Router       /portfolios/{id}/history [get]
+func NewGetPortfolioHistoryHandler(portfolioService services.PortfolioService) func(echo.Context) error {
+	return func(ctx echo.Context) error {
+		query := &requests.GetPortfolioHistoryRequest{}
+
+		if err := ctx.Bind(query); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+		}
+
+		history, err := portfolioService.GetPortfolioHistory(ctx.Request().Context(), ctx.Param("id"), query)
+
+		if err != nil {
+			return err
+		}
+
+		return collectionJSON(ctx, history)
+	}
+}
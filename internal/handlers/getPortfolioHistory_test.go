@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/alekseyshevchenko93/go-crud-api-example/test/factories"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type GetPortfolioHistorySuite struct {
+	suite.Suite
+	portfolioRepository *mocks.PortfolioRepository
+	portfolioService    services.PortfolioService
+	e                   *echo.Echo
+}
+
+func TestGetPortfolioHistorySuite(t *testing.T) {
+	suite.Run(t, new(GetPortfolioHistorySuite))
+}
+
+func (suite *GetPortfolioHistorySuite) SetupTest() {
+	t := suite.T()
+	e := echo.New()
+	porftolioRepository := mocks.NewPortfolioRepository(t)
+	portfolioService := services.NewPortfolioService(porftolioRepository)
+
+	suite.e = e
+	suite.portfolioRepository = porftolioRepository
+	suite.portfolioService = portfolioService
+}
+
+func (suite *GetPortfolioHistorySuite) get(id string, target string) (*httptest.ResponseRecorder, error) {
+	handler := NewGetPortfolioHistoryHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	ctx := suite.e.NewContext(req, rec)
+	ctx.SetPath("/portfolios/:id/history")
+	ctx.SetParamNames("id")
+	ctx.SetParamValues(id)
+
+	return rec, handler(ctx)
+}
+
+func historyEntries(portfolio *models.Portfolio) []*models.AuditEntry {
+	updated := *portfolio
+	updated.Name = "renamed"
+	updated.Version++
+
+	return []*models.AuditEntry{
+		{
+			Id: 1, PortfolioId: portfolio.Id, Actor: "alice", Operation: models.AuditCreate, At: time.Unix(0, 0).UTC(),
+			After:   portfolio,
+			Changes: []models.AuditChange{{Field: "name", From: json.RawMessage("null"), To: json.RawMessage(`"` + portfolio.Name + `"`)}},
+		},
+		{
+			Id: 2, PortfolioId: portfolio.Id, Actor: "bob", Operation: models.AuditUpdate, At: time.Unix(60, 0).UTC(),
+			Before: portfolio, After: &updated,
+			Changes: []models.AuditChange{{Field: "name", From: json.RawMessage(`"` + portfolio.Name + `"`), To: json.RawMessage(`"renamed"`)}},
+		},
+	}
+}
+
+func (suite *GetPortfolioHistorySuite) TestGetPortfolioHistory() {
+	r := suite.Require()
+	portfolio := factories.GetPortfolio()
+	entries := historyEntries(portfolio)
+
+	suite.portfolioRepository.EXPECT().
+		GetPortfolioHistory(mock.Anything, portfolio.Id, &repository.AuditQuery{Limit: 20}).
+		Return(&repository.AuditPage{Items: entries}, nil).Once()
+	historyJson, _ := json.Marshal(responses.PortfolioHistoryResponse{Items: entries})
+
+	rec, err := suite.get(fmt.Sprintf("%d", portfolio.Id), "/")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.JSONEq(string(historyJson), rec.Body.String())
+	r.NotEmpty(rec.Header().Get(HeaderETag))
+}
+
+func (suite *GetPortfolioHistorySuite) TestGetPortfolioHistoryNextPage() {
+	r := suite.Require()
+	portfolio := factories.GetPortfolio()
+	entries := historyEntries(portfolio)
+
+	suite.portfolioRepository.EXPECT().
+		GetPortfolioHistory(mock.Anything, portfolio.Id, &repository.AuditQuery{Limit: 1}).
+		Return(&repository.AuditPage{Items: entries[:1], HasMore: true}, nil).Once()
+
+	rec, err := suite.get(fmt.Sprintf("%d", portfolio.Id), "/?limit=1")
+	r.NoError(err)
+
+	response := responses.PortfolioHistoryResponse{}
+	r.NoError(json.Unmarshal(rec.Body.Bytes(), &response))
+	r.NotEmpty(response.NextCursor)
+
+	suite.portfolioRepository.EXPECT().
+		GetPortfolioHistory(mock.Anything, portfolio.Id, &repository.AuditQuery{Limit: 1, After: 1}).
+		Return(&repository.AuditPage{Items: entries[1:]}, nil).Once()
+
+	rec, err = suite.get(fmt.Sprintf("%d", portfolio.Id), "/?limit=1&cursor="+response.NextCursor)
+	r.NoError(err)
+
+	response = responses.PortfolioHistoryResponse{}
+	r.NoError(json.Unmarshal(rec.Body.Bytes(), &response))
+	r.Len(response.Items, 1)
+	r.Equal(models.AuditUpdate, response.Items[0].Operation)
+	r.Empty(response.NextCursor)
+}
+
+func (suite *GetPortfolioHistorySuite) TestGetPortfolioHistoryNotFound() {
+	r := suite.Require()
+
+	suite.portfolioRepository.EXPECT().
+		GetPortfolioHistory(mock.Anything, 1, &repository.AuditQuery{Limit: 20}).
+		Return(&repository.AuditPage{Items: []*models.AuditEntry{}}, nil).Once()
+
+	_, err := suite.get("1", "/")
+
+	httpErr, ok := err.(*echo.HTTPError)
+	r.True(ok)
+	r.Equal(http.StatusNotFound, httpErr.Code)
+}
+
+func (suite *GetPortfolioHistorySuite) TestGetPortfolioHistoryBadRequest() {
+	r := suite.Require()
+
+	tt := []struct {
+		id     string
+		target string
+	}{
+		{id: "#$(*)@", target: "/"},
+		{id: "1", target: "/?limit=101"},
+		{id: "1", target: "/?limit=abc"},
+		{id: "1", target: "/?cursor=not-a-cursor"},
+		// A cursor of GET /portfolios sorted by name.
+		{id: "1", target: "/?cursor=eyJzb3J0IjoibmFtZSIsImlkIjoxLCJuYW1lIjoiYSJ9"},
+	}
+
+	for _, tc := range tt {
+		_, err := suite.get(tc.id, tc.target)
+
+		httpErr, ok := err.(*echo.HTTPError)
+		r.True(ok, tc.target)
+		r.Equal(http.StatusBadRequest, httpErr.Code, tc.target)
+	}
+}
+
+func (suite *GetPortfolioHistorySuite) TestGetPortfolioHistoryRepositoryError() {
+	r := suite.Require()
+	repositoryErr := errors.New("database is down")
+
+	suite.portfolioRepository.EXPECT().GetPortfolioHistory(mock.Anything, 1, mock.Anything).Return(nil, repositoryErr).Once()
+
+	_, err := suite.get("1", "/")
+
+	r.ErrorIs(err, repositoryErr)
+}
@@ -1,26 +1,111 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
+	"time"
 
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	"github.com/labstack/echo/v4"
 )
 
-// GetPortfolios responds with the list of all portfolios
-// @Summary      Get portfolios array
+// GetPortfolios responds with one page of matching portfolios
+// @Summary      Get page of portfolios
+// @Description  With Accept: application/x-ndjson all matching portfolios are streamed instead, one JSON object per line.
+// @Description  The stream ignores limit and withTotalCount and has no ETag.
 // @Tags         Portfolios
 // @Produce      json
-// @Success      200  {array}  models.Portfolio
+// @Produce      application/x-ndjson
+// @Param        limit query int false "Page size" minimum(1) maximum(100) default(20)
+// @Param        cursor query string false "nextCursor of the previous page, only valid with the same sort"
+// @Param        sort query string false "Comma separated fields, prefixed with - for descending order, e.g. -updatedAt,name. Ties are ordered by id" default(id)
+// @Param        withTotalCount query bool false "Include the total number of matching portfolios"
+// @Param        asOf query string false "List the portfolios as they were at this time, RFC 3339" format(date-time)
+// @Param        isActive query bool false "Only active or inactive portfolios"
+// @Param        isFinance query bool false "Only finance or non-finance portfolios"
+// @Param        isInternal query bool false "Only internal or external portfolios"
+// @Param        createdFrom query string false "Created at or after, RFC 3339" format(date-time)
+// @Param        createdTo query string false "Created before, RFC 3339" format(date-time)
+// @Param        updatedFrom query string false "Updated at or after, RFC 3339" format(date-time)
+// @Param        updatedTo query string false "Updated before, RFC 3339" format(date-time)
+// @Param        namePrefix query string false "Name starts with, case-sensitive" maxlength(20)
+// @Param        If-None-Match header string false "ETag of a previous response, responds with 304 if the page did not change"
+// @Success      200  {object}  responses.PortfoliosResponse
+// @Header       200  {string}  ETag  "Changes whenever any portfolio on the page changes"
+// @Success      304
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
 // @Router       /portfolios [get]
 func NewGetPortfoliosHandler(portfolioService services.PortfolioService) func(echo.Context) error {
 	return func(ctx echo.Context) error {
-		portfolios, err := portfolioService.GetPortfolios()
+		query := &requests.GetPortfoliosRequest{}
+
+		if err := ctx.Bind(query); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+		}
+
+		if accepts(ctx.Request(), MIMEApplicationNDJSON) {
+			return streamPortfolios(ctx, portfolioService, query)
+		}
+
+		portfolios, err := portfolioService.GetPortfolios(ctx.Request().Context(), query)
 
 		if err != nil {
 			return err
 		}
 
-		return ctx.JSON(http.StatusOK, portfolios)
+		return collectionJSON(ctx, portfolios)
+	}
+}
+
+const (
+	streamFlushCount    = 100
+	streamFlushInterval = time.Second
+)
+
+// streamPortfolios writes the portfolios as newline delimited JSON and flushes them every
+// streamFlushCount portfolios or streamFlushInterval. Writes to a disconnected client fail
+// and its request context is cancelled, either stops the stream.
+func streamPortfolios(ctx echo.Context, portfolioService services.PortfolioService, query *requests.GetPortfoliosRequest) error {
+	response := ctx.Response()
+	encoder := json.NewEncoder(response)
+	pending := 0
+	flushed := time.Now()
+
+	// The status is written with the first portfolio, so that errors before it are still sent as JSON.
+	writeHeader := func() {
+		if !response.Committed {
+			response.Header().Set(echo.HeaderContentType, MIMEApplicationNDJSON)
+			response.WriteHeader(http.StatusOK)
+		}
+	}
+
+	err := portfolioService.StreamPortfolios(ctx.Request().Context(), query, func(portfolio *models.Portfolio) error {
+		writeHeader()
+
+		if err := encoder.Encode(portfolio); err != nil {
+			return err
+		}
+
+		pending++
+
+		if pending >= streamFlushCount || time.Since(flushed) >= streamFlushInterval {
+			response.Flush()
+			pending = 0
+			flushed = time.Now()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
 	}
+
+	writeHeader()
+	response.Flush()
+
+	return nil
 }
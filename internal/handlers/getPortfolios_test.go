@@ -1,16 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
 	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -36,39 +43,384 @@ func (suite *GetPortfoliosSuite) SetupTest() {
 	suite.portfolioService = portfolioService
 }
 
+func (suite *GetPortfoliosSuite) get(target string) (*httptest.ResponseRecorder, error) {
+	handler := NewGetPortfoliosHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	ctx := suite.e.NewContext(req, rec)
+
+	return rec, handler(ctx)
+}
+
 func (suite *GetPortfoliosSuite) TestGetPortfolios() {
 	r := suite.Require()
-	handler := NewGetPortfoliosHandler(suite.portfolioService)
 	portfolios := []*models.Portfolio{
-		{Name: "mock-portfolio", IsActive: true, IsFinance: false, IsInternal: false},
-		{Name: "mock-portfolio-2", IsActive: false, IsFinance: true},
-		{Name: "mock-portfolio-3", IsActive: false, IsFinance: false, IsInternal: true},
+		{Id: 1, Name: "mock-portfolio", IsActive: true, IsFinance: false, IsInternal: false},
+		{Id: 2, Name: "mock-portfolio-2", IsActive: false, IsFinance: true},
+		{Id: 3, Name: "mock-portfolio-3", IsActive: false, IsFinance: false, IsInternal: true},
 	}
-	suite.portfolioRepository.EXPECT().GetPortfolios().Return(portfolios, nil).Once()
-	portfoliosJson, _ := json.Marshal(portfolios)
+	query := &repository.PortfolioQuery{Limit: 20}
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, query).Return(&repository.PortfolioPage{Items: portfolios}, nil).Once()
+	portfoliosJson, _ := json.Marshal(responses.PortfoliosResponse{Items: portfolios})
 
-	req := httptest.NewRequest(http.MethodGet, "/", nil)
-	rec := httptest.NewRecorder()
-	ctx := suite.e.NewContext(req, rec)
+	rec, err := suite.get("/")
 
-	err := handler(ctx)
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.JSONEq(string(portfoliosJson), rec.Body.String())
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosNextPage() {
+	r := suite.Require()
+	portfolios := []*models.Portfolio{
+		{Id: 4, Name: "mock-portfolio-4"},
+		{Id: 7, Name: "mock-portfolio-7"},
+	}
+	firstQuery := &repository.PortfolioQuery{Limit: 2, WithTotalCount: true}
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, firstQuery).Return(&repository.PortfolioPage{Items: portfolios, HasMore: true, TotalCount: 5}, nil).Once()
+
+	rec, err := suite.get("/?limit=2&withTotalCount=true")
 
 	r.NoError(err)
-	r.Contains(rec.Body.String(), string(portfoliosJson))
+
+	var page responses.PortfoliosResponse
+	r.NoError(json.Unmarshal(rec.Body.Bytes(), &page))
+	r.Equal(portfolios, page.Items)
+	r.NotEmpty(page.NextCursor)
+	r.NotNil(page.TotalCount)
+	r.Equal(5, *page.TotalCount)
+
+	secondQuery := &repository.PortfolioQuery{Limit: 2, After: &models.Portfolio{Id: 7}}
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, secondQuery).Return(&repository.PortfolioPage{Items: []*models.Portfolio{}}, nil).Once()
+
+	rec, err = suite.get("/?limit=2&cursor=" + page.NextCursor)
+
+	r.NoError(err)
+	r.JSONEq(`{"items":[]}`, rec.Body.String())
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosInvalidLimit() {
+	r := suite.Require()
+
+	for _, target := range []string{"/?limit=-1", "/?limit=101", "/?limit=abc"} {
+		_, err := suite.get(target)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he, target)
+		r.Equal(http.StatusBadRequest, he.Code, target)
+	}
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosInvalidCursor() {
+	r := suite.Require()
+
+	for _, target := range []string{"/?cursor=not-a-cursor", "/?cursor=e30x"} {
+		_, err := suite.get(target)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he, target)
+		r.Equal(http.StatusBadRequest, he.Code, target)
+		r.Equal("Invalid cursor", he.Message, target)
+	}
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosFilters() {
+	r := suite.Require()
+	createdFrom := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	updatedTo := time.Date(2023, 2, 1, 12, 30, 0, 0, time.FixedZone("", 2*60*60))
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, mock.MatchedBy(func(actual *repository.PortfolioQuery) bool {
+		return actual.Limit == 20 &&
+			*actual.Filter.IsActive && !*actual.Filter.IsFinance && actual.Filter.IsInternal == nil &&
+			actual.Filter.CreatedFrom.Equal(createdFrom) && actual.Filter.CreatedTo == nil &&
+			actual.Filter.UpdatedFrom == nil && actual.Filter.UpdatedTo.Equal(updatedTo) &&
+			actual.Filter.NamePrefix == "fin"
+	})).Return(&repository.PortfolioPage{Items: []*models.Portfolio{}}, nil).Once()
+
+	rec, err := suite.get("/?isActive=true&isFinance=false&createdFrom=2023-01-01T00:00:00Z&updatedTo=2023-02-01T12:30:00%2B02:00&namePrefix=fin")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosAsOf() {
+	r := suite.Require()
+	asOf := time.Date(2023, 3, 31, 23, 59, 59, 0, time.UTC)
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, mock.MatchedBy(func(actual *repository.PortfolioQuery) bool {
+		return actual.AsOf.Equal(asOf) && *actual.Filter.IsActive
+	})).Return(&repository.PortfolioPage{Items: []*models.Portfolio{}}, nil).Once()
+
+	rec, err := suite.get("/?asOf=2023-03-31T23:59:59Z&isActive=true")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+
+	_, err = suite.get("/?asOf=end-of-quarter")
+
+	var he *echo.HTTPError
+	r.ErrorAs(err, &he)
+	r.Equal(http.StatusBadRequest, he.Code)
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosInvalidFilters() {
+	r := suite.Require()
+	targets := []string{
+		"/?isActive=maybe",
+		"/?createdFrom=yesterday",
+		"/?updatedTo=2023-01-01",
+		"/?createdFrom=2023-01-02T00:00:00Z&createdTo=2023-01-01T00:00:00Z",
+		"/?updatedFrom=2023-01-01T00:00:00Z&updatedTo=2023-01-01T00:00:00Z",
+		"/?namePrefix=" + strings.Repeat("a", 21),
+	}
+
+	for _, target := range targets {
+		_, err := suite.get(target)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he, target)
+		r.Equal(http.StatusBadRequest, he.Code, target)
+	}
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosSort() {
+	r := suite.Require()
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	portfolios := []*models.Portfolio{
+		{Id: 3, Name: "b", UpdatedAt: &updatedAt},
+		{Id: 1, Name: "c", UpdatedAt: &updatedAt},
+	}
+	keys := []repository.PortfolioSortKey{
+		{Field: repository.SortByUpdatedAt, Desc: true},
+		{Field: repository.SortByName},
+	}
+	firstQuery := &repository.PortfolioQuery{Sort: keys, Limit: 2}
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, firstQuery).Return(&repository.PortfolioPage{Items: portfolios, HasMore: true}, nil).Once()
+
+	rec, err := suite.get("/?limit=2&sort=-updatedAt,name")
+
+	r.NoError(err)
+
+	var page responses.PortfoliosResponse
+	r.NoError(json.Unmarshal(rec.Body.Bytes(), &page))
+	r.NotEmpty(page.NextCursor)
+
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, mock.MatchedBy(func(actual *repository.PortfolioQuery) bool {
+		return assert.ObjectsAreEqual(keys, actual.Sort) &&
+			actual.After.Id == 1 && actual.After.Name == "c" && actual.After.UpdatedAt.Equal(updatedAt)
+	})).Return(&repository.PortfolioPage{Items: []*models.Portfolio{}}, nil).Once()
+
+	_, err = suite.get("/?limit=2&sort=-updatedAt,name&cursor=" + page.NextCursor)
+	r.NoError(err)
+
+	for _, target := range []string{"/?sort=name&cursor=" + page.NextCursor, "/?cursor=" + page.NextCursor} {
+		_, err = suite.get(target)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he, target)
+		r.Equal(http.StatusBadRequest, he.Code, target)
+		r.Equal("Invalid cursor", he.Message, target)
+	}
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosInvalidSort() {
+	r := suite.Require()
+
+	for _, target := range []string{"/?sort=foo", "/?sort=name,-name", "/?sort=name,", "/?sort=created_at", "/?sort=--id"} {
+		_, err := suite.get(target)
+
+		var he *echo.HTTPError
+		r.ErrorAs(err, &he, target)
+		r.Equal(http.StatusBadRequest, he.Code, target)
+	}
 }
 
 func (suite *GetPortfoliosSuite) TestGetPortfoliosInternalServerError() {
 	r := suite.Require()
 	repoErr := errors.New("some error from repo")
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, mock.Anything).Return(nil, repoErr).Once()
+
+	_, err := suite.get("/")
+
+	r.Error(err)
+	r.True(errors.Is(err, repoErr))
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosPassesRequestContext() {
+	r := suite.Require()
 	handler := NewGetPortfoliosHandler(suite.portfolioService)
-	suite.portfolioRepository.EXPECT().GetPortfolios().Return(nil, repoErr).Once()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
 
-	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
 	rec := httptest.NewRecorder()
 	ctx := suite.e.NewContext(req, rec)
 
+	suite.portfolioRepository.EXPECT().ListPortfolios(reqCtx, mock.Anything).Return(nil, context.Canceled).Once()
+
 	err := handler(ctx)
 
+	r.ErrorIs(err, context.Canceled)
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosNotModified() {
+	r := suite.Require()
+	portfolios := []*models.Portfolio{
+		{Id: 1, Name: "portfolio-1", Version: 1},
+		{Id: 2, Name: "portfolio-2", Version: 1},
+	}
+	suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, mock.Anything).Return(&repository.PortfolioPage{Items: portfolios}, nil).Times(2)
+
+	rec, err := suite.get("/")
+	r.NoError(err)
+	etag := rec.Header().Get(HeaderETag)
+	r.NotEmpty(etag)
+
+	handler := NewGetPortfoliosHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderIfNoneMatch, etag)
+	rec = httptest.NewRecorder()
+
+	r.NoError(handler(suite.e.NewContext(req, rec)))
+	r.Equal(http.StatusNotModified, rec.Code)
+	r.Empty(rec.Body.String())
+	r.Equal(etag, rec.Header().Get(HeaderETag))
+
+	changes := [][]*models.Portfolio{
+		{{Id: 1, Name: "portfolio-1", Version: 2}, {Id: 2, Name: "portfolio-2", Version: 1}},
+		{{Id: 1, Name: "portfolio-1", Version: 1}},
+		{{Id: 1, Name: "portfolio-1", Version: 1}, {Id: 2, Name: "portfolio-2", Version: 1}, {Id: 3, Name: "portfolio-3", Version: 1}},
+	}
+
+	for _, changed := range changes {
+		suite.portfolioRepository.EXPECT().ListPortfolios(mock.Anything, mock.Anything).Return(&repository.PortfolioPage{Items: changed}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderIfNoneMatch, etag)
+		rec := httptest.NewRecorder()
+
+		r.NoError(handler(suite.e.NewContext(req, rec)))
+		r.Equal(http.StatusOK, rec.Code)
+		r.NotEqual(etag, rec.Header().Get(HeaderETag))
+	}
+}
+
+// disconnectingRecorder cancels the request context on the first flush, like a client that goes away.
+type disconnectingRecorder struct {
+	*httptest.ResponseRecorder
+	cancel  context.CancelFunc
+	flushes int
+}
+
+func (rec *disconnectingRecorder) Flush() {
+	rec.flushes++
+	rec.ResponseRecorder.Flush()
+	rec.cancel()
+}
+
+// yieldPortfolios yields the portfolios while the context is not done, like the repositories do.
+func yieldPortfolios(portfolios []*models.Portfolio) func(context.Context, *repository.PortfolioQuery, func(*models.Portfolio) error) error {
+	return func(ctx context.Context, _ *repository.PortfolioQuery, yield func(*models.Portfolio) error) error {
+		for _, portfolio := range portfolios {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := yield(portfolio); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func (suite *GetPortfoliosSuite) getNDJSON(target string) (*httptest.ResponseRecorder, error) {
+	handler := NewGetPortfoliosHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set(echo.HeaderAccept, "application/json;q=0.5, application/x-ndjson")
+	rec := httptest.NewRecorder()
+
+	return rec, handler(suite.e.NewContext(req, rec))
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosNDJSON() {
+	r := suite.Require()
+	isActive := true
+	portfolios := []*models.Portfolio{
+		{Id: 1, Name: "portfolio-1", IsActive: true, Version: 1},
+		{Id: 3, Name: "portfolio-3", IsActive: true, Version: 2},
+	}
+
+	suite.portfolioRepository.EXPECT().
+		StreamPortfolios(mock.Anything, mock.MatchedBy(func(query *repository.PortfolioQuery) bool {
+			return *query.Filter.IsActive == isActive && query.Sort[0].Field == repository.SortByName
+		}), mock.Anything).
+		RunAndReturn(yieldPortfolios(portfolios)).
+		Once()
+
+	rec, err := suite.getNDJSON("/?isActive=true&sort=name&limit=1")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal(MIMEApplicationNDJSON, rec.Header().Get(echo.HeaderContentType))
+	r.Empty(rec.Header().Get(HeaderETag))
+	r.True(rec.Flushed)
+
+	lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+	r.Len(lines, len(portfolios))
+
+	for i, line := range lines {
+		portfolio := &models.Portfolio{}
+		r.NoError(json.Unmarshal([]byte(line), portfolio))
+		r.Equal(portfolios[i], portfolio)
+	}
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosNDJSONEmpty() {
+	r := suite.Require()
+
+	suite.portfolioRepository.EXPECT().StreamPortfolios(mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	rec, err := suite.getNDJSON("/")
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal(MIMEApplicationNDJSON, rec.Header().Get(echo.HeaderContentType))
+	r.Empty(rec.Body.String())
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosNDJSONBadRequest() {
+	r := suite.Require()
+
+	rec, err := suite.getNDJSON("/?sort=unknown")
+
 	r.Error(err)
-	r.True(errors.Is(err, repoErr))
+	httpError, ok := err.(*echo.HTTPError)
+	r.True(ok)
+	r.Equal(http.StatusBadRequest, httpError.Code)
+	r.Empty(rec.Header().Get(echo.HeaderContentType))
+}
+
+func (suite *GetPortfoliosSuite) TestGetPortfoliosNDJSONDisconnect() {
+	r := suite.Require()
+	portfolios := make([]*models.Portfolio, 0, 1000)
+
+	for i := 1; i <= 1000; i++ {
+		portfolios = append(portfolios, &models.Portfolio{Id: i, Name: "portfolio", Version: 1})
+	}
+
+	suite.portfolioRepository.EXPECT().StreamPortfolios(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(yieldPortfolios(portfolios)).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := NewGetPortfoliosHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	req.Header.Set(echo.HeaderAccept, MIMEApplicationNDJSON)
+	rec := &disconnectingRecorder{ResponseRecorder: httptest.NewRecorder(), cancel: cancel}
+
+	err := handler(suite.e.NewContext(req, rec))
+
+	r.ErrorIs(err, context.Canceled)
+	r.Equal(1, rec.flushes)
+	r.Equal(streamFlushCount, strings.Count(rec.Body.String(), "\n"))
 }
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	MIMEApplicationNDJSON = "application/x-ndjson"
+)
+
+// portfolioJSON responds with the portfolio, its ETag and Last-Modified,
+// or with 304 Not Modified when a GET request already has this version.
+func portfolioJSON(ctx echo.Context, code int, portfolio *models.Portfolio) error {
+	etag := services.PortfolioETag(portfolio)
+	header := ctx.Response().Header()
+	header.Set(HeaderETag, etag)
+
+	if portfolio.UpdatedAt != nil {
+		header.Set(echo.HeaderLastModified, portfolio.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(ctx.Request(), etag, portfolio.UpdatedAt) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	return ctx.JSON(code, portfolio)
+}
+
+// collectionJSON responds with value and an ETag computed from its JSON, so the ETag
+// changes whenever anything in the response changes, including removed items.
+// Responds with 304 Not Modified when a GET request already has this ETag.
+func collectionJSON(ctx echo.Context, value interface{}) error {
+	body, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	ctx.Response().Header().Set(HeaderETag, etag)
+
+	if isNotModified(ctx.Request(), etag, nil) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	return ctx.JSONBlob(http.StatusOK, body)
+}
+
+// isNotModified evaluates If-None-Match, or If-Modified-Since when there is no
+// If-None-Match, as RFC 9110 requires for GET and HEAD requests.
+func isNotModified(req *http.Request, etag string, lastModified *time.Time) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := req.Header.Get(HeaderIfNoneMatch); ifNoneMatch != "" {
+		return matchesETag(ifNoneMatch, etag)
+	}
+
+	if lastModified == nil {
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get(echo.HeaderIfModifiedSince))
+
+	if err != nil {
+		return false
+	}
+
+	// Last-Modified has a precision of one second.
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// matchesETag uses the weak comparison of RFC 9110, which If-None-Match requires.
+func matchesETag(list string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// accepts reports whether the Accept header of the request lists the media type.
+func accepts(req *http.Request, mediaType string) bool {
+	for _, accepted := range strings.Split(req.Header.Get(echo.HeaderAccept), ",") {
+		accepted, _, _ = strings.Cut(accepted, ";")
+
+		if strings.EqualFold(strings.TrimSpace(accepted), mediaType) {
+			return true
+		}
+	}
+
+	return false
+}
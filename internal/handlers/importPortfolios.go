@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+const maxImportSize = 10 << 20
+
+// ImportPortfolios creates or updates a portfolio for every row of a CSV file and responds with a report per row
+// @Summary      Import portfolios
+// @Description  The file is sent as the body or as the field file of a multipart form. It starts with a header row,
+// @Description  name is required, isInternal, isFinance and isActive are optional and empty values are false.
+// @Description  The read-only columns of an export are ignored. Rows are validated like a created portfolio,
+// @Description  invalid rows are reported with their line number and skipped. With mode upsert existing
+// @Description  portfolios with the same name are updated, otherwise they fail with 409.
+// @Tags         Portfolios
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Param        file formData file false "CSV file, up to 10000 rows and 10 MB"
+// @Param        mode query string false "create or upsert" Enums(create, upsert) default(create)
+// @Param        dryRun query bool false "Only validate and report what would be done"
+// @Produce      json
+// @Success      200  {object}  responses.ImportPortfoliosResponse
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
+// @Router       /portfolios/import [post]
+func NewImportPortfoliosHandler(portfolioService services.PortfolioService) func(echo.Context) error {
+	return func(ctx echo.Context) error {
+		query := &requests.ImportPortfoliosRequest{}
+
+		if err := (&echo.DefaultBinder{}).BindQueryParams(ctx, query); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+		}
+
+		request := ctx.Request()
+		request.Body = http.MaxBytesReader(ctx.Response(), request.Body, maxImportSize)
+
+		file, err := importFile(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		response, err := portfolioService.ImportPortfolios(request.Context(), query, file)
+
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(err, &maxBytesError) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "CSV file must not be larger than 10 MB")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, response)
+	}
+}
+
+// importFile is the uploaded file of a multipart form or the body.
+func importFile(ctx echo.Context) (io.ReadCloser, error) {
+	contentType := ctx.Request().Header.Get(echo.HeaderContentType)
+
+	if !strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		return ctx.Request().Body, nil
+	}
+
+	header, err := ctx.FormFile("file")
+
+	var maxBytesError *http.MaxBytesError
+
+	if errors.As(err, &maxBytesError) {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "CSV file must not be larger than 10 MB")
+	}
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Form field file is required")
+	}
+
+	return header.Open()
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ImportPortfoliosSuite struct {
+	suite.Suite
+	portfolioRepository *mocks.PortfolioRepository
+	portfolioService    services.PortfolioService
+	e                   *echo.Echo
+}
+
+func TestImportPortfoliosSuite(t *testing.T) {
+	suite.Run(t, new(ImportPortfoliosSuite))
+}
+
+func (suite *ImportPortfoliosSuite) SetupTest() {
+	t := suite.T()
+	e := echo.New()
+	porftolioRepository := mocks.NewPortfolioRepository(t)
+	portfolioService := services.NewPortfolioService(porftolioRepository)
+
+	suite.e = e
+	suite.portfolioRepository = porftolioRepository
+	suite.portfolioService = portfolioService
+}
+
+func (suite *ImportPortfoliosSuite) post(target string, contentType string, body []byte) (*responses.ImportPortfoliosResponse, error) {
+	handler := NewImportPortfoliosHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	rec := httptest.NewRecorder()
+
+	if err := handler(suite.e.NewContext(req, rec)); err != nil {
+		return nil, err
+	}
+
+	suite.Require().Equal(http.StatusOK, rec.Code)
+	response := &responses.ImportPortfoliosResponse{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), response))
+
+	return response, nil
+}
+
+func (suite *ImportPortfoliosSuite) postCSV(target string, lines ...string) (*responses.ImportPortfoliosResponse, error) {
+	return suite.post(target, "text/csv", []byte(strings.Join(lines, "\n")))
+}
+
+func (suite *ImportPortfoliosSuite) TestImportPortfoliosCreate() {
+	r := suite.Require()
+	created := &models.Portfolio{Id: 3, Name: "portfolio-3", IsActive: true, Version: 1}
+
+	expected := []repository.PortfolioOperation{
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-3", IsActive: true}},
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-1", IsFinance: true}},
+	}
+
+	suite.portfolioRepository.EXPECT().ApplyPortfolioOperations(mock.Anything, expected, false).Return([]repository.PortfolioOperationResult{
+		{Portfolio: created},
+		{Err: repository.ErrPortfolioAlreadyExists},
+	}, nil).Once()
+
+	response, err := suite.postCSV("/",
+		"id,name,isInternal,isFinance,isActive,createdAt,updatedAt,version",
+		"7,portfolio-3,false,false,true,2023-05-01T10:30:15Z,2023-05-01T10:30:15Z,2",
+		",portfolio-1,,1,,,,",
+		",,false,false,false,,,",
+		",portfolio-4,no-bool,false,false,,,",
+		",portfolio-3,false,false,false,,,",
+		",name-longer-than-twenty-characters,false,false,false,,,",
+		",portfolio-5,false",
+	)
+
+	r.NoError(err)
+	r.False(response.DryRun)
+	r.Equal(1, response.Created)
+	r.Equal(0, response.Updated)
+	r.Equal(6, response.Failed)
+	r.Len(response.Rows, 7)
+
+	tt := []struct {
+		line   int
+		status int
+		action string
+	}{
+		{line: 2, status: http.StatusCreated, action: "create"},
+		{line: 3, status: http.StatusConflict},
+		{line: 4, status: http.StatusBadRequest},
+		{line: 5, status: http.StatusBadRequest},
+		{line: 6, status: http.StatusBadRequest},
+		{line: 7, status: http.StatusBadRequest},
+		{line: 8, status: http.StatusBadRequest},
+	}
+
+	for i, tc := range tt {
+		row := response.Rows[i]
+		r.Equal(tc.line, row.Line)
+		r.Equal(tc.status, row.Status, row)
+		r.Equal(tc.action, row.Action)
+
+		if tc.status >= http.StatusBadRequest {
+			r.NotEmpty(row.Error)
+			r.Nil(row.Portfolio)
+		}
+	}
+
+	r.Equal(created, response.Rows[0].Portfolio)
+	r.Contains(response.Rows[4].Error, "line 2")
+}
+
+func (suite *ImportPortfoliosSuite) TestImportPortfoliosUpsert() {
+	r := suite.Require()
+	existing := &models.Portfolio{Id: 1, Name: "portfolio-1", Version: 4}
+	created := &models.Portfolio{Id: 3, Name: "portfolio-3", Version: 1}
+	updated := &models.Portfolio{Id: 1, Name: "portfolio-1", IsInternal: true, Version: 5}
+
+	expected := []repository.PortfolioOperation{
+		{Type: repository.OperationUpdate, Update: &models.Portfolio{Id: 1, Name: "portfolio-1", IsInternal: true, Version: 4}},
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-3"}},
+	}
+
+	suite.portfolioRepository.EXPECT().GetPortfolios(mock.Anything).Return([]*models.Portfolio{existing}, nil).Once()
+	suite.portfolioRepository.EXPECT().ApplyPortfolioOperations(mock.Anything, expected, false).Return([]repository.PortfolioOperationResult{
+		{Portfolio: updated},
+		{Portfolio: created},
+	}, nil).Once()
+
+	response, err := suite.postCSV("/?mode=upsert",
+		"name,isInternal",
+		"portfolio-1,true",
+		"portfolio-3,false",
+	)
+
+	r.NoError(err)
+	r.Equal(1, response.Created)
+	r.Equal(1, response.Updated)
+	r.Equal(0, response.Failed)
+	r.Equal("update", response.Rows[0].Action)
+	r.Equal(http.StatusOK, response.Rows[0].Status)
+	r.Equal(updated, response.Rows[0].Portfolio)
+	r.Equal("create", response.Rows[1].Action)
+	r.Equal(http.StatusCreated, response.Rows[1].Status)
+}
+
+func (suite *ImportPortfoliosSuite) TestImportPortfoliosDryRun() {
+	r := suite.Require()
+	existing := &models.Portfolio{Id: 1, Name: "portfolio-1", Version: 4}
+
+	suite.portfolioRepository.EXPECT().GetPortfolios(mock.Anything).Return([]*models.Portfolio{existing}, nil).Twice()
+
+	response, err := suite.postCSV("/?dryRun=true", "name", "portfolio-1", "portfolio-3")
+
+	r.NoError(err)
+	r.True(response.DryRun)
+	r.Equal(1, response.Created)
+	r.Equal(1, response.Failed)
+	r.Equal(http.StatusConflict, response.Rows[0].Status)
+	r.Equal(http.StatusCreated, response.Rows[1].Status)
+	r.Nil(response.Rows[1].Portfolio)
+
+	response, err = suite.postCSV("/?dryRun=true&mode=upsert", "name", "portfolio-1", "portfolio-3")
+
+	r.NoError(err)
+	r.Equal(1, response.Created)
+	r.Equal(1, response.Updated)
+	r.Equal("update", response.Rows[0].Action)
+	r.Equal(http.StatusOK, response.Rows[0].Status)
+}
+
+func (suite *ImportPortfoliosSuite) TestImportPortfoliosMultipart() {
+	r := suite.Require()
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	file, _ := form.CreateFormFile("file", "portfolios.csv")
+	_, _ = file.Write([]byte("\ufeffname\nportfolio-3\n"))
+	r.NoError(form.Close())
+
+	created := &models.Portfolio{Id: 3, Name: "portfolio-3", Version: 1}
+	expected := []repository.PortfolioOperation{
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-3"}},
+	}
+
+	suite.portfolioRepository.EXPECT().ApplyPortfolioOperations(mock.Anything, expected, false).Return([]repository.PortfolioOperationResult{
+		{Portfolio: created},
+	}, nil).Once()
+
+	response, err := suite.post("/", form.FormDataContentType(), body.Bytes())
+
+	r.NoError(err)
+	r.Equal(1, response.Created)
+	r.Equal(created, response.Rows[0].Portfolio)
+}
+
+func (suite *ImportPortfoliosSuite) TestImportPortfoliosRoundTripsExportedNames() {
+	r := suite.Require()
+	names := []string{"=HYPERLINK(A1)", "'-hedge", "''quoted", "'portfolio", "-hedge", "portfolio-1"}
+	expected := make([]repository.PortfolioOperation, 0, len(names))
+	results := make([]repository.PortfolioOperationResult, 0, len(names))
+
+	exported := &bytes.Buffer{}
+	writer := csv.NewWriter(exported)
+	r.NoError(writer.Write(services.PortfolioCSVHeader))
+
+	for i, name := range names {
+		r.NoError(writer.Write(services.PortfolioCSVRecord(&models.Portfolio{Id: i + 1, Name: name, Version: 1})))
+		expected = append(expected, repository.PortfolioOperation{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: name}})
+		results = append(results, repository.PortfolioOperationResult{Portfolio: &models.Portfolio{Id: i + 1, Name: name, Version: 1}})
+	}
+
+	writer.Flush()
+	r.NoError(writer.Error())
+	r.Contains(exported.String(), "'=HYPERLINK(A1)")
+	r.Contains(exported.String(), "''-hedge")
+
+	suite.portfolioRepository.EXPECT().ApplyPortfolioOperations(mock.Anything, expected, false).Return(results, nil).Once()
+
+	response, err := suite.post("/", "text/csv", exported.Bytes())
+
+	r.NoError(err)
+	r.Equal(len(names), response.Created)
+
+	for i, name := range names {
+		r.Equal(name, response.Rows[i].Name)
+	}
+}
+
+func (suite *ImportPortfoliosSuite) TestImportPortfoliosBadRequest() {
+	r := suite.Require()
+	tt := []struct {
+		target string
+		body   string
+	}{
+		{target: "/", body: ""},
+		{target: "/", body: "isActive\ntrue"},
+		{target: "/", body: "name,owner\nportfolio-1,someone"},
+		{target: "/", body: "name,name\nportfolio-1,portfolio-1"},
+		{target: "/", body: "name\n\"portfolio-1"},
+		{target: "/?mode=replace", body: "name\nportfolio-1"},
+		{target: "/?dryRun=maybe", body: "name\nportfolio-1"},
+	}
+
+	for _, tc := range tt {
+		_, err := suite.postCSV(tc.target, tc.body)
+
+		r.Error(err, tc.body)
+		httpError, ok := err.(*echo.HTTPError)
+		r.True(ok)
+		r.Equal(http.StatusBadRequest, httpError.Code, tc.body)
+	}
+
+	_, err := suite.post("/", echo.MIMEMultipartForm+"; boundary=x", []byte("--x--\r\n"))
+
+	r.Error(err)
+	httpError, ok := err.(*echo.HTTPError)
+	r.True(ok)
+	r.Equal(http.StatusBadRequest, httpError.Code)
+}
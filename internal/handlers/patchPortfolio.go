@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+	MIMEApplicationJSONPatch      = "application/json-patch+json"
+)
+
+// PatchPortfolio changes only the fields present in the body and responds with updated portfolio
+// @Summary      Partially updates portfolio
+// @Description  Applies a JSON merge patch (RFC 7396) or a JSON patch (RFC 6902) to name, isInternal, isFinance and isActive.
+// @Description  With a merge patch fields missing from the body keep their values, null resets a flag to false.
+// @Description  A JSON patch is applied atomically, a failed test operation responds with 409.
+// @Description  Patches that change id, createdAt, updatedAt or version are rejected.
+// @Tags         Portfolios
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Param        id path int  true "Portfolio ID"
+// @Param        patch body object true "Merge patch object or array of JSON patch operations"
+// @Param        If-Match header string false "ETag of the portfolio, responds with 412 if it was changed"
+// @Produce      json
+// @Success      200  {object}  models.Portfolio
+// @Header       200  {string}  ETag  "Portfolio version"
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
+// @Router       /portfolios/{id} [patch]
+func NewPatchPortfolioHandler(portfolioService services.PortfolioService) func(echo.Context) error {
+	return func(ctx echo.Context) error {
+		id := ctx.Param("id")
+		mediaType, _, _ := mime.ParseMediaType(ctx.Request().Header.Get(echo.HeaderContentType))
+		var apply func(context.Context, string, string, []byte) (*models.Portfolio, error)
+
+		switch mediaType {
+		case MIMEApplicationMergePatchJSON:
+			apply = portfolioService.MergePatchPortfolio
+		case MIMEApplicationJSONPatch:
+			apply = portfolioService.JSONPatchPortfolio
+		default:
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be "+MIMEApplicationMergePatchJSON+" or "+MIMEApplicationJSONPatch)
+		}
+
+		body, err := io.ReadAll(ctx.Request().Body)
+
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid body")
+		}
+
+		portfolio, err := apply(ctx.Request().Context(), id, ctx.Request().Header.Get(HeaderIfMatch), body)
+
+		if err != nil {
+			return err
+		}
+
+		return portfolioJSON(ctx, http.StatusOK, portfolio)
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/alekseyshevchenko93/go-crud-api-example/test/factories"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PatchPortfolioSuite struct {
+	suite.Suite
+	portfolioRepository *mocks.PortfolioRepository
+	portfolioService    services.PortfolioService
+	e                   *echo.Echo
+}
+
+func TestPatchPortfolioSuite(t *testing.T) {
+	suite.Run(t, new(PatchPortfolioSuite))
+}
+
+func (suite *PatchPortfolioSuite) SetupTest() {
+	t := suite.T()
+	e := echo.New()
+	porftolioRepository := mocks.NewPortfolioRepository(t)
+	portfolioService := services.NewPortfolioService(porftolioRepository)
+
+	suite.e = e
+	suite.portfolioRepository = porftolioRepository
+	suite.portfolioService = portfolioService
+}
+
+func (suite *PatchPortfolioSuite) patch(id, contentType, body string) (*httptest.ResponseRecorder, error) {
+	return suite.patchIfMatch(id, contentType, "", body)
+}
+
+func (suite *PatchPortfolioSuite) patchIfMatch(id, contentType, ifMatch, body string) (*httptest.ResponseRecorder, error) {
+	handler := NewPatchPortfolioHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	req.Header.Set(HeaderIfMatch, ifMatch)
+	rec := httptest.NewRecorder()
+	ctx := suite.e.NewContext(req, rec)
+	ctx.SetPath("/portfolios/:id")
+	ctx.SetParamNames("id")
+	ctx.SetParamValues(id)
+
+	return rec, handler(ctx)
+}
+
+func (suite *PatchPortfolioSuite) requireHTTPError(err error, code int) {
+	r := suite.Require()
+
+	r.Error(err)
+	httpError, ok := err.(*echo.HTTPError)
+	r.True(ok)
+	r.Equal(code, httpError.Code, httpError.Message)
+}
+
+// onUpdateFunc makes the repository run the update function on a copy of stored
+// and returns the portfolio that the repository saves.
+func (suite *PatchPortfolioSuite) onUpdateFunc(stored *models.Portfolio) (*models.Portfolio, *mock.Call) {
+	saved := &models.Portfolio{}
+
+	call := suite.portfolioRepository.EXPECT().UpdatePortfolioFunc(mock.Anything, stored.Id, mock.Anything).
+		RunAndReturn(func(ctx context.Context, id int, update func(*models.Portfolio) error) (*models.Portfolio, error) {
+			portfolio := *stored
+
+			if err := update(&portfolio); err != nil {
+				return nil, err
+			}
+
+			*saved = portfolio
+
+			return saved, nil
+		})
+
+	return saved, call.Call
+}
+
+func (suite *PatchPortfolioSuite) TestMergePatchPortfolioSuccess() {
+	r := suite.Require()
+	portfolio := factories.GetPortfolio()
+	portfolio.IsFinance = true
+
+	expected := *portfolio
+	expected.IsActive = true
+
+	saved, call := suite.onUpdateFunc(portfolio)
+	call.Once()
+
+	rec, err := suite.patch(fmt.Sprintf("%d", portfolio.Id), MIMEApplicationMergePatchJSON, `{"isActive": true}`)
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal(&expected, saved)
+	r.Equal(fmt.Sprintf(`"%d"`, saved.Version), rec.Header().Get(HeaderETag))
+
+	responseJson, _ := json.Marshal(saved)
+	r.JSONEq(string(responseJson), rec.Body.String())
+}
+
+func (suite *PatchPortfolioSuite) TestMergePatchPortfolioKeepsMissingFields() {
+	r := suite.Require()
+	portfolio := &models.Portfolio{Id: 3, Name: "portfolio-3", IsActive: true, IsFinance: true, IsInternal: true}
+	saved, call := suite.onUpdateFunc(portfolio)
+	call.Once()
+
+	_, err := suite.patch("3", MIMEApplicationMergePatchJSON+"; charset=utf-8", `{"name": "renamed", "isFinance": null}`)
+
+	r.NoError(err)
+	r.Equal(&models.Portfolio{Id: 3, Name: "renamed", IsActive: true, IsFinance: false, IsInternal: true}, saved)
+}
+
+func (suite *PatchPortfolioSuite) TestMergePatchPortfolioBadRequests() {
+	patches := []string{
+		`not json`,
+		`{"name": ""}`,
+		`{"name": null}`,
+		`{"name": "here-should-be-20-symbols"}`,
+		`{"isActive": "yes"}`,
+		`{"id": 2}`,
+		`{"id": 1}`,
+		`{"createdAt": "2023-01-01T00:00:00Z"}`,
+		`{"updatedAt": null}`,
+		`{"deletedAt": "2023-01-01T00:00:00Z"}`,
+		`{"version": 5}`,
+		`{"unknown": 1}`,
+		`["isActive"]`,
+		`true`,
+	}
+
+	_, call := suite.onUpdateFunc(&models.Portfolio{Id: 1, Name: "portfolio-1"})
+	call.Maybe()
+
+	for _, body := range patches {
+		_, err := suite.patch("1", MIMEApplicationMergePatchJSON, body)
+
+		suite.requireHTTPError(err, http.StatusBadRequest)
+	}
+
+	_, err := suite.patch("some-test-string", MIMEApplicationMergePatchJSON, `{}`)
+	suite.requireHTTPError(err, http.StatusBadRequest)
+}
+
+func (suite *PatchPortfolioSuite) TestJSONPatchPortfolioSuccess() {
+	r := suite.Require()
+	portfolio := &models.Portfolio{Id: 2, Name: "portfolio-2", IsActive: true}
+	saved, call := suite.onUpdateFunc(portfolio)
+	call.Once()
+
+	body := `[
+		{"op": "test", "path": "/isActive", "value": true},
+		{"op": "test", "path": "/id", "value": 2},
+		{"op": "replace", "path": "/name", "value": "renamed"},
+		{"op": "copy", "from": "/isActive", "path": "/isFinance"}
+	]`
+
+	rec, err := suite.patch("2", MIMEApplicationJSONPatch, body)
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal(&models.Portfolio{Id: 2, Name: "renamed", IsActive: true, IsFinance: true}, saved)
+}
+
+func (suite *PatchPortfolioSuite) TestJSONPatchPortfolioFailedTest() {
+	r := suite.Require()
+	portfolio := &models.Portfolio{Id: 2, Name: "portfolio-2", IsActive: false}
+	saved, call := suite.onUpdateFunc(portfolio)
+	call.Once()
+
+	body := `[
+		{"op": "replace", "path": "/name", "value": "renamed"},
+		{"op": "test", "path": "/isActive", "value": true}
+	]`
+
+	_, err := suite.patch("2", MIMEApplicationJSONPatch, body)
+
+	suite.requireHTTPError(err, http.StatusConflict)
+	r.Zero(*saved, "nothing is saved when a test fails")
+}
+
+func (suite *PatchPortfolioSuite) TestJSONPatchPortfolioBadRequests() {
+	patches := []string{
+		`not json`,
+		`{"op": "replace", "path": "/name", "value": "renamed"}`,
+		`[{"op": "replace", "path": "/name"}]`,
+		`[{"op": "increment", "path": "/name", "value": 1}]`,
+		`[{"op": "replace", "path": "/id", "value": 2}]`,
+		`[{"op": "remove", "path": "/createdAt"}]`,
+		`[{"op": "add", "path": "/updatedAt", "value": "2023-01-01T00:00:00Z"}]`,
+		`[{"op": "add", "path": "/deletedAt", "value": "2023-01-01T00:00:00Z"}]`,
+		`[{"op": "move", "from": "/id", "path": "/name"}]`,
+		`[{"op": "replace", "path": "", "value": {}}]`,
+		`[{"op": "replace", "path": "/name", "value": ""}]`,
+		`[{"op": "replace", "path": "/isActive", "value": "yes"}]`,
+		`[{"op": "add", "path": "/unknown", "value": 1}]`,
+		`[{"op": "remove", "path": "/missing"}]`,
+		`[{"op": "copy", "from": "/createdAt", "path": "/isActive"}]`,
+		`[{"op": "replace", "path": "/version", "value": 5}]`,
+	}
+
+	now := time.Now()
+	_, call := suite.onUpdateFunc(&models.Portfolio{Id: 1, Name: "portfolio-1", CreatedAt: &now, UpdatedAt: &now})
+	call.Maybe()
+
+	for _, body := range patches {
+		_, err := suite.patch("1", MIMEApplicationJSONPatch, body)
+
+		suite.requireHTTPError(err, http.StatusBadRequest)
+	}
+}
+
+func (suite *PatchPortfolioSuite) TestPatchPortfolioUnsupportedMediaType() {
+	for _, contentType := range []string{echo.MIMEApplicationJSON, "", "text/plain"} {
+		_, err := suite.patch("1", contentType, `{"isActive": true}`)
+
+		suite.requireHTTPError(err, http.StatusUnsupportedMediaType)
+	}
+}
+
+func (suite *PatchPortfolioSuite) TestPatchPortfolioNotFound() {
+	patches := map[string]string{
+		MIMEApplicationMergePatchJSON: `{"isActive": true}`,
+		MIMEApplicationJSONPatch:      `[{"op": "replace", "path": "/isActive", "value": true}]`,
+	}
+
+	for contentType, body := range patches {
+		suite.portfolioRepository.EXPECT().UpdatePortfolioFunc(mock.Anything, 1, mock.Anything).Return(nil, repository.ErrPortfolioNotFound).Once()
+
+		_, err := suite.patch("1", contentType, body)
+
+		suite.requireHTTPError(err, http.StatusNotFound)
+	}
+}
+
+func (suite *PatchPortfolioSuite) TestPatchPortfolioConflict() {
+	patches := map[string]string{
+		MIMEApplicationMergePatchJSON: `{"name": "portfolio-2"}`,
+		MIMEApplicationJSONPatch:      `[{"op": "replace", "path": "/name", "value": "portfolio-2"}]`,
+	}
+
+	for contentType, body := range patches {
+		suite.portfolioRepository.EXPECT().UpdatePortfolioFunc(mock.Anything, 1, mock.Anything).
+			RunAndReturn(func(ctx context.Context, id int, update func(*models.Portfolio) error) (*models.Portfolio, error) {
+				if err := update(&models.Portfolio{Id: 1, Name: "portfolio-1"}); err != nil {
+					return nil, err
+				}
+
+				return nil, repository.ErrPortfolioAlreadyExists
+			}).Once()
+
+		_, err := suite.patch("1", contentType, body)
+
+		suite.requireHTTPError(err, http.StatusConflict)
+	}
+}
+
+func (suite *PatchPortfolioSuite) TestPatchPortfolioIfMatch() {
+	r := suite.Require()
+	portfolio := &models.Portfolio{Id: 1, Name: "portfolio-1", Version: 3}
+	patches := map[string]string{
+		MIMEApplicationMergePatchJSON: `{"isActive": true}`,
+		MIMEApplicationJSONPatch:      `[{"op": "replace", "path": "/isActive", "value": true}]`,
+	}
+
+	for contentType, body := range patches {
+		saved, call := suite.onUpdateFunc(portfolio)
+		call.Times(3)
+
+		_, err := suite.patchIfMatch("1", contentType, `"3"`, body)
+		r.NoError(err)
+		r.True(saved.IsActive)
+
+		*saved = models.Portfolio{}
+		_, err = suite.patchIfMatch("1", contentType, `"2"`, body)
+		suite.requireHTTPError(err, http.StatusPreconditionFailed)
+		r.Zero(*saved, "nothing is saved when the version does not match")
+
+		_, err = suite.patchIfMatch("1", contentType, `*`, body)
+		r.NoError(err)
+		r.True(saved.IsActive)
+
+		_, err = suite.patchIfMatch("1", contentType, `W/"3"`, body)
+		suite.requireHTTPError(err, http.StatusBadRequest)
+	}
+}
+
+func (suite *PatchPortfolioSuite) TestJSONPatchPortfolioTestVersion() {
+	portfolio := &models.Portfolio{Id: 1, Name: "portfolio-1", Version: 3}
+	_, call := suite.onUpdateFunc(portfolio)
+	call.Once()
+
+	_, err := suite.patch("1", MIMEApplicationJSONPatch, `[{"op": "test", "path": "/version", "value": 2}]`)
+
+	suite.requireHTTPError(err, http.StatusConflict)
+}
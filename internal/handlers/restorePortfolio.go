@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// RestorePortfolio undoes the soft delete of a portfolio
+// @Summary      Restores deleted portfolio by id
+// @Description  Responds with 409 if the portfolio is not deleted or its name was taken by another portfolio in the meantime.
+// @Tags         Portfolios
+// @Produce      json
+// @Success      200  {object}  models.Portfolio
+// @Header       200  {string}  ETag  "Portfolio version, send it in If-Match to update or delete"
+// @Param        id path int  true "Portfolio ID"
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
+// @Router       /portfolios/{id}/restore [post]
+func NewRestorePortfolioHandler(portfolioService services.PortfolioService) func(echo.Context) error {
+	return func(ctx echo.Context) error {
+		id := ctx.Param("id")
+		portfolio, err := portfolioService.RestorePortfolio(ctx.Request().Context(), id)
+
+		if err != nil {
+			return err
+		}
+
+		return portfolioJSON(ctx, http.StatusOK, portfolio)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/alekseyshevchenko93/go-crud-api-example/test/factories"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type RestorePortfolioSuite struct {
+	suite.Suite
+	portfolioRepository *mocks.PortfolioRepository
+	portfolioService    services.PortfolioService
+	e                   *echo.Echo
+}
+
+func TestRestorePortfolioSuite(t *testing.T) {
+	suite.Run(t, new(RestorePortfolioSuite))
+}
+
+func (suite *RestorePortfolioSuite) SetupTest() {
+	t := suite.T()
+	e := echo.New()
+	porftolioRepository := mocks.NewPortfolioRepository(t)
+	portfolioService := services.NewPortfolioService(porftolioRepository)
+
+	suite.e = e
+	suite.portfolioRepository = porftolioRepository
+	suite.portfolioService = portfolioService
+}
+
+func (suite *RestorePortfolioSuite) restore(id string) (*httptest.ResponseRecorder, error) {
+	handler := NewRestorePortfolioHandler(suite.portfolioService)
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	ctx := suite.e.NewContext(req, rec)
+	ctx.SetPath("/portfolios/:id/restore")
+	ctx.SetParamNames("id")
+	ctx.SetParamValues(id)
+
+	return rec, handler(ctx)
+}
+
+func (suite *RestorePortfolioSuite) TestRestorePortfolioSuccess() {
+	r := suite.Require()
+	portfolio := factories.GetPortfolio()
+
+	suite.portfolioRepository.EXPECT().RestorePortfolio(mock.Anything, portfolio.Id).Return(portfolio, nil).Once()
+	portfolioJson, _ := json.Marshal(portfolio)
+
+	rec, err := suite.restore(fmt.Sprintf("%d", portfolio.Id))
+
+	r.NoError(err)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Contains(rec.Body.String(), string(portfolioJson))
+	r.Equal(fmt.Sprintf(`"%d"`, portfolio.Version), rec.Header().Get(HeaderETag))
+}
+
+func (suite *RestorePortfolioSuite) TestRestorePortfolioErrors() {
+	r := suite.Require()
+	portfolio := factories.GetPortfolio()
+
+	tt := []struct {
+		err  error
+		code int
+	}{
+		{err: repository.ErrPortfolioNotFound, code: http.StatusNotFound},
+		{err: repository.ErrPortfolioNotDeleted, code: http.StatusConflict},
+		{err: repository.ErrPortfolioAlreadyExists, code: http.StatusConflict},
+	}
+
+	for _, tc := range tt {
+		suite.portfolioRepository.EXPECT().RestorePortfolio(mock.Anything, portfolio.Id).Return(nil, tc.err).Once()
+
+		_, err := suite.restore(fmt.Sprintf("%d", portfolio.Id))
+
+		r.Error(err)
+		httpError, ok := err.(*echo.HTTPError)
+		r.True(ok)
+		r.Equal(tc.code, httpError.Code, tc.err)
+	}
+}
+
+func (suite *RestorePortfolioSuite) TestRestorePortfolioBadRequest() {
+	r := suite.Require()
+
+	for _, portfolioId := range []string{"", "some-string", "#$(*)@"} {
+		_, err := suite.restore(portfolioId)
+
+		r.Error(err)
+		httpError, ok := err.(*echo.HTTPError)
+		r.True(ok)
+		r.Equal(http.StatusBadRequest, httpError.Code, portfolioId)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/idempotency"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/middlewares"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+// headerTestSubject authenticates requests of TenancySuite as a principal with this subject.
+const headerTestSubject = "X-Test-Subject"
+
+// TenancySuite serves requests of several tenants from one store, selected by the X-Tenant-Id header.
+type TenancySuite struct {
+	suite.Suite
+	e *echo.Echo
+}
+
+func TestTenancySuite(t *testing.T) {
+	suite.Run(t, new(TenancySuite))
+}
+
+func (suite *TenancySuite) SetupTest() {
+	service := services.NewTenantPortfolioService(repository.NewPortfolioStore())
+
+	suite.e = echo.New()
+	suite.e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if subject := c.Request().Header.Get(headerTestSubject); subject != "" {
+				principal := &auth.Principal{Subject: subject, Tenant: c.Request().Header.Get(middlewares.HeaderTenant)}
+				c.SetRequest(c.Request().WithContext(auth.ContextWithPrincipal(c.Request().Context(), principal)))
+			}
+
+			return next(c)
+		}
+	})
+	suite.e.Use(middlewares.Tenant)
+	suite.e.GET("/portfolios", NewGetPortfoliosHandler(service))
+	suite.e.GET("/portfolios/:id", NewGetPortfolioByIdHandler(service))
+	suite.e.DELETE("/portfolios/:id", NewDeletePortfolioHandler(service))
+	suite.e.POST("/portfolios", NewCreatePortfolioHandler(service, idempotency.NewMemoryStore(time.Hour)))
+}
+
+func (suite *TenancySuite) serve(tenant string, method string, path string, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(middlewares.HeaderTenant, tenant)
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	suite.e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (suite *TenancySuite) create(tenant string, name string) *models.Portfolio {
+	rec := suite.serve(tenant, http.MethodPost, "/portfolios", `{"name":"`+name+`"}`)
+	suite.Require().Equal(http.StatusCreated, rec.Code, rec.Body.String())
+
+	portfolio := &models.Portfolio{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), portfolio))
+
+	return portfolio
+}
+
+func (suite *TenancySuite) names(tenant string) []string {
+	rec := suite.serve(tenant, http.MethodGet, "/portfolios", "")
+	suite.Require().Equal(http.StatusOK, rec.Code)
+
+	response := &responses.PortfoliosResponse{}
+	suite.Require().NoError(json.Unmarshal(rec.Body.Bytes(), response))
+
+	names := make([]string, 0, len(response.Items))
+
+	for _, portfolio := range response.Items {
+		names = append(names, portfolio.Name)
+	}
+
+	return names
+}
+
+func (suite *TenancySuite) TestTenantsAreIsolated() {
+	r := suite.Require()
+	suite.create("tenant-a", "portfolio-1")
+	second := suite.create("tenant-a", "portfolio-2")
+	suite.create("tenant-b", "portfolio-1")
+
+	r.Equal([]string{"portfolio-1", "portfolio-2"}, suite.names("tenant-a"))
+	r.Equal([]string{"portfolio-1"}, suite.names("tenant-b"))
+	r.Empty(suite.names(""))
+
+	path := fmt.Sprintf("/portfolios/%d", second.Id)
+	r.Equal(http.StatusNotFound, suite.serve("tenant-b", http.MethodGet, path, "").Code)
+	r.Equal(http.StatusNotFound, suite.serve("tenant-b", http.MethodDelete, path, "").Code)
+	r.Equal(http.StatusOK, suite.serve("tenant-a", http.MethodGet, path, "").Code)
+}
+
+func (suite *TenancySuite) TestIdempotencyKeysArePerTenant() {
+	r := suite.Require()
+	body := `{"name":"portfolio-1"}`
+
+	first := suite.serve("tenant-a", http.MethodPost, "/portfolios", body, HeaderIdempotencyKey, "key-1")
+	r.Equal(http.StatusCreated, first.Code)
+
+	other := suite.serve("tenant-b", http.MethodPost, "/portfolios", body, HeaderIdempotencyKey, "key-1")
+	r.Equal(http.StatusCreated, other.Code)
+	r.Empty(other.Header().Get(HeaderIdempotentReplayed), "the key of another tenant must not replay its response")
+
+	replayed := suite.serve("tenant-a", http.MethodPost, "/portfolios", body, HeaderIdempotencyKey, "key-1")
+	r.Equal("true", replayed.Header().Get(HeaderIdempotentReplayed))
+
+	r.Equal([]string{"portfolio-1"}, suite.names("tenant-b"))
+}
+
+func (suite *TenancySuite) TestIdempotencyKeysArePerPrincipal() {
+	r := suite.Require()
+	body := `{"name":"portfolio-1"}`
+
+	first := suite.serve("tenant-a", http.MethodPost, "/portfolios", body, HeaderIdempotencyKey, "key-1", headerTestSubject, "alice")
+	r.Equal(http.StatusCreated, first.Code)
+
+	for _, subject := range []string{"bob", "alice/key-1"} {
+		other := suite.serve("tenant-a", http.MethodPost, "/portfolios", body, HeaderIdempotencyKey, "key-1", headerTestSubject, subject)
+		r.Empty(other.Header().Get(HeaderIdempotentReplayed), "the key of another principal must not replay its response")
+		r.Equal(http.StatusConflict, other.Code, "the name is taken, so the request must reach the service")
+	}
+
+	anonymous := suite.serve("tenant-a", http.MethodPost, "/portfolios", body, HeaderIdempotencyKey, "alice/key-1")
+	r.Empty(anonymous.Header().Get(HeaderIdempotentReplayed))
+
+	replayed := suite.serve("tenant-a", http.MethodPost, "/portfolios", body, HeaderIdempotencyKey, "key-1", headerTestSubject, "alice")
+	r.Equal("true", replayed.Header().Get(HeaderIdempotentReplayed))
+}
+
+func (suite *TenancySuite) TestInvalidTenant() {
+	rec := suite.serve("Tenant A", http.MethodGet, "/portfolios", "")
+
+	suite.Equal(http.StatusBadRequest, rec.Code)
+}
@@ -13,8 +13,12 @@ import (
 // @Tags         Portfolios
 // @Param        id path int  true "Portfolio ID"
 // @Param        portfolio body requests.UpdatePortfolioRequest true "Portfolio Body"
+// @Param        If-Match header string false "ETag of the portfolio, responds with 412 if it was changed"
 // @Produce      json
 // @Success      200  {object}  models.Portfolio
+// @Header       200  {string}  ETag  "Portfolio version"
+// @Param        X-Tenant-Id header string false "Tenant of the request, must match the tenant claim of the token"
+// @Security     BearerAuth
 // @Router       /portfolios [put]
 func NewUpdatePortfolioHandler(portfolioService services.PortfolioService) func(echo.Context) error {
 	return func(ctx echo.Context) error {
@@ -25,12 +29,13 @@ func NewUpdatePortfolioHandler(portfolioService services.PortfolioService) func(
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid json body")
 		}
 
-		portfolio, err := portfolioService.UpdatePortfolio(id, body)
+		ifMatch := ctx.Request().Header.Get(HeaderIfMatch)
+		portfolio, err := portfolioService.UpdatePortfolio(ctx.Request().Context(), id, ifMatch, body)
 
 		if err != nil {
 			return err
 		}
 
-		return ctx.JSON(http.StatusOK, portfolio)
+		return portfolioJSON(ctx, http.StatusOK, portfolio)
 	}
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/services"
 	"github.com/alekseyshevchenko93/go-crud-api-example/test/factories"
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -49,6 +50,10 @@ func (suite *UpdatePortfolioSuite) TestUpdatePortfolioSuccess() {
 	updatedPortfolio := &portfolioCopy
 	updatedPortfolio.Name = "updated-portfolio"
 	updatedPortfolio.IsActive = true
+	updatedPortfolio.Version = 0
+
+	savedPortfolio := *updatedPortfolio
+	savedPortfolio.Version = portfolio.Version + 1
 
 	requestBody := requests.UpdatePortfolioRequest{
 		Id:         updatedPortfolio.Id,
@@ -58,10 +63,10 @@ func (suite *UpdatePortfolioSuite) TestUpdatePortfolioSuccess() {
 		IsInternal: updatedPortfolio.IsInternal,
 	}
 
-	responseJson, _ := json.Marshal(updatedPortfolio)
+	responseJson, _ := json.Marshal(savedPortfolio)
 	requestJson, _ := json.Marshal(requestBody)
-	suite.portfolioRepository.EXPECT().GetPortfolioById(portfolio.Id).Return(portfolio, nil).Once()
-	suite.portfolioRepository.EXPECT().UpdatePortfolio(updatedPortfolio).Return(updatedPortfolio, nil).Once()
+	suite.portfolioRepository.EXPECT().GetPortfolioById(mock.Anything, portfolio.Id).Return(portfolio, nil).Once()
+	suite.portfolioRepository.EXPECT().UpdatePortfolio(mock.Anything, updatedPortfolio).Return(&savedPortfolio, nil).Once()
 
 	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(requestJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -75,6 +80,7 @@ func (suite *UpdatePortfolioSuite) TestUpdatePortfolioSuccess() {
 
 	r.NoError(err)
 	r.Contains(rec.Body.String(), string(responseJson))
+	r.Equal(fmt.Sprintf(`"%d"`, savedPortfolio.Version), rec.Header().Get(HeaderETag))
 }
 
 func (suite *UpdatePortfolioSuite) TestUpdatePortfolioBadRequests() {
@@ -144,7 +150,7 @@ func (suite *UpdatePortfolioSuite) TestUpdatePortfolioNotFound() {
 	}
 
 	responseErr := echo.NewHTTPError(http.StatusNotFound)
-	suite.portfolioRepository.EXPECT().GetPortfolioById(portfolio.Id).Return(nil, responseErr).Once()
+	suite.portfolioRepository.EXPECT().GetPortfolioById(mock.Anything, portfolio.Id).Return(nil, responseErr).Once()
 	bodyJson, _ := json.Marshal(requestBody)
 	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(bodyJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -171,6 +177,7 @@ func (suite *UpdatePortfolioSuite) TestUpdatePortfolioConflict() {
 	portfolioCopy := *portfolio
 	updatedPortfolio := &portfolioCopy
 	updatedPortfolio.Name = "updated-portfolio"
+	updatedPortfolio.Version = 0
 
 	requestBody := requests.UpdatePortfolioRequest{
 		Id:         updatedPortfolio.Id,
@@ -180,8 +187,8 @@ func (suite *UpdatePortfolioSuite) TestUpdatePortfolioConflict() {
 		IsInternal: updatedPortfolio.IsInternal,
 	}
 
-	suite.portfolioRepository.EXPECT().GetPortfolioById(portfolio.Id).Return(portfolio, nil).Once()
-	suite.portfolioRepository.EXPECT().UpdatePortfolio(updatedPortfolio).Return(nil, repository.ErrPortfolioAlreadyExists).Once()
+	suite.portfolioRepository.EXPECT().GetPortfolioById(mock.Anything, portfolio.Id).Return(portfolio, nil).Once()
+	suite.portfolioRepository.EXPECT().UpdatePortfolio(mock.Anything, updatedPortfolio).Return(nil, repository.ErrPortfolioAlreadyExists).Once()
 
 	bodyJson, _ := json.Marshal(requestBody)
 	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(bodyJson))
@@ -200,3 +207,70 @@ func (suite *UpdatePortfolioSuite) TestUpdatePortfolioConflict() {
 	r.True(ok)
 	r.Equal(httpError.Code, http.StatusConflict)
 }
+
+func (suite *UpdatePortfolioSuite) TestUpdatePortfolioIfMatch() {
+	r := suite.Require()
+	handler := NewUpdatePortfolioHandler(suite.portfolioService)
+	portfolio := factories.GetPortfolio()
+	portfolioIdStr := fmt.Sprintf("%d", portfolio.Id)
+
+	requestBody := requests.UpdatePortfolioRequest{
+		Id:         portfolio.Id,
+		Name:       portfolio.Name,
+		IsActive:   !portfolio.IsActive,
+		IsFinance:  portfolio.IsFinance,
+		IsInternal: portfolio.IsInternal,
+	}
+
+	tt := []struct {
+		ifMatch string
+		err     error
+		code    int
+	}{
+		{ifMatch: `"1"`, code: http.StatusOK},
+		{ifMatch: `"1"`, err: repository.ErrPortfolioVersionMismatch, code: http.StatusPreconditionFailed},
+		{ifMatch: `W/"1"`, code: http.StatusBadRequest},
+		{ifMatch: `"abc"`, code: http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		if tc.code != http.StatusBadRequest {
+			stored := *portfolio
+			expected := *portfolio
+			expected.IsActive = !portfolio.IsActive
+			saved := expected
+			saved.Version = 2
+
+			suite.portfolioRepository.EXPECT().GetPortfolioById(mock.Anything, portfolio.Id).Return(&stored, nil).Once()
+
+			if tc.err != nil {
+				suite.portfolioRepository.EXPECT().UpdatePortfolio(mock.Anything, &expected).Return(nil, tc.err).Once()
+			} else {
+				suite.portfolioRepository.EXPECT().UpdatePortfolio(mock.Anything, &expected).Return(&saved, nil).Once()
+			}
+		}
+
+		bodyJson, _ := json.Marshal(requestBody)
+		req := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(bodyJson))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, tc.ifMatch)
+		rec := httptest.NewRecorder()
+		ctx := suite.e.NewContext(req, rec)
+		ctx.SetPath("/portfolios/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues(portfolioIdStr)
+
+		err := handler(ctx)
+
+		if tc.code == http.StatusOK {
+			r.NoError(err)
+			r.Equal(`"2"`, rec.Header().Get(HeaderETag))
+			continue
+		}
+
+		r.Error(err)
+		httpError, ok := err.(*echo.HTTPError)
+		r.True(ok)
+		r.Equal(tc.code, httpError.Code, tc.ifMatch)
+	}
+}
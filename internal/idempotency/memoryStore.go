@@ -0,0 +1,102 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	fingerprint string
+	// response is nil while the first request is in progress.
+	response  *Response
+	expiresAt time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	ttl     time.Duration
+	sweptAt time.Time
+	now     func() time.Time
+}
+
+func (s *memoryStore) Begin(ctx context.Context, key string, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	s.sweep(now)
+
+	entry, ok := s.entries[key]
+
+	if !ok || !now.Before(entry.expiresAt) {
+		s.entries[key] = &memoryEntry{fingerprint: fingerprint, expiresAt: now.Add(s.ttl)}
+		return nil, nil
+	}
+
+	if entry.fingerprint != fingerprint {
+		return nil, ErrFingerprintMismatch
+	}
+
+	if entry.response == nil {
+		return nil, ErrRequestInProgress
+	}
+
+	response := *entry.response
+
+	return &response, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, key string, response *Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		saved := *response
+		entry.response = &saved
+		entry.expiresAt = s.now().Add(s.ttl)
+	}
+
+	return nil
+}
+
+func (s *memoryStore) Abort(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok && entry.response == nil {
+		delete(s.entries, key)
+	}
+
+	return nil
+}
+
+// sweep drops expired entries at most once per TTL, so memory is bounded by
+// the keys used within two TTLs. Must be called with the lock held.
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < s.ttl {
+		return
+	}
+
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.sweptAt = now
+}
+
+// NewMemoryStore keeps keys in process memory for ttl after the response is saved.
+func NewMemoryStore(ttl time.Duration) *memoryStore {
+	return &memoryStore{
+		entries: make(map[string]*memoryEntry),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
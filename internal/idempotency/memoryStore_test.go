@@ -0,0 +1,168 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryStoreSuite struct {
+	suite.Suite
+	store *memoryStore
+	now   time.Time
+	ctx   context.Context
+}
+
+func TestMemoryStoreSuite(t *testing.T) {
+	suite.Run(t, new(MemoryStoreSuite))
+}
+
+func (suite *MemoryStoreSuite) SetupTest() {
+	suite.now = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.store = NewMemoryStore(time.Hour)
+	suite.store.now = func() time.Time { return suite.now }
+	suite.ctx = context.Background()
+}
+
+func (suite *MemoryStoreSuite) TestReplaysCompletedResponse() {
+	r := suite.Require()
+
+	response, err := suite.store.Begin(suite.ctx, "key-1", "body-1")
+	r.NoError(err)
+	r.Nil(response)
+
+	r.NoError(suite.store.Complete(suite.ctx, "key-1", &Response{StatusCode: 201, Body: []byte(`{"id":1}`)}))
+
+	for i := 0; i < 2; i++ {
+		response, err = suite.store.Begin(suite.ctx, "key-1", "body-1")
+		r.NoError(err)
+		r.Equal(&Response{StatusCode: 201, Body: []byte(`{"id":1}`)}, response)
+	}
+
+	response, err = suite.store.Begin(suite.ctx, "key-2", "body-1")
+	r.NoError(err)
+	r.Nil(response, "keys are independent")
+}
+
+func (suite *MemoryStoreSuite) TestFingerprintMismatch() {
+	r := suite.Require()
+
+	_, err := suite.store.Begin(suite.ctx, "key-1", "body-1")
+	r.NoError(err)
+
+	_, err = suite.store.Begin(suite.ctx, "key-1", "body-2")
+	r.ErrorIs(err, ErrFingerprintMismatch)
+
+	r.NoError(suite.store.Complete(suite.ctx, "key-1", &Response{StatusCode: 201}))
+
+	_, err = suite.store.Begin(suite.ctx, "key-1", "body-2")
+	r.ErrorIs(err, ErrFingerprintMismatch)
+}
+
+func (suite *MemoryStoreSuite) TestRequestInProgress() {
+	r := suite.Require()
+
+	_, err := suite.store.Begin(suite.ctx, "key-1", "body-1")
+	r.NoError(err)
+
+	_, err = suite.store.Begin(suite.ctx, "key-1", "body-1")
+	r.ErrorIs(err, ErrRequestInProgress)
+}
+
+func (suite *MemoryStoreSuite) TestAbortReleasesKey() {
+	r := suite.Require()
+
+	_, err := suite.store.Begin(suite.ctx, "key-1", "body-1")
+	r.NoError(err)
+	r.NoError(suite.store.Abort(suite.ctx, "key-1"))
+
+	response, err := suite.store.Begin(suite.ctx, "key-1", "body-2")
+	r.NoError(err)
+	r.Nil(response)
+
+	r.NoError(suite.store.Complete(suite.ctx, "key-1", &Response{StatusCode: 201}))
+	r.NoError(suite.store.Abort(suite.ctx, "key-1"))
+
+	response, err = suite.store.Begin(suite.ctx, "key-1", "body-2")
+	r.NoError(err)
+	r.NotNil(response, "completed keys are not aborted")
+}
+
+func (suite *MemoryStoreSuite) TestKeysExpire() {
+	r := suite.Require()
+
+	_, err := suite.store.Begin(suite.ctx, "key-1", "body-1")
+	r.NoError(err)
+
+	suite.now = suite.now.Add(30 * time.Minute)
+	r.NoError(suite.store.Complete(suite.ctx, "key-1", &Response{StatusCode: 201}))
+
+	suite.now = suite.now.Add(59 * time.Minute)
+	response, err := suite.store.Begin(suite.ctx, "key-1", "body-1")
+	r.NoError(err)
+	r.NotNil(response, "the TTL starts when the response is saved")
+
+	suite.now = suite.now.Add(time.Minute)
+	response, err = suite.store.Begin(suite.ctx, "key-1", "body-2")
+	r.NoError(err)
+	r.Nil(response)
+}
+
+func (suite *MemoryStoreSuite) TestExpiredKeysAreSwept() {
+	r := suite.Require()
+
+	for _, key := range []string{"key-1", "key-2", "key-3"} {
+		_, err := suite.store.Begin(suite.ctx, key, "body")
+		r.NoError(err)
+		r.NoError(suite.store.Complete(suite.ctx, key, &Response{StatusCode: 201}))
+	}
+
+	suite.now = suite.now.Add(2 * time.Hour)
+
+	_, err := suite.store.Begin(suite.ctx, "key-4", "body")
+	r.NoError(err)
+	r.Len(suite.store.entries, 1)
+}
+
+func (suite *MemoryStoreSuite) TestConcurrentBegin() {
+	r := suite.Require()
+	workers := 8
+	claimed := make(chan bool, workers)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			response, err := suite.store.Begin(suite.ctx, "key-1", "body-1")
+			claimed <- err == nil && response == nil
+		}()
+	}
+
+	wg.Wait()
+	close(claimed)
+
+	count := 0
+
+	for ok := range claimed {
+		if ok {
+			count++
+		}
+	}
+
+	r.Equal(1, count)
+}
+
+func (suite *MemoryStoreSuite) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	_, err := suite.store.Begin(ctx, "key-1", "body-1")
+
+	suite.Require().ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,32 @@
+// Package idempotency remembers responses to requests with an Idempotency-Key,
+// so that a retried request gets the original response instead of being executed twice.
+package idempotency
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrRequestInProgress   = errors.New("request with this idempotency key is in progress")
+	ErrFingerprintMismatch = errors.New("idempotency key was used with a different request")
+)
+
+// Response is what the first request with a key responded with.
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+type Store interface {
+	// Begin claims key for a request with the given fingerprint. If the key was used before,
+	// it returns the saved response, or ErrRequestInProgress while the first request runs.
+	// A different fingerprint for a known key is ErrFingerprintMismatch.
+	// A nil response and error means the key is claimed and the request should run.
+	Begin(ctx context.Context, key string, fingerprint string) (*Response, error)
+	// Complete saves the response of a request that claimed key with Begin.
+	Complete(ctx context.Context, key string, response *Response) error
+	// Abort releases a key claimed with Begin without saving a response,
+	// so the request can be retried.
+	Abort(ctx context.Context, key string) error
+}
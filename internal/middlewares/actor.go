@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	echo "github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderActor = "X-Actor"
+
+	// AnonymousActor is recorded for changes made by requests without an actor.
+	AnonymousActor = "anonymous"
+)
+
+// Actor records the subject of the authenticated principal, or the X-Actor header of
+// unauthenticated requests, in the request context, so the repository writes it to the
+// audit history of every change. It must run after Authenticate.
+func Actor(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		actor := strings.TrimSpace(c.Request().Header.Get(HeaderActor))
+
+		if principal, ok := auth.PrincipalFromContext(c.Request().Context()); ok {
+			actor = principal.Subject
+		}
+
+		if actor == "" {
+			actor = AnonymousActor
+		}
+
+		req := c.Request()
+		c.SetRequest(req.WithContext(repository.ContextWithActor(req.Context(), actor)))
+
+		return next(c)
+	}
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActor(t *testing.T) {
+	e := echo.New()
+
+	tt := map[string]string{
+		"alice":   "alice",
+		" bob  ":  "bob",
+		"":        AnonymousActor,
+		"  \t   ": AnonymousActor,
+	}
+
+	for header, expected := range tt {
+		req := httptest.NewRequest(http.MethodPost, "/any-route", nil)
+		req.Header.Set(HeaderActor, header)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		var actor string
+
+		err := Actor(func(c echo.Context) error {
+			actor = repository.ActorFromContext(c.Request().Context())
+			return nil
+		})(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actor)
+	}
+}
+
+func TestActorOfPrincipal(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/any-route", nil)
+	req.Header.Set(HeaderActor, "mallory")
+	req = req.WithContext(auth.ContextWithPrincipal(req.Context(), &auth.Principal{Subject: "alice"}))
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	var actor string
+
+	err := Actor(func(c echo.Context) error {
+		actor = repository.ActorFromContext(c.Request().Context())
+		return nil
+	})(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", actor)
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	echo "github.com/labstack/echo/v4"
+)
+
+const bearerScheme = "Bearer"
+
+// Authenticate rejects requests without a valid bearer token with 401 and records the
+// principal of the token in the request context. Routes in publicPaths, such as
+// "/swagger/*", are let through without a token.
+func Authenticate(verifier auth.Verifier, publicPaths ...string) echo.MiddlewareFunc {
+	public := make(map[string]bool, len(publicPaths))
+
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if public[c.Path()] {
+				return next(c)
+			}
+
+			token, ok := bearerToken(c.Request().Header.Get(echo.HeaderAuthorization))
+
+			if !ok {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, bearerScheme)
+				return echo.NewHTTPError(http.StatusUnauthorized, "Missing bearer token")
+			}
+
+			principal, err := verifier.Verify(token)
+
+			if err != nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, bearerScheme+` error="invalid_token"`)
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid bearer token").SetInternal(err)
+			}
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(auth.ContextWithPrincipal(req.Context(), principal)))
+
+			return next(c)
+		}
+	}
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+
+	if !ok || !strings.EqualFold(scheme, bearerScheme) {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenVerifier map[string]string
+
+func (v tokenVerifier) Verify(token string) (*auth.Principal, error) {
+	if subject, ok := v[token]; ok {
+		return &auth.Principal{Subject: subject}, nil
+	}
+
+	return nil, auth.ErrInvalidToken
+}
+
+func TestAuthenticate(t *testing.T) {
+	e := echo.New()
+	middleware := Authenticate(tokenVerifier{"valid-token": "alice"}, "/swagger/*", "/health")
+
+	tt := []struct {
+		path          string
+		authorization string
+		code          int
+		subject       string
+	}{
+		{"/portfolios", "Bearer valid-token", http.StatusOK, "alice"},
+		{"/portfolios", "bearer  valid-token ", http.StatusOK, "alice"},
+		{"/portfolios", "", http.StatusUnauthorized, ""},
+		{"/portfolios", "Bearer", http.StatusUnauthorized, ""},
+		{"/portfolios", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+		{"/portfolios", "Bearer invalid-token", http.StatusUnauthorized, ""},
+		{"/swagger/*", "", http.StatusOK, ""},
+		{"/health", "Bearer invalid-token", http.StatusOK, ""},
+	}
+
+	for _, tc := range tt {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, tc.authorization)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetPath(tc.path)
+
+		subject := ""
+
+		err := middleware(func(c echo.Context) error {
+			if principal, ok := auth.PrincipalFromContext(c.Request().Context()); ok {
+				subject = principal.Subject
+			}
+
+			return c.NoContent(http.StatusOK)
+		})(ctx)
+
+		if tc.code == http.StatusUnauthorized {
+			var he *echo.HTTPError
+			assert.True(t, errors.As(err, &he), tc.authorization)
+			assert.Equal(t, http.StatusUnauthorized, he.Code)
+			assert.Contains(t, rec.Header().Get(echo.HeaderWWWAuthenticate), "Bearer")
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.code, rec.Code)
+		assert.Equal(t, tc.subject, subject)
+	}
+}
+
+func TestAuthenticateErrorResponse(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+	e.Use(Authenticate(tokenVerifier{}))
+	e.GET("/portfolios", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/portfolios", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer expired-token")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.JSONEq(t, `{"message":"Invalid bearer token"}`, rec.Body.String())
+	assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get(echo.HeaderWWWAuthenticate))
+}
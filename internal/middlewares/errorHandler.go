@@ -1,12 +1,20 @@
 package middlewares
 
 import (
+	"fmt"
 	"net/http"
 
 	echo "github.com/labstack/echo/v4"
 )
 
+// ErrorHandler responds with the status and message of the error. Errors after the response was
+// committed, such as a failed stream, are only logged, a message would end up in the middle of the body.
 func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		c.Logger().Errorf("%s %s failed after the response was sent: %v", c.Request().Method, c.Request().URL.Path, err)
+		return
+	}
+
 	code := http.StatusInternalServerError
 	message := "Internal Server Error"
 
@@ -14,7 +22,7 @@ func ErrorHandler(err error, c echo.Context) {
 		code = he.Code
 
 		if code != http.StatusInternalServerError {
-			message = he.Message.(string)
+			message = fmt.Sprint(he.Message)
 		}
 	}
 
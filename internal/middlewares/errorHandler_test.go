@@ -56,3 +56,30 @@ func TestErrorHandlerNonHttpError(t *testing.T) {
 		assert.Contains(t, rec.Body.String(), "Internal Server Error")
 	}
 }
+
+func TestErrorHandlerNonStringMessage(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/any-route", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	ErrorHandler(echo.NewHTTPError(http.StatusBadRequest, errors.New("Field validation failed")), ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Field validation failed")
+}
+
+func TestErrorHandlerCommittedResponse(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/any-route", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	ctx.Response().WriteHeader(http.StatusOK)
+	ctx.Response().Write([]byte("{\"id\":1}\n"))
+
+	ErrorHandler(errors.New("stream failed"), ctx)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "{\"id\":1}\n", rec.Body.String())
+}
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	echo "github.com/labstack/echo/v4"
+)
+
+const HeaderTenant = "X-Tenant-Id"
+
+// Tenant puts the tenant of the request in its context, so the service only reaches the portfolios
+// of that tenant. The tenant of an authenticated principal is the tenant claim of its token, and an
+// X-Tenant-Id header must name the same tenant. Unauthenticated requests are in the tenant of the
+// header. Without either the request is in repository.DefaultTenant. It must run after Authenticate.
+func Tenant(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tenant := strings.TrimSpace(c.Request().Header.Get(HeaderTenant))
+
+		if principal, ok := auth.PrincipalFromContext(c.Request().Context()); ok {
+			claimed := principal.Tenant
+
+			if claimed == "" {
+				claimed = repository.DefaultTenant
+			}
+
+			if tenant != "" && tenant != claimed {
+				return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Token is not valid for tenant %q", tenant))
+			}
+
+			tenant = claimed
+		}
+
+		if tenant == "" {
+			tenant = repository.DefaultTenant
+		}
+
+		if !repository.ValidTenant(tenant) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant")
+		}
+
+		req := c.Request()
+		c.SetRequest(req.WithContext(repository.ContextWithTenant(req.Context(), tenant)))
+
+		return next(c)
+	}
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// runTenant runs the Tenant middleware and returns the tenant it put in the context, or the error it returned.
+func runTenant(header string, principal *auth.Principal) (string, error) {
+	req := httptest.NewRequest(http.MethodGet, "/any-route", nil)
+	req.Header.Set(HeaderTenant, header)
+
+	if principal != nil {
+		req = req.WithContext(auth.ContextWithPrincipal(req.Context(), principal))
+	}
+
+	ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+	var tenant string
+
+	err := Tenant(func(c echo.Context) error {
+		tenant, _ = repository.TenantFromContext(c.Request().Context())
+		return nil
+	})(ctx)
+
+	return tenant, err
+}
+
+func TestTenant(t *testing.T) {
+	tt := map[string]string{
+		"tenant-a":   "tenant-a",
+		" tenant-b ": "tenant-b",
+		"":           repository.DefaultTenant,
+	}
+
+	for header, expected := range tt {
+		tenant, err := runTenant(header, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, tenant)
+	}
+}
+
+func TestTenantInvalid(t *testing.T) {
+	for _, header := range []string{"Tenant-A", "../tenant", "tenant/a"} {
+		_, err := runTenant(header, nil)
+
+		assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code, header)
+	}
+
+	_, err := runTenant("", &auth.Principal{Subject: "alice", Tenant: "Tenant A"})
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+}
+
+func TestTenantOfPrincipal(t *testing.T) {
+	tenant, err := runTenant("", &auth.Principal{Subject: "alice", Tenant: "tenant-a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-a", tenant)
+
+	tenant, err = runTenant("tenant-a", &auth.Principal{Subject: "alice", Tenant: "tenant-a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-a", tenant)
+
+	tenant, err = runTenant("", &auth.Principal{Subject: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, repository.DefaultTenant, tenant)
+}
+
+func TestTenantHeaderMustMatchPrincipal(t *testing.T) {
+	for _, principal := range []*auth.Principal{{Subject: "alice", Tenant: "tenant-a"}, {Subject: "alice"}} {
+		_, err := runTenant("tenant-b", principal)
+
+		assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	}
+}
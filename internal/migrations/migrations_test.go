@@ -0,0 +1,171 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/database"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationsSuite struct {
+	suite.Suite
+	path string
+	db   *sql.DB
+}
+
+func TestMigrationsSuite(t *testing.T) {
+	suite.Run(t, new(MigrationsSuite))
+}
+
+func (suite *MigrationsSuite) SetupTest() {
+	r := suite.Require()
+	suite.path = filepath.Join(suite.T().TempDir(), "migrations.db")
+
+	db, err := database.OpenSQLite(suite.path)
+	r.NoError(err)
+
+	suite.db = db
+}
+
+func (suite *MigrationsSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+func (suite *MigrationsSuite) newMigrator(db *sql.DB) *Migrator {
+	return &Migrator{
+		db:      db,
+		dialect: dialects[database.DriverSQLite],
+		migrations: []Migration{
+			{Version: 1, Name: "create_a", Up: `CREATE TABLE a (id INTEGER)`, Down: `DROP TABLE a`},
+			{Version: 2, Name: "create_b", Up: `CREATE TABLE b (id INTEGER)`, Down: `DROP TABLE b`},
+			{Version: 3, Name: "create_c", Up: `CREATE TABLE c (id INTEGER)`, Down: `DROP TABLE c`},
+		},
+	}
+}
+
+func (suite *MigrationsSuite) appliedVersions(migrator *Migrator) []int {
+	statuses, err := migrator.Status(context.Background())
+	suite.Require().NoError(err)
+
+	versions := make([]int, 0)
+
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			versions = append(versions, status.Version)
+		}
+	}
+
+	return versions
+}
+
+func (suite *MigrationsSuite) tableExists(name string) bool {
+	var count int
+	err := suite.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	suite.Require().NoError(err)
+
+	return count == 1
+}
+
+func (suite *MigrationsSuite) TestUpAndDown() {
+	r := suite.Require()
+	ctx := context.Background()
+	migrator := suite.newMigrator(suite.db)
+
+	r.NoError(migrator.Up(ctx))
+	r.Equal([]int{1, 2, 3}, suite.appliedVersions(migrator))
+	r.True(suite.tableExists("c"))
+
+	r.NoError(migrator.Up(ctx))
+	r.Equal([]int{1, 2, 3}, suite.appliedVersions(migrator))
+
+	r.NoError(migrator.Down(ctx))
+	r.Equal([]int{1, 2}, suite.appliedVersions(migrator))
+	r.False(suite.tableExists("c"))
+	r.True(suite.tableExists("b"))
+}
+
+func (suite *MigrationsSuite) TestTo() {
+	r := suite.Require()
+	ctx := context.Background()
+	migrator := suite.newMigrator(suite.db)
+
+	r.NoError(migrator.To(ctx, 2))
+	r.Equal([]int{1, 2}, suite.appliedVersions(migrator))
+
+	r.NoError(migrator.To(ctx, 3))
+	r.Equal([]int{1, 2, 3}, suite.appliedVersions(migrator))
+
+	r.NoError(migrator.To(ctx, 1))
+	r.Equal([]int{1}, suite.appliedVersions(migrator))
+	r.False(suite.tableExists("b"))
+
+	r.NoError(migrator.To(ctx, 0))
+	r.Empty(suite.appliedVersions(migrator))
+	r.False(suite.tableExists("a"))
+
+	r.ErrorIs(migrator.To(ctx, 4), ErrUnknownVersion)
+}
+
+func (suite *MigrationsSuite) TestFailedMigrationIsRolledBack() {
+	r := suite.Require()
+	migrator := suite.newMigrator(suite.db)
+	migrator.migrations[2].Up = `CREATE TABLE c (id INTEGER); SELECT * FROM missing_table`
+
+	r.Error(migrator.Up(context.Background()))
+	r.Empty(suite.appliedVersions(migrator))
+	r.False(suite.tableExists("a"))
+}
+
+func (suite *MigrationsSuite) TestConcurrentUp() {
+	r := suite.Require()
+	instances := 4
+	errs := make(chan error, instances)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < instances; i++ {
+		db, err := database.OpenSQLite(suite.path)
+		r.NoError(err)
+		defer db.Close()
+
+		wg.Add(1)
+
+		go func(migrator *Migrator) {
+			defer wg.Done()
+			errs <- migrator.Up(context.Background())
+		}(suite.newMigrator(db))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		r.NoError(err)
+	}
+
+	r.Equal([]int{1, 2, 3}, suite.appliedVersions(suite.newMigrator(suite.db)))
+}
+
+func (suite *MigrationsSuite) TestEmbeddedMigrations() {
+	r := suite.Require()
+
+	for driver := range dialects {
+		migrations, err := load(driver)
+		r.NoError(err)
+		r.NotEmpty(migrations)
+
+		for i, migration := range migrations {
+			r.Equal(i+1, migration.Version, "%s migrations must be numbered without gaps", driver)
+		}
+	}
+
+	migrator, err := NewMigrator(suite.db, database.DriverSQLite)
+	r.NoError(err)
+	r.NoError(migrator.Up(context.Background()))
+	r.True(suite.tableExists("portfolios"))
+	r.NoError(migrator.To(context.Background(), 0))
+	r.False(suite.tableExists("portfolios"))
+}
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS portfolios (
+	id          SERIAL PRIMARY KEY,
+	name        VARCHAR(255) NOT NULL,
+	is_internal BOOLEAN NOT NULL DEFAULT FALSE,
+	is_finance  BOOLEAN NOT NULL DEFAULT FALSE,
+	is_active   BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS portfolios_name_idx ON portfolios (name);
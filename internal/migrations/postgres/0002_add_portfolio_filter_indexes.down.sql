@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS portfolios_name_pattern_idx;
+DROP INDEX IF EXISTS portfolios_updated_at_idx;
+DROP INDEX IF EXISTS portfolios_created_at_idx;
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS portfolios_created_at_idx ON portfolios (created_at);
+CREATE INDEX IF NOT EXISTS portfolios_updated_at_idx ON portfolios (updated_at);
+
+-- Lets name prefix filters (name LIKE 'prefix%') use an index under any collation.
+CREATE INDEX IF NOT EXISTS portfolios_name_pattern_idx ON portfolios (name text_pattern_ops);
@@ -0,0 +1 @@
+ALTER TABLE portfolios ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,6 @@
+ALTER TABLE portfolios ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Names only have to be unique among portfolios that are not deleted.
+DROP INDEX IF EXISTS portfolios_name_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS portfolios_name_idx ON portfolios (name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS portfolios_deleted_at_idx ON portfolios (deleted_at) WHERE deleted_at IS NOT NULL;
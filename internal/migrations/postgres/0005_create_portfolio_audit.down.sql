@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS portfolio_audit;
+DROP FUNCTION IF EXISTS portfolio_audit_immutable();
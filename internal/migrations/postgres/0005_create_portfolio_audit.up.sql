@@ -0,0 +1,23 @@
+-- Entries outlive purged portfolios, so portfolio_id has no foreign key.
+CREATE TABLE IF NOT EXISTS portfolio_audit (
+	id           BIGSERIAL PRIMARY KEY,
+	portfolio_id INTEGER NOT NULL,
+	actor        VARCHAR(255) NOT NULL,
+	operation    VARCHAR(16) NOT NULL,
+	at           TIMESTAMPTZ NOT NULL,
+	before_state JSONB,
+	after_state  JSONB,
+	changes      JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS portfolio_audit_portfolio_id_idx ON portfolio_audit (portfolio_id, id);
+
+CREATE OR REPLACE FUNCTION portfolio_audit_immutable() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'portfolio audit entries are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER portfolio_audit_immutable
+	BEFORE UPDATE OR DELETE ON portfolio_audit
+	FOR EACH ROW EXECUTE FUNCTION portfolio_audit_immutable();
@@ -0,0 +1,23 @@
+-- Every state a portfolio had, current from valid_from until valid_to. The current
+-- state has no valid_to, a purged portfolio has no current state. Columns match
+-- portfolios, so point-in-time reads run the same queries against this table.
+CREATE TABLE IF NOT EXISTS portfolio_versions (
+	id          INTEGER NOT NULL,
+	name        VARCHAR(255) NOT NULL,
+	is_internal BOOLEAN NOT NULL,
+	is_finance  BOOLEAN NOT NULL,
+	is_active   BOOLEAN NOT NULL,
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL,
+	version     INTEGER NOT NULL,
+	deleted_at  TIMESTAMPTZ,
+	valid_from  TIMESTAMPTZ NOT NULL,
+	valid_to    TIMESTAMPTZ,
+	PRIMARY KEY (id, version)
+);
+
+CREATE INDEX IF NOT EXISTS portfolio_versions_valid_idx ON portfolio_versions (valid_from, valid_to);
+
+-- Earlier states of existing portfolios are unknown, their current state is known since their last update.
+INSERT INTO portfolio_versions (id, name, is_internal, is_finance, is_active, created_at, updated_at, version, deleted_at, valid_from)
+SELECT id, name, is_internal, is_finance, is_active, created_at, updated_at, version, deleted_at, updated_at FROM portfolios;
@@ -0,0 +1,8 @@
+-- Fails if two tenants have live portfolios with the same name.
+DROP INDEX IF EXISTS portfolios_tenant_idx;
+DROP INDEX IF EXISTS portfolios_name_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS portfolios_name_idx ON portfolios (name) WHERE deleted_at IS NULL;
+
+ALTER TABLE portfolio_versions DROP COLUMN tenant_id;
+ALTER TABLE portfolio_audit DROP COLUMN tenant_id;
+ALTER TABLE portfolios DROP COLUMN tenant_id;
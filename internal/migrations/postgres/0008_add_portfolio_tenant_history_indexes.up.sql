@@ -0,0 +1,3 @@
+-- Audit and version reads filter by tenant_id as well as the portfolio.
+CREATE INDEX IF NOT EXISTS portfolio_audit_tenant_idx ON portfolio_audit (tenant_id, portfolio_id, id);
+CREATE INDEX IF NOT EXISTS portfolio_versions_tenant_idx ON portfolio_versions (tenant_id, id, version);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS portfolios (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	name        TEXT NOT NULL,
+	is_internal BOOLEAN NOT NULL DEFAULT 0,
+	is_finance  BOOLEAN NOT NULL DEFAULT 0,
+	is_active   BOOLEAN NOT NULL DEFAULT 0,
+	created_at  DATETIME NOT NULL,
+	updated_at  DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS portfolios_name_idx ON portfolios (name);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS portfolios_updated_at_idx;
+DROP INDEX IF EXISTS portfolios_created_at_idx;
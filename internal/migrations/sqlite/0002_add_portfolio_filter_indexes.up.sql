@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS portfolios_created_at_idx ON portfolios (created_at);
+CREATE INDEX IF NOT EXISTS portfolios_updated_at_idx ON portfolios (updated_at);
@@ -0,0 +1,7 @@
+-- Deleted portfolios may share names with other portfolios, so they are purged first.
+DELETE FROM portfolios WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS portfolios_deleted_at_idx;
+DROP INDEX IF EXISTS portfolios_name_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS portfolios_name_idx ON portfolios (name);
+ALTER TABLE portfolios DROP COLUMN deleted_at;
@@ -0,0 +1,23 @@
+-- Entries outlive purged portfolios, so portfolio_id has no foreign key.
+CREATE TABLE IF NOT EXISTS portfolio_audit (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	portfolio_id INTEGER NOT NULL,
+	actor        TEXT NOT NULL,
+	operation    TEXT NOT NULL,
+	at           DATETIME NOT NULL,
+	before_state TEXT,
+	after_state  TEXT,
+	changes      TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS portfolio_audit_portfolio_id_idx ON portfolio_audit (portfolio_id, id);
+
+CREATE TRIGGER IF NOT EXISTS portfolio_audit_no_update BEFORE UPDATE ON portfolio_audit
+BEGIN
+	SELECT RAISE(ABORT, 'portfolio audit entries are immutable');
+END;
+
+CREATE TRIGGER IF NOT EXISTS portfolio_audit_no_delete BEFORE DELETE ON portfolio_audit
+BEGIN
+	SELECT RAISE(ABORT, 'portfolio audit entries are immutable');
+END;
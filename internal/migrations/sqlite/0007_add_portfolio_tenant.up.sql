@@ -0,0 +1,9 @@
+-- Existing portfolios, their audit entries and versions belong to the default tenant.
+ALTER TABLE portfolios ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE portfolio_audit ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE portfolio_versions ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+-- Names only have to be unique within a tenant.
+DROP INDEX IF EXISTS portfolios_name_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS portfolios_name_idx ON portfolios (tenant_id, name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS portfolios_tenant_idx ON portfolios (tenant_id, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS portfolio_versions_tenant_idx;
+DROP INDEX IF EXISTS portfolio_audit_tenant_idx;
@@ -0,0 +1,410 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned for patch documents that do not follow RFC 6902.
+	ErrInvalidPatch = errors.New("invalid JSON patch")
+	// ErrTestFailed is returned when a test operation does not match the document.
+	ErrTestFailed = errors.New("test operation failed")
+	// ErrPathNotFound is returned when an operation refers to a missing location.
+	ErrPathNotFound = errors.New("path not found")
+)
+
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// Operation is one operation of a JSON patch (RFC 6902).
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+
+	value interface{}
+}
+
+// JSONPatch is a list of operations that are applied in order, all or nothing.
+type JSONPatch []Operation
+
+// Pointer splits a JSON pointer (RFC 6901) into unescaped reference tokens.
+// The empty pointer refers to the whole document and has no tokens.
+func Pointer(path string) ([]string, error) {
+	if path == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalidPatch, path)
+	}
+
+	tokens := strings.Split(path[1:], "/")
+
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// DecodeJSONPatch parses and checks a JSON patch document.
+func DecodeJSONPatch(data []byte) (JSONPatch, error) {
+	var operations JSONPatch
+
+	if err := unmarshal(data, &operations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	if operations == nil {
+		return nil, fmt.Errorf("%w: expected an array of operations", ErrInvalidPatch)
+	}
+
+	for i := range operations {
+		if err := operations[i].check(); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return operations, nil
+}
+
+func (o *Operation) check() error {
+	if _, err := Pointer(o.Path); err != nil {
+		return err
+	}
+
+	switch o.Op {
+	case OpAdd, OpReplace, OpTest:
+		if len(o.Value) == 0 {
+			return fmt.Errorf("%w: %s requires a value", ErrInvalidPatch, o.Op)
+		}
+
+		if err := unmarshal(o.Value, &o.value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+	case OpMove, OpCopy:
+		if _, err := Pointer(o.From); err != nil {
+			return err
+		}
+
+		if o.Op == OpMove && strings.HasPrefix(o.Path+"/", o.From+"/") && o.Path != o.From {
+			return fmt.Errorf("%w: cannot move %q into its own child", ErrInvalidPatch, o.From)
+		}
+	case OpRemove:
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, o.Op)
+	}
+
+	return nil
+}
+
+// Apply applies the operations to document and returns the result.
+// If any operation fails, the error is returned and document is left as it was.
+func (p JSONPatch) Apply(document []byte) ([]byte, error) {
+	var root interface{}
+
+	if err := unmarshal(document, &root); err != nil {
+		return nil, err
+	}
+
+	for i, operation := range p {
+		var err error
+
+		if root, err = operation.apply(root); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func (o *Operation) apply(root interface{}) (interface{}, error) {
+	path, _ := Pointer(o.Path)
+
+	switch o.Op {
+	case OpAdd:
+		return add(root, path, clone(o.value))
+	case OpRemove:
+		root, _, err := remove(root, path)
+		return root, err
+	case OpReplace:
+		if len(path) == 0 {
+			return clone(o.value), nil
+		}
+
+		if _, err := get(root, path); err != nil {
+			return nil, err
+		}
+
+		root, _, err := remove(root, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return add(root, path, clone(o.value))
+	case OpMove:
+		from, _ := Pointer(o.From)
+		root, value, err := remove(root, from)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return add(root, path, value)
+	case OpCopy:
+		from, _ := Pointer(o.From)
+		value, err := get(root, from)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return add(root, path, clone(value))
+	case OpTest:
+		value, err := get(root, path)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrTestFailed, err)
+		}
+
+		if !equal(value, o.value) {
+			return nil, ErrTestFailed
+		}
+
+		return root, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, o.Op)
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+
+			node = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			node = container[index]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+
+	return node, nil
+}
+
+// modify replaces the parent of the location at path with the result of fn.
+// Maps are changed in place, but arrays that grow or shrink are new slices,
+// so every container on the way down gets the new child assigned.
+func modify(node interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+
+	child, err := get(node, path[:1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	child, err = modify(child, path[1:], fn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := node.(type) {
+	case map[string]interface{}:
+		container[path[0]] = child
+	case []interface{}:
+		index, _ := arrayIndex(path[0], len(container)-1)
+		container[index] = child
+	}
+
+	return node, nil
+}
+
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return modify(root, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			index := len(container)
+
+			if token != "-" {
+				var err error
+
+				if index, err = arrayIndex(token, len(container)); err != nil {
+					return nil, err
+				}
+			}
+
+			result := make([]interface{}, 0, len(container)+1)
+			result = append(result, container[:index]...)
+			result = append(result, value)
+
+			return append(result, container[index:]...), nil
+		}
+
+		return nil, ErrPathNotFound
+	})
+}
+
+func remove(root interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	var removed interface{}
+
+	root, err := modify(root, path, func(parent interface{}, token string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+
+			removed = value
+			delete(container, token)
+
+			return container, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			removed = container[index]
+			result := make([]interface{}, 0, len(container)-1)
+			result = append(result, container[:index]...)
+
+			return append(result, container[index+1:]...), nil
+		}
+
+		return nil, ErrPathNotFound
+	})
+
+	return root, removed, err
+}
+
+// arrayIndex parses an array index token that must not exceed max.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || len(token) > 1 && token[0] == '0' || strings.Trim(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrPathNotFound, token)
+	}
+
+	index, err := strconv.Atoi(token)
+
+	if err != nil || index > max {
+		return 0, fmt.Errorf("%w: array index %q out of range", ErrPathNotFound, token)
+	}
+
+	return index, nil
+}
+
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+
+		for key, item := range v {
+			result[key] = clone(item)
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+
+		for i, item := range v {
+			result[i] = clone(item)
+		}
+
+		return result
+	}
+
+	return value
+}
+
+// equal compares JSON values as RFC 6902 defines for test operations:
+// numbers by value, objects regardless of member order.
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for key, value := range x {
+			other, ok := y[key]
+
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+
+		if !ok {
+			return false
+		}
+
+		m, okX := new(big.Rat).SetString(x.String())
+		n, okY := new(big.Rat).SetString(y.String())
+
+		return okX && okY && m.Cmp(n) == 0
+	}
+
+	return a == b
+}
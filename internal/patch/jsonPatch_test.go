@@ -0,0 +1,179 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type JSONPatchSuite struct {
+	suite.Suite
+}
+
+func TestJSONPatchSuite(t *testing.T) {
+	suite.Run(t, new(JSONPatchSuite))
+}
+
+func (suite *JSONPatchSuite) apply(document, patch string) (string, error) {
+	operations, err := DecodeJSONPatch([]byte(patch))
+
+	if err != nil {
+		return "", err
+	}
+
+	result, err := operations.Apply([]byte(document))
+
+	return string(result), err
+}
+
+// TestRFCExamples runs the successful examples from appendix A of RFC 6902.
+func (suite *JSONPatchSuite) TestRFCExamples() {
+	r := suite.Require()
+
+	tt := []struct {
+		document, patch, result string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"foo":{"foo":1,"bar":2}}`, `[{"op":"test","path":"/foo","value":{"bar":2,"foo":1}}]`, `{"foo":{"foo":1,"bar":2}}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"a/b":1}`, `[{"op":"copy","from":"/a~1b","path":"/c"}]`, `{"a/b":1,"c":1}`},
+		{`{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+
+	for _, tc := range tt {
+		result, err := suite.apply(tc.document, tc.patch)
+
+		r.NoError(err, tc.patch)
+		r.JSONEq(tc.result, result, tc.patch)
+	}
+}
+
+func (suite *JSONPatchSuite) TestNumbersAreComparedByValue() {
+	r := suite.Require()
+
+	_, err := suite.apply(`{"a":1}`, `[{"op":"test","path":"/a","value":1.0}]`)
+	r.NoError(err)
+
+	_, err = suite.apply(`{"a":1}`, `[{"op":"test","path":"/a","value":"1"}]`)
+	r.ErrorIs(err, ErrTestFailed)
+}
+
+func (suite *JSONPatchSuite) TestFailedTest() {
+	r := suite.Require()
+
+	tt := []string{
+		`[{"op":"test","path":"/baz","value":"bar"}]`,
+		`[{"op":"test","path":"/missing","value":"qux"}]`,
+		`[{"op":"test","path":"/foo","value":["a",2]}]`,
+		`[{"op":"replace","path":"/baz","value":"boo"},{"op":"test","path":"/baz","value":"qux"}]`,
+	}
+
+	for _, patch := range tt {
+		_, err := suite.apply(`{"baz":"qux","foo":["a",2,"c"]}`, patch)
+
+		r.ErrorIs(err, ErrTestFailed, patch)
+	}
+}
+
+func (suite *JSONPatchSuite) TestInvalidPatches() {
+	r := suite.Require()
+
+	tt := []string{
+		`{"op":"add","path":"/a","value":1}`,
+		`null`,
+		`[{"op":"unknown","path":"/a"}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"replace","path":"/a"}]`,
+		`[{"op":"test","path":"/a"}]`,
+		`[{"op":"move","path":"/a"}]`,
+		`[{"op":"copy","path":"/a","from":"b"}]`,
+		`[{"op":"move","from":"/a","path":"/a/b"}]`,
+	}
+
+	for _, patch := range tt {
+		_, err := DecodeJSONPatch([]byte(patch))
+
+		r.ErrorIs(err, ErrInvalidPatch, patch)
+	}
+}
+
+func (suite *JSONPatchSuite) TestMissingPaths() {
+	r := suite.Require()
+
+	tt := []string{
+		`[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"replace","path":"/missing","value":1}]`,
+		`[{"op":"add","path":"/foo/4","value":1}]`,
+		`[{"op":"add","path":"/foo/01","value":1}]`,
+		`[{"op":"remove","path":"/foo/-"}]`,
+		`[{"op":"remove","path":"/foo/3"}]`,
+		`[{"op":"move","from":"/missing","path":"/a"}]`,
+		`[{"op":"copy","from":"/foo/9","path":"/a"}]`,
+	}
+
+	for _, patch := range tt {
+		_, err := suite.apply(`{"foo":["a",2,"c"]}`, patch)
+
+		r.ErrorIs(err, ErrPathNotFound, patch)
+	}
+}
+
+func (suite *JSONPatchSuite) TestAllOrNothing() {
+	r := suite.Require()
+	document := []byte(`{"name":"a","list":[1,2]}`)
+	operations, err := DecodeJSONPatch([]byte(`[
+		{"op":"replace","path":"/name","value":"b"},
+		{"op":"add","path":"/list/0","value":0},
+		{"op":"remove","path":"/missing"}
+	]`))
+	r.NoError(err)
+
+	_, err = operations.Apply(document)
+
+	r.ErrorIs(err, ErrPathNotFound)
+	r.JSONEq(`{"name":"a","list":[1,2]}`, string(document))
+}
+
+func (suite *JSONPatchSuite) TestCopyIsDeep() {
+	r := suite.Require()
+
+	result, err := suite.apply(`{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`)
+
+	r.NoError(err)
+	r.JSONEq(`{"a":{"b":1},"c":{"b":2}}`, result)
+}
+
+func (suite *JSONPatchSuite) TestPointer() {
+	r := suite.Require()
+
+	tokens, err := Pointer("")
+	r.NoError(err)
+	r.Empty(tokens)
+
+	tokens, err = Pointer("/a~1b/~0c/~01/")
+	r.NoError(err)
+	r.Equal([]string{"a/b", "~c", "~1", ""}, tokens)
+
+	_, err = Pointer("a")
+	r.ErrorIs(err, ErrInvalidPatch)
+}
@@ -0,0 +1,69 @@
+// Package patch applies JSON patch documents to JSON values.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+var errTrailingData = errors.New("unexpected data after JSON value")
+
+// MergePatch applies a JSON merge patch (RFC 7396) to target and returns the result.
+// Members of patch objects replace the members of target objects recursively,
+// null values remove them and any patch that is not an object replaces target as a whole.
+func MergePatch(target, patch []byte) ([]byte, error) {
+	var targetValue, patchValue interface{}
+
+	if err := unmarshal(target, &targetValue); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValues(targetValue, patchValue))
+}
+
+func mergeValues(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+
+		targetObject[name] = mergeValues(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// unmarshal keeps numbers as json.Number so patches do not round large integers through float64.
+func unmarshal(data []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(value); err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return errTrailingData
+	}
+
+	return nil
+}
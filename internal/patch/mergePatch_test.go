@@ -0,0 +1,70 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MergePatchSuite struct {
+	suite.Suite
+}
+
+func TestMergePatchSuite(t *testing.T) {
+	suite.Run(t, new(MergePatchSuite))
+}
+
+// TestRFCExamples runs the examples from appendix A of RFC 7396.
+func (suite *MergePatchSuite) TestRFCExamples() {
+	r := suite.Require()
+
+	tt := []struct {
+		target, patch, result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tc := range tt {
+		result, err := MergePatch([]byte(tc.target), []byte(tc.patch))
+
+		r.NoError(err, tc.patch)
+		r.JSONEq(tc.result, string(result), "%s + %s", tc.target, tc.patch)
+	}
+}
+
+func (suite *MergePatchSuite) TestKeepsNumbers() {
+	r := suite.Require()
+
+	result, err := MergePatch([]byte(`{"id":9007199254740993}`), []byte(`{"a":1.50}`))
+
+	r.NoError(err)
+	r.JSONEq(`{"id":9007199254740993,"a":1.50}`, string(result))
+	r.Contains(string(result), "9007199254740993")
+}
+
+func (suite *MergePatchSuite) TestInvalidJson() {
+	r := suite.Require()
+
+	_, err := MergePatch([]byte(`{"a":1}`), []byte(`{"a":`))
+	r.Error(err)
+
+	_, err = MergePatch([]byte(`{`), []byte(`{"a":1}`))
+	r.Error(err)
+
+	_, err = MergePatch([]byte(`{"a":1}`), []byte(`{"a":2} {"a":3}`))
+	r.Error(err)
+}
@@ -3,10 +3,15 @@
 package mocks
 
 import (
+	context "context"
+
 	models "github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	repository "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
 	mock "github.com/stretchr/testify/mock"
 
 	requests "github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+
+	time "time"
 )
 
 // PortfolioRepository is an autogenerated mock type for the PortfolioRepository type
@@ -22,25 +27,81 @@ func (_m *PortfolioRepository) EXPECT() *PortfolioRepository_Expecter {
 	return &PortfolioRepository_Expecter{mock: &_m.Mock}
 }
 
-// CreatePortfolio provides a mock function with given fields: _a0
-func (_m *PortfolioRepository) CreatePortfolio(_a0 *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
-	ret := _m.Called(_a0)
+// ApplyPortfolioOperations provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PortfolioRepository) ApplyPortfolioOperations(_a0 context.Context, _a1 []repository.PortfolioOperation, _a2 bool) ([]repository.PortfolioOperationResult, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []repository.PortfolioOperationResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []repository.PortfolioOperation, bool) ([]repository.PortfolioOperationResult, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []repository.PortfolioOperation, bool) []repository.PortfolioOperationResult); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.PortfolioOperationResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []repository.PortfolioOperation, bool) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioRepository_ApplyPortfolioOperations_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApplyPortfolioOperations'
+type PortfolioRepository_ApplyPortfolioOperations_Call struct {
+	*mock.Call
+}
+
+// ApplyPortfolioOperations is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 []repository.PortfolioOperation
+//   - _a2 bool
+func (_e *PortfolioRepository_Expecter) ApplyPortfolioOperations(_a0 interface{}, _a1 interface{}, _a2 interface{}) *PortfolioRepository_ApplyPortfolioOperations_Call {
+	return &PortfolioRepository_ApplyPortfolioOperations_Call{Call: _e.mock.On("ApplyPortfolioOperations", _a0, _a1, _a2)}
+}
+
+func (_c *PortfolioRepository_ApplyPortfolioOperations_Call) Run(run func(_a0 context.Context, _a1 []repository.PortfolioOperation, _a2 bool)) *PortfolioRepository_ApplyPortfolioOperations_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]repository.PortfolioOperation), args[2].(bool))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_ApplyPortfolioOperations_Call) Return(_a0 []repository.PortfolioOperationResult, _a1 error) *PortfolioRepository_ApplyPortfolioOperations_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioRepository_ApplyPortfolioOperations_Call) RunAndReturn(run func(context.Context, []repository.PortfolioOperation, bool) ([]repository.PortfolioOperationResult, error)) *PortfolioRepository_ApplyPortfolioOperations_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreatePortfolio provides a mock function with given fields: _a0, _a1
+func (_m *PortfolioRepository) CreatePortfolio(_a0 context.Context, _a1 *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 *models.Portfolio
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*requests.CreatePortfolioRequest) (*models.Portfolio, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *requests.CreatePortfolioRequest) (*models.Portfolio, error)); ok {
+		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(*requests.CreatePortfolioRequest) *models.Portfolio); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *requests.CreatePortfolioRequest) *models.Portfolio); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Portfolio)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*requests.CreatePortfolioRequest) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, *requests.CreatePortfolioRequest) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -54,14 +115,15 @@ type PortfolioRepository_CreatePortfolio_Call struct {
 }
 
 // CreatePortfolio is a helper method to define mock.On call
-//   - _a0 *requests.CreatePortfolioRequest
-func (_e *PortfolioRepository_Expecter) CreatePortfolio(_a0 interface{}) *PortfolioRepository_CreatePortfolio_Call {
-	return &PortfolioRepository_CreatePortfolio_Call{Call: _e.mock.On("CreatePortfolio", _a0)}
+//   - _a0 context.Context
+//   - _a1 *requests.CreatePortfolioRequest
+func (_e *PortfolioRepository_Expecter) CreatePortfolio(_a0 interface{}, _a1 interface{}) *PortfolioRepository_CreatePortfolio_Call {
+	return &PortfolioRepository_CreatePortfolio_Call{Call: _e.mock.On("CreatePortfolio", _a0, _a1)}
 }
 
-func (_c *PortfolioRepository_CreatePortfolio_Call) Run(run func(_a0 *requests.CreatePortfolioRequest)) *PortfolioRepository_CreatePortfolio_Call {
+func (_c *PortfolioRepository_CreatePortfolio_Call) Run(run func(_a0 context.Context, _a1 *requests.CreatePortfolioRequest)) *PortfolioRepository_CreatePortfolio_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(*requests.CreatePortfolioRequest))
+		run(args[0].(context.Context), args[1].(*requests.CreatePortfolioRequest))
 	})
 	return _c
 }
@@ -71,18 +133,18 @@ func (_c *PortfolioRepository_CreatePortfolio_Call) Return(_a0 *models.Portfolio
 	return _c
 }
 
-func (_c *PortfolioRepository_CreatePortfolio_Call) RunAndReturn(run func(*requests.CreatePortfolioRequest) (*models.Portfolio, error)) *PortfolioRepository_CreatePortfolio_Call {
+func (_c *PortfolioRepository_CreatePortfolio_Call) RunAndReturn(run func(context.Context, *requests.CreatePortfolioRequest) (*models.Portfolio, error)) *PortfolioRepository_CreatePortfolio_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// DeletePortfolio provides a mock function with given fields: _a0
-func (_m *PortfolioRepository) DeletePortfolio(_a0 int) error {
-	ret := _m.Called(_a0)
+// DeletePortfolio provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PortfolioRepository) DeletePortfolio(_a0 context.Context, _a1 int, _a2 int) error {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -96,14 +158,16 @@ type PortfolioRepository_DeletePortfolio_Call struct {
 }
 
 // DeletePortfolio is a helper method to define mock.On call
-//   - _a0 int
-func (_e *PortfolioRepository_Expecter) DeletePortfolio(_a0 interface{}) *PortfolioRepository_DeletePortfolio_Call {
-	return &PortfolioRepository_DeletePortfolio_Call{Call: _e.mock.On("DeletePortfolio", _a0)}
+//   - _a0 context.Context
+//   - _a1 int
+//   - _a2 int
+func (_e *PortfolioRepository_Expecter) DeletePortfolio(_a0 interface{}, _a1 interface{}, _a2 interface{}) *PortfolioRepository_DeletePortfolio_Call {
+	return &PortfolioRepository_DeletePortfolio_Call{Call: _e.mock.On("DeletePortfolio", _a0, _a1, _a2)}
 }
 
-func (_c *PortfolioRepository_DeletePortfolio_Call) Run(run func(_a0 int)) *PortfolioRepository_DeletePortfolio_Call {
+func (_c *PortfolioRepository_DeletePortfolio_Call) Run(run func(_a0 context.Context, _a1 int, _a2 int)) *PortfolioRepository_DeletePortfolio_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(int))
+		run(args[0].(context.Context), args[1].(int), args[2].(int))
 	})
 	return _c
 }
@@ -113,30 +177,86 @@ func (_c *PortfolioRepository_DeletePortfolio_Call) Return(_a0 error) *Portfolio
 	return _c
 }
 
-func (_c *PortfolioRepository_DeletePortfolio_Call) RunAndReturn(run func(int) error) *PortfolioRepository_DeletePortfolio_Call {
+func (_c *PortfolioRepository_DeletePortfolio_Call) RunAndReturn(run func(context.Context, int, int) error) *PortfolioRepository_DeletePortfolio_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetPortfolioById provides a mock function with given fields: _a0
-func (_m *PortfolioRepository) GetPortfolioById(_a0 int) (*models.Portfolio, error) {
-	ret := _m.Called(_a0)
+// GetPortfolioAsOf provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PortfolioRepository) GetPortfolioAsOf(_a0 context.Context, _a1 int, _a2 time.Time) (*models.Portfolio, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 *models.Portfolio
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int) (*models.Portfolio, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) (*models.Portfolio, error)); ok {
+		return rf(_a0, _a1, _a2)
 	}
-	if rf, ok := ret.Get(0).(func(int) *models.Portfolio); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) *models.Portfolio); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Portfolio)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioRepository_GetPortfolioAsOf_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPortfolioAsOf'
+type PortfolioRepository_GetPortfolioAsOf_Call struct {
+	*mock.Call
+}
+
+// GetPortfolioAsOf is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 int
+//   - _a2 time.Time
+func (_e *PortfolioRepository_Expecter) GetPortfolioAsOf(_a0 interface{}, _a1 interface{}, _a2 interface{}) *PortfolioRepository_GetPortfolioAsOf_Call {
+	return &PortfolioRepository_GetPortfolioAsOf_Call{Call: _e.mock.On("GetPortfolioAsOf", _a0, _a1, _a2)}
+}
+
+func (_c *PortfolioRepository_GetPortfolioAsOf_Call) Run(run func(_a0 context.Context, _a1 int, _a2 time.Time)) *PortfolioRepository_GetPortfolioAsOf_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_GetPortfolioAsOf_Call) Return(_a0 *models.Portfolio, _a1 error) *PortfolioRepository_GetPortfolioAsOf_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioRepository_GetPortfolioAsOf_Call) RunAndReturn(run func(context.Context, int, time.Time) (*models.Portfolio, error)) *PortfolioRepository_GetPortfolioAsOf_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPortfolioById provides a mock function with given fields: _a0, _a1
+func (_m *PortfolioRepository) GetPortfolioById(_a0 context.Context, _a1 int) (*models.Portfolio, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *models.Portfolio
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Portfolio, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Portfolio); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Portfolio)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -150,14 +270,15 @@ type PortfolioRepository_GetPortfolioById_Call struct {
 }
 
 // GetPortfolioById is a helper method to define mock.On call
-//   - _a0 int
-func (_e *PortfolioRepository_Expecter) GetPortfolioById(_a0 interface{}) *PortfolioRepository_GetPortfolioById_Call {
-	return &PortfolioRepository_GetPortfolioById_Call{Call: _e.mock.On("GetPortfolioById", _a0)}
+//   - _a0 context.Context
+//   - _a1 int
+func (_e *PortfolioRepository_Expecter) GetPortfolioById(_a0 interface{}, _a1 interface{}) *PortfolioRepository_GetPortfolioById_Call {
+	return &PortfolioRepository_GetPortfolioById_Call{Call: _e.mock.On("GetPortfolioById", _a0, _a1)}
 }
 
-func (_c *PortfolioRepository_GetPortfolioById_Call) Run(run func(_a0 int)) *PortfolioRepository_GetPortfolioById_Call {
+func (_c *PortfolioRepository_GetPortfolioById_Call) Run(run func(_a0 context.Context, _a1 int)) *PortfolioRepository_GetPortfolioById_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(int))
+		run(args[0].(context.Context), args[1].(int))
 	})
 	return _c
 }
@@ -167,30 +288,143 @@ func (_c *PortfolioRepository_GetPortfolioById_Call) Return(_a0 *models.Portfoli
 	return _c
 }
 
-func (_c *PortfolioRepository_GetPortfolioById_Call) RunAndReturn(run func(int) (*models.Portfolio, error)) *PortfolioRepository_GetPortfolioById_Call {
+func (_c *PortfolioRepository_GetPortfolioById_Call) RunAndReturn(run func(context.Context, int) (*models.Portfolio, error)) *PortfolioRepository_GetPortfolioById_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPortfolioHistory provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PortfolioRepository) GetPortfolioHistory(_a0 context.Context, _a1 int, _a2 *repository.AuditQuery) (*repository.AuditPage, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *repository.AuditPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *repository.AuditQuery) (*repository.AuditPage, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *repository.AuditQuery) *repository.AuditPage); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.AuditPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *repository.AuditQuery) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioRepository_GetPortfolioHistory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPortfolioHistory'
+type PortfolioRepository_GetPortfolioHistory_Call struct {
+	*mock.Call
+}
+
+// GetPortfolioHistory is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 int
+//   - _a2 *repository.AuditQuery
+func (_e *PortfolioRepository_Expecter) GetPortfolioHistory(_a0 interface{}, _a1 interface{}, _a2 interface{}) *PortfolioRepository_GetPortfolioHistory_Call {
+	return &PortfolioRepository_GetPortfolioHistory_Call{Call: _e.mock.On("GetPortfolioHistory", _a0, _a1, _a2)}
+}
+
+func (_c *PortfolioRepository_GetPortfolioHistory_Call) Run(run func(_a0 context.Context, _a1 int, _a2 *repository.AuditQuery)) *PortfolioRepository_GetPortfolioHistory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(*repository.AuditQuery))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_GetPortfolioHistory_Call) Return(_a0 *repository.AuditPage, _a1 error) *PortfolioRepository_GetPortfolioHistory_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioRepository_GetPortfolioHistory_Call) RunAndReturn(run func(context.Context, int, *repository.AuditQuery) (*repository.AuditPage, error)) *PortfolioRepository_GetPortfolioHistory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPortfolioVersions provides a mock function with given fields: ctx, id, from, to
+func (_m *PortfolioRepository) GetPortfolioVersions(ctx context.Context, id int, from int, to int) ([]*models.Portfolio, error) {
+	ret := _m.Called(ctx, id, from, to)
+
+	var r0 []*models.Portfolio
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) ([]*models.Portfolio, error)); ok {
+		return rf(ctx, id, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) []*models.Portfolio); ok {
+		r0 = rf(ctx, id, from, to)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Portfolio)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, int) error); ok {
+		r1 = rf(ctx, id, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioRepository_GetPortfolioVersions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPortfolioVersions'
+type PortfolioRepository_GetPortfolioVersions_Call struct {
+	*mock.Call
+}
+
+// GetPortfolioVersions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id int
+//   - from int
+//   - to int
+func (_e *PortfolioRepository_Expecter) GetPortfolioVersions(ctx interface{}, id interface{}, from interface{}, to interface{}) *PortfolioRepository_GetPortfolioVersions_Call {
+	return &PortfolioRepository_GetPortfolioVersions_Call{Call: _e.mock.On("GetPortfolioVersions", ctx, id, from, to)}
+}
+
+func (_c *PortfolioRepository_GetPortfolioVersions_Call) Run(run func(ctx context.Context, id int, from int, to int)) *PortfolioRepository_GetPortfolioVersions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_GetPortfolioVersions_Call) Return(_a0 []*models.Portfolio, _a1 error) *PortfolioRepository_GetPortfolioVersions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioRepository_GetPortfolioVersions_Call) RunAndReturn(run func(context.Context, int, int, int) ([]*models.Portfolio, error)) *PortfolioRepository_GetPortfolioVersions_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetPortfolios provides a mock function with given fields:
-func (_m *PortfolioRepository) GetPortfolios() ([]*models.Portfolio, error) {
-	ret := _m.Called()
+// GetPortfolios provides a mock function with given fields: _a0
+func (_m *PortfolioRepository) GetPortfolios(_a0 context.Context) ([]*models.Portfolio, error) {
+	ret := _m.Called(_a0)
 
 	var r0 []*models.Portfolio
 	var r1 error
-	if rf, ok := ret.Get(0).(func() ([]*models.Portfolio, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*models.Portfolio, error)); ok {
+		return rf(_a0)
 	}
-	if rf, ok := ret.Get(0).(func() []*models.Portfolio); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) []*models.Portfolio); ok {
+		r0 = rf(_a0)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Portfolio)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -204,13 +438,14 @@ type PortfolioRepository_GetPortfolios_Call struct {
 }
 
 // GetPortfolios is a helper method to define mock.On call
-func (_e *PortfolioRepository_Expecter) GetPortfolios() *PortfolioRepository_GetPortfolios_Call {
-	return &PortfolioRepository_GetPortfolios_Call{Call: _e.mock.On("GetPortfolios")}
+//   - _a0 context.Context
+func (_e *PortfolioRepository_Expecter) GetPortfolios(_a0 interface{}) *PortfolioRepository_GetPortfolios_Call {
+	return &PortfolioRepository_GetPortfolios_Call{Call: _e.mock.On("GetPortfolios", _a0)}
 }
 
-func (_c *PortfolioRepository_GetPortfolios_Call) Run(run func()) *PortfolioRepository_GetPortfolios_Call {
+func (_c *PortfolioRepository_GetPortfolios_Call) Run(run func(_a0 context.Context)) *PortfolioRepository_GetPortfolios_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run()
+		run(args[0].(context.Context))
 	})
 	return _c
 }
@@ -220,30 +455,281 @@ func (_c *PortfolioRepository_GetPortfolios_Call) Return(_a0 []*models.Portfolio
 	return _c
 }
 
-func (_c *PortfolioRepository_GetPortfolios_Call) RunAndReturn(run func() ([]*models.Portfolio, error)) *PortfolioRepository_GetPortfolios_Call {
+func (_c *PortfolioRepository_GetPortfolios_Call) RunAndReturn(run func(context.Context) ([]*models.Portfolio, error)) *PortfolioRepository_GetPortfolios_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// UpdatePortfolio provides a mock function with given fields: _a0
-func (_m *PortfolioRepository) UpdatePortfolio(_a0 *models.Portfolio) (*models.Portfolio, error) {
-	ret := _m.Called(_a0)
+// ListPortfolios provides a mock function with given fields: _a0, _a1
+func (_m *PortfolioRepository) ListPortfolios(_a0 context.Context, _a1 *repository.PortfolioQuery) (*repository.PortfolioPage, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *repository.PortfolioPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.PortfolioQuery) (*repository.PortfolioPage, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.PortfolioQuery) *repository.PortfolioPage); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.PortfolioPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *repository.PortfolioQuery) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioRepository_ListPortfolios_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListPortfolios'
+type PortfolioRepository_ListPortfolios_Call struct {
+	*mock.Call
+}
+
+// ListPortfolios is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 *repository.PortfolioQuery
+func (_e *PortfolioRepository_Expecter) ListPortfolios(_a0 interface{}, _a1 interface{}) *PortfolioRepository_ListPortfolios_Call {
+	return &PortfolioRepository_ListPortfolios_Call{Call: _e.mock.On("ListPortfolios", _a0, _a1)}
+}
+
+func (_c *PortfolioRepository_ListPortfolios_Call) Run(run func(_a0 context.Context, _a1 *repository.PortfolioQuery)) *PortfolioRepository_ListPortfolios_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*repository.PortfolioQuery))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_ListPortfolios_Call) Return(_a0 *repository.PortfolioPage, _a1 error) *PortfolioRepository_ListPortfolios_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioRepository_ListPortfolios_Call) RunAndReturn(run func(context.Context, *repository.PortfolioQuery) (*repository.PortfolioPage, error)) *PortfolioRepository_ListPortfolios_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgeDeletedPortfolios provides a mock function with given fields: _a0, _a1
+func (_m *PortfolioRepository) PurgeDeletedPortfolios(_a0 context.Context, _a1 time.Time) (int, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioRepository_PurgeDeletedPortfolios_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgeDeletedPortfolios'
+type PortfolioRepository_PurgeDeletedPortfolios_Call struct {
+	*mock.Call
+}
+
+// PurgeDeletedPortfolios is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 time.Time
+func (_e *PortfolioRepository_Expecter) PurgeDeletedPortfolios(_a0 interface{}, _a1 interface{}) *PortfolioRepository_PurgeDeletedPortfolios_Call {
+	return &PortfolioRepository_PurgeDeletedPortfolios_Call{Call: _e.mock.On("PurgeDeletedPortfolios", _a0, _a1)}
+}
+
+func (_c *PortfolioRepository_PurgeDeletedPortfolios_Call) Run(run func(_a0 context.Context, _a1 time.Time)) *PortfolioRepository_PurgeDeletedPortfolios_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(time.Time))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_PurgeDeletedPortfolios_Call) Return(_a0 int, _a1 error) *PortfolioRepository_PurgeDeletedPortfolios_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioRepository_PurgeDeletedPortfolios_Call) RunAndReturn(run func(context.Context, time.Time) (int, error)) *PortfolioRepository_PurgeDeletedPortfolios_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PurgePortfolio provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PortfolioRepository) PurgePortfolio(_a0 context.Context, _a1 int, _a2 int) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PortfolioRepository_PurgePortfolio_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PurgePortfolio'
+type PortfolioRepository_PurgePortfolio_Call struct {
+	*mock.Call
+}
+
+// PurgePortfolio is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 int
+//   - _a2 int
+func (_e *PortfolioRepository_Expecter) PurgePortfolio(_a0 interface{}, _a1 interface{}, _a2 interface{}) *PortfolioRepository_PurgePortfolio_Call {
+	return &PortfolioRepository_PurgePortfolio_Call{Call: _e.mock.On("PurgePortfolio", _a0, _a1, _a2)}
+}
+
+func (_c *PortfolioRepository_PurgePortfolio_Call) Run(run func(_a0 context.Context, _a1 int, _a2 int)) *PortfolioRepository_PurgePortfolio_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_PurgePortfolio_Call) Return(_a0 error) *PortfolioRepository_PurgePortfolio_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *PortfolioRepository_PurgePortfolio_Call) RunAndReturn(run func(context.Context, int, int) error) *PortfolioRepository_PurgePortfolio_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RestorePortfolio provides a mock function with given fields: _a0, _a1
+func (_m *PortfolioRepository) RestorePortfolio(_a0 context.Context, _a1 int) (*models.Portfolio, error) {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 *models.Portfolio
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*models.Portfolio) (*models.Portfolio, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Portfolio, error)); ok {
+		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(*models.Portfolio) *models.Portfolio); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Portfolio); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Portfolio)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*models.Portfolio) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioRepository_RestorePortfolio_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestorePortfolio'
+type PortfolioRepository_RestorePortfolio_Call struct {
+	*mock.Call
+}
+
+// RestorePortfolio is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 int
+func (_e *PortfolioRepository_Expecter) RestorePortfolio(_a0 interface{}, _a1 interface{}) *PortfolioRepository_RestorePortfolio_Call {
+	return &PortfolioRepository_RestorePortfolio_Call{Call: _e.mock.On("RestorePortfolio", _a0, _a1)}
+}
+
+func (_c *PortfolioRepository_RestorePortfolio_Call) Run(run func(_a0 context.Context, _a1 int)) *PortfolioRepository_RestorePortfolio_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_RestorePortfolio_Call) Return(_a0 *models.Portfolio, _a1 error) *PortfolioRepository_RestorePortfolio_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioRepository_RestorePortfolio_Call) RunAndReturn(run func(context.Context, int) (*models.Portfolio, error)) *PortfolioRepository_RestorePortfolio_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// StreamPortfolios provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PortfolioRepository) StreamPortfolios(_a0 context.Context, _a1 *repository.PortfolioQuery, _a2 func(*models.Portfolio) error) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.PortfolioQuery, func(*models.Portfolio) error) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PortfolioRepository_StreamPortfolios_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StreamPortfolios'
+type PortfolioRepository_StreamPortfolios_Call struct {
+	*mock.Call
+}
+
+// StreamPortfolios is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 *repository.PortfolioQuery
+//   - _a2 func(*models.Portfolio) error
+func (_e *PortfolioRepository_Expecter) StreamPortfolios(_a0 interface{}, _a1 interface{}, _a2 interface{}) *PortfolioRepository_StreamPortfolios_Call {
+	return &PortfolioRepository_StreamPortfolios_Call{Call: _e.mock.On("StreamPortfolios", _a0, _a1, _a2)}
+}
+
+func (_c *PortfolioRepository_StreamPortfolios_Call) Run(run func(_a0 context.Context, _a1 *repository.PortfolioQuery, _a2 func(*models.Portfolio) error)) *PortfolioRepository_StreamPortfolios_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*repository.PortfolioQuery), args[2].(func(*models.Portfolio) error))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_StreamPortfolios_Call) Return(_a0 error) *PortfolioRepository_StreamPortfolios_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *PortfolioRepository_StreamPortfolios_Call) RunAndReturn(run func(context.Context, *repository.PortfolioQuery, func(*models.Portfolio) error) error) *PortfolioRepository_StreamPortfolios_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdatePortfolio provides a mock function with given fields: _a0, _a1
+func (_m *PortfolioRepository) UpdatePortfolio(_a0 context.Context, _a1 *models.Portfolio) (*models.Portfolio, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *models.Portfolio
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Portfolio) (*models.Portfolio, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Portfolio) *models.Portfolio); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Portfolio)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Portfolio) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -257,14 +743,15 @@ type PortfolioRepository_UpdatePortfolio_Call struct {
 }
 
 // UpdatePortfolio is a helper method to define mock.On call
-//   - _a0 *models.Portfolio
-func (_e *PortfolioRepository_Expecter) UpdatePortfolio(_a0 interface{}) *PortfolioRepository_UpdatePortfolio_Call {
-	return &PortfolioRepository_UpdatePortfolio_Call{Call: _e.mock.On("UpdatePortfolio", _a0)}
+//   - _a0 context.Context
+//   - _a1 *models.Portfolio
+func (_e *PortfolioRepository_Expecter) UpdatePortfolio(_a0 interface{}, _a1 interface{}) *PortfolioRepository_UpdatePortfolio_Call {
+	return &PortfolioRepository_UpdatePortfolio_Call{Call: _e.mock.On("UpdatePortfolio", _a0, _a1)}
 }
 
-func (_c *PortfolioRepository_UpdatePortfolio_Call) Run(run func(_a0 *models.Portfolio)) *PortfolioRepository_UpdatePortfolio_Call {
+func (_c *PortfolioRepository_UpdatePortfolio_Call) Run(run func(_a0 context.Context, _a1 *models.Portfolio)) *PortfolioRepository_UpdatePortfolio_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(*models.Portfolio))
+		run(args[0].(context.Context), args[1].(*models.Portfolio))
 	})
 	return _c
 }
@@ -274,7 +761,63 @@ func (_c *PortfolioRepository_UpdatePortfolio_Call) Return(_a0 *models.Portfolio
 	return _c
 }
 
-func (_c *PortfolioRepository_UpdatePortfolio_Call) RunAndReturn(run func(*models.Portfolio) (*models.Portfolio, error)) *PortfolioRepository_UpdatePortfolio_Call {
+func (_c *PortfolioRepository_UpdatePortfolio_Call) RunAndReturn(run func(context.Context, *models.Portfolio) (*models.Portfolio, error)) *PortfolioRepository_UpdatePortfolio_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdatePortfolioFunc provides a mock function with given fields: _a0, _a1, _a2
+func (_m *PortfolioRepository) UpdatePortfolioFunc(_a0 context.Context, _a1 int, _a2 func(*models.Portfolio) error) (*models.Portfolio, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *models.Portfolio
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, func(*models.Portfolio) error) (*models.Portfolio, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, func(*models.Portfolio) error) *models.Portfolio); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Portfolio)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, func(*models.Portfolio) error) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioRepository_UpdatePortfolioFunc_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdatePortfolioFunc'
+type PortfolioRepository_UpdatePortfolioFunc_Call struct {
+	*mock.Call
+}
+
+// UpdatePortfolioFunc is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 int
+//   - _a2 func(*models.Portfolio) error
+func (_e *PortfolioRepository_Expecter) UpdatePortfolioFunc(_a0 interface{}, _a1 interface{}, _a2 interface{}) *PortfolioRepository_UpdatePortfolioFunc_Call {
+	return &PortfolioRepository_UpdatePortfolioFunc_Call{Call: _e.mock.On("UpdatePortfolioFunc", _a0, _a1, _a2)}
+}
+
+func (_c *PortfolioRepository_UpdatePortfolioFunc_Call) Run(run func(_a0 context.Context, _a1 int, _a2 func(*models.Portfolio) error)) *PortfolioRepository_UpdatePortfolioFunc_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(func(*models.Portfolio) error))
+	})
+	return _c
+}
+
+func (_c *PortfolioRepository_UpdatePortfolioFunc_Call) Return(_a0 *models.Portfolio, _a1 error) *PortfolioRepository_UpdatePortfolioFunc_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioRepository_UpdatePortfolioFunc_Call) RunAndReturn(run func(context.Context, int, func(*models.Portfolio) error) (*models.Portfolio, error)) *PortfolioRepository_UpdatePortfolioFunc_Call {
 	_c.Call.Return(run)
 	return _c
 }
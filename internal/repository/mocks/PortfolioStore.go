@@ -0,0 +1,145 @@
+// Code generated by mockery v2.32.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	repository "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// PortfolioStore is an autogenerated mock type for the PortfolioStore type
+type PortfolioStore struct {
+	mock.Mock
+}
+
+type PortfolioStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *PortfolioStore) EXPECT() *PortfolioStore_Expecter {
+	return &PortfolioStore_Expecter{mock: &_m.Mock}
+}
+
+// ForTenant provides a mock function with given fields: tenant
+func (_m *PortfolioStore) ForTenant(tenant string) (repository.PortfolioRepository, error) {
+	ret := _m.Called(tenant)
+
+	var r0 repository.PortfolioRepository
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (repository.PortfolioRepository, error)); ok {
+		return rf(tenant)
+	}
+	if rf, ok := ret.Get(0).(func(string) repository.PortfolioRepository); ok {
+		r0 = rf(tenant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(repository.PortfolioRepository)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(tenant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioStore_ForTenant_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ForTenant'
+type PortfolioStore_ForTenant_Call struct {
+	*mock.Call
+}
+
+// ForTenant is a helper method to define mock.On call
+//   - tenant string
+func (_e *PortfolioStore_Expecter) ForTenant(tenant interface{}) *PortfolioStore_ForTenant_Call {
+	return &PortfolioStore_ForTenant_Call{Call: _e.mock.On("ForTenant", tenant)}
+}
+
+func (_c *PortfolioStore_ForTenant_Call) Run(run func(tenant string)) *PortfolioStore_ForTenant_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *PortfolioStore_ForTenant_Call) Return(_a0 repository.PortfolioRepository, _a1 error) *PortfolioStore_ForTenant_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioStore_ForTenant_Call) RunAndReturn(run func(string) (repository.PortfolioRepository, error)) *PortfolioStore_ForTenant_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Tenants provides a mock function with given fields: _a0
+func (_m *PortfolioStore) Tenants(_a0 context.Context) ([]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]string, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PortfolioStore_Tenants_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Tenants'
+type PortfolioStore_Tenants_Call struct {
+	*mock.Call
+}
+
+// Tenants is a helper method to define mock.On call
+//   - _a0 context.Context
+func (_e *PortfolioStore_Expecter) Tenants(_a0 interface{}) *PortfolioStore_Tenants_Call {
+	return &PortfolioStore_Tenants_Call{Call: _e.mock.On("Tenants", _a0)}
+}
+
+func (_c *PortfolioStore_Tenants_Call) Run(run func(_a0 context.Context)) *PortfolioStore_Tenants_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *PortfolioStore_Tenants_Call) Return(_a0 []string, _a1 error) *PortfolioStore_Tenants_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *PortfolioStore_Tenants_Call) RunAndReturn(run func(context.Context) ([]string, error)) *PortfolioStore_Tenants_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewPortfolioStore creates a new instance of PortfolioStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPortfolioStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PortfolioStore {
+	mock := &PortfolioStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
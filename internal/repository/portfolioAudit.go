@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+// SystemActor is recorded for changes made without an actor in the context, such as purges.
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// ContextWithActor returns a context that records the actor on every change made with it.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor recorded for changes made with the context.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+
+	return SystemActor
+}
+
+// AuditQuery selects one page of the history of a portfolio, oldest entries first.
+type AuditQuery struct {
+	Limit int
+	// After skips entries up to and including the one with this id.
+	After int
+}
+
+type AuditPage struct {
+	Items []*models.AuditEntry
+	// HasMore reports whether entries exist after the last item.
+	HasMore bool
+}
+
+// AuditStore reads the audit history of portfolios. Every repository writes an entry for each
+// change in the same lock or transaction as the change itself, so the history is complete and an
+// entry exists only if its change was saved. Entries are never changed, and they are kept when
+// their portfolio is purged.
+type AuditStore interface {
+	GetPortfolioHistory(context.Context, int, *AuditQuery) (*AuditPage, error)
+}
+
+// newAuditEntry describes a change from before to after, either of which is nil if the portfolio did not exist.
+func newAuditEntry(ctx context.Context, operation models.AuditOperation, before, after *models.Portfolio) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		Actor:     ActorFromContext(ctx),
+		Operation: operation,
+		At:        time.Now().UTC(),
+		Before:    copyPortfolio(before),
+		After:     copyPortfolio(after),
+		Changes:   DiffPortfolios(before, after),
+	}
+
+	if after != nil {
+		entry.PortfolioId = after.Id
+	} else {
+		entry.PortfolioId = before.Id
+	}
+
+	// The time of the change is the update time the portfolio got, purges do not update it.
+	if after != nil && after.UpdatedAt != nil {
+		entry.At = after.UpdatedAt.UTC()
+	}
+
+	return entry
+}
+
+// copyPortfolio returns a copy of the portfolio that shares no memory with it, or nil.
+// Entries hold copies, so that callers cannot change the history through the portfolios they get.
+func copyPortfolio(portfolio *models.Portfolio) *models.Portfolio {
+	if portfolio == nil {
+		return nil
+	}
+
+	model := *portfolio
+	model.CreatedAt = copyTime(portfolio.CreatedAt)
+	model.UpdatedAt = copyTime(portfolio.UpdatedAt)
+	model.DeletedAt = copyTime(portfolio.DeletedAt)
+
+	return &model
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	value := *t
+
+	return &value
+}
+
+// copyAuditEntry returns a copy of the entry that shares no memory with it.
+func copyAuditEntry(entry models.AuditEntry) *models.AuditEntry {
+	entry.Before = copyPortfolio(entry.Before)
+	entry.After = copyPortfolio(entry.After)
+	entry.Changes = append([]models.AuditChange(nil), entry.Changes...)
+
+	return &entry
+}
+
+// auditedFields are the fields compared by DiffPortfolios. Id and CreatedAt never change,
+// UpdatedAt and Version change with every change.
+var auditedFields = []struct {
+	name  string
+	value func(*models.Portfolio) interface{}
+}{
+	{"name", func(p *models.Portfolio) interface{} { return p.Name }},
+	{"isInternal", func(p *models.Portfolio) interface{} { return p.IsInternal }},
+	{"isFinance", func(p *models.Portfolio) interface{} { return p.IsFinance }},
+	{"isActive", func(p *models.Portfolio) interface{} { return p.IsActive }},
+	{"deletedAt", func(p *models.Portfolio) interface{} { return p.DeletedAt }},
+}
+
+// DiffPortfolios returns the fields of a portfolio that differ between before and after, in a fixed order.
+// Either side is nil if the portfolio did not exist, its fields are then null.
+func DiffPortfolios(before, after *models.Portfolio) []models.AuditChange {
+	changes := make([]models.AuditChange, 0)
+
+	for _, field := range auditedFields {
+		from := auditValue(before, field.value)
+		to := auditValue(after, field.value)
+
+		if !bytes.Equal(from, to) {
+			changes = append(changes, models.AuditChange{Field: field.name, From: from, To: to})
+		}
+	}
+
+	return changes
+}
+
+func auditValue(portfolio *models.Portfolio, value func(*models.Portfolio) interface{}) json.RawMessage {
+	if portfolio == nil {
+		return json.RawMessage("null")
+	}
+
+	data, _ := json.Marshal(value(portfolio))
+
+	return data
+}
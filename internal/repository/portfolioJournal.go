@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+const (
+	journalFileName  = "portfolios.log"
+	snapshotFileName = "portfolios.snapshot"
+
+	// Every record is prefixed with its payload length and the CRC32 checksum of the length and the payload.
+	journalHeaderSize = 8
+
+	DefaultSnapshotEvery = 1000
+)
+
+const (
+	journalOpCreate = "create"
+	journalOpUpdate = "update"
+	journalOpDelete = "delete"
+	// journalOpBatch applies all records of a batch at once.
+	journalOpBatch = "batch"
+)
+
+// ErrJournalCorrupted is returned when a record in the middle of the log fails its checksum
+// or its length runs past records that follow it.
+// Only the last record can be torn by a crash, so the log is left as it is for an operator to inspect.
+var ErrJournalCorrupted = errors.New("portfolio journal is corrupted")
+
+type journalRecord struct {
+	Op        string            `json:"op"`
+	Id        int               `json:"id"`
+	Portfolio *models.Portfolio `json:"portfolio,omitempty"`
+	Batch     []journalRecord   `json:"batch,omitempty"`
+	// Audit is the audit entry of the change, which is applied with it.
+	Audit *models.AuditEntry `json:"audit,omitempty"`
+}
+
+type portfolioSnapshot struct {
+	Counter    int                `json:"counter"`
+	Portfolios []models.Portfolio `json:"portfolios"`
+	// Audit holds the entries of all portfolios ordered by id.
+	Audit []models.AuditEntry `json:"audit,omitempty"`
+}
+
+// portfolioJournal is a write-ahead log of portfolioRepository mutations.
+// Every mutation is appended and synced before it is applied in memory,
+// and after snapshotEvery records the whole storage is written to a
+// snapshot file and the log starts over.
+type portfolioJournal struct {
+	dir           string
+	file          *os.File
+	size          int64
+	records       int
+	snapshotEvery int
+}
+
+func (j *portfolioJournal) append(record journalRecord) error {
+	payload, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, journalHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], journalChecksum(buf[0:4], payload))
+	copy(buf[journalHeaderSize:], payload)
+
+	if _, err := j.file.Write(buf); err != nil {
+		return j.rewind(err)
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return j.rewind(err)
+	}
+
+	j.size += int64(len(buf))
+	j.records++
+
+	return nil
+}
+
+// rewind drops a partially written record so later appends are not lost behind it on replay.
+func (j *portfolioJournal) rewind(cause error) error {
+	if err := j.file.Truncate(j.size); err != nil {
+		return fmt.Errorf("%w (rewind journal: %v)", cause, err)
+	}
+
+	if _, err := j.file.Seek(j.size, io.SeekStart); err != nil {
+		return fmt.Errorf("%w (rewind journal: %v)", cause, err)
+	}
+
+	return cause
+}
+
+func (j *portfolioJournal) shouldSnapshot() bool {
+	return j.snapshotEvery > 0 && j.records >= j.snapshotEvery
+}
+
+// snapshot atomically replaces the snapshot file and truncates the log.
+// A crash between the two steps is harmless because replaying records
+// on top of a newer snapshot yields the same state.
+func (j *portfolioJournal) snapshot(snapshot *portfolioSnapshot) error {
+	data, err := json.Marshal(snapshot)
+
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(j.dir, snapshotFileName)
+	tmpPath := path + ".tmp"
+
+	if err := writeFileSync(tmpPath, data); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	if err := syncDir(j.dir); err != nil {
+		return err
+	}
+
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	j.size = 0
+	j.records = 0
+
+	return j.file.Sync()
+}
+
+func (j *portfolioJournal) close() error {
+	return j.file.Close()
+}
+
+// openPortfolioJournal loads the snapshot and replays the log into restore and apply.
+// A torn or corrupted record at the end of the log, left by a crash in the middle
+// of an append, is truncated away, a corrupted record before it fails with ErrJournalCorrupted.
+func openPortfolioJournal(dir string, snapshotEvery int, restore func(*portfolioSnapshot), apply func(journalRecord)) (*portfolioJournal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := readSnapshot(filepath.Join(dir, snapshotFileName))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot != nil {
+		restore(snapshot)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_RDWR|os.O_CREATE, 0o644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	records, validSize, err := replayJournal(file, info.Size(), apply)
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if err := file.Truncate(validSize); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if _, err := file.Seek(validSize, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &portfolioJournal{
+		dir:           dir,
+		file:          file,
+		size:          validSize,
+		records:       records,
+		snapshotEvery: snapshotEvery,
+	}, nil
+}
+
+func replayJournal(file *os.File, fileSize int64, apply func(journalRecord)) (int, int64, error) {
+	reader := bufio.NewReader(file)
+	header := make([]byte, journalHeaderSize)
+	records := 0
+	offset := int64(0)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, offset, nil
+			}
+
+			return 0, 0, err
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		checksum := binary.BigEndian.Uint32(header[4:8])
+
+		if offset+int64(journalHeaderSize)+int64(size) > fileSize {
+			if err := tornTail(file, offset, fileSize); err != nil {
+				return 0, 0, err
+			}
+
+			return records, offset, nil
+		}
+
+		payload := make([]byte, size)
+
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, offset, nil
+			}
+
+			return 0, 0, err
+		}
+
+		if journalChecksum(header[0:4], payload) != checksum {
+			if offset+int64(journalHeaderSize)+int64(size) == fileSize {
+				return records, offset, nil
+			}
+
+			return 0, 0, fmt.Errorf("%w: checksum mismatch at offset %d", ErrJournalCorrupted, offset)
+		}
+
+		var record journalRecord
+
+		if err := json.Unmarshal(payload, &record); err != nil {
+			return 0, 0, fmt.Errorf("decode journal record at offset %d: %w", offset, err)
+		}
+
+		apply(record)
+		records++
+		offset += int64(journalHeaderSize) + int64(size)
+	}
+}
+
+// journalChecksum covers the length field as well, so that a corrupted length cannot pass
+// for the length of a torn record.
+func journalChecksum(length []byte, payload []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE(length), crc32.IEEETable, payload)
+}
+
+// tornTail checks the bytes from offset to the end of the file, which start with a record
+// that is longer than the file. That is a torn append unless a valid record follows it,
+// in which case the length of a record in the middle of the log was corrupted.
+func tornTail(file *os.File, offset int64, fileSize int64) error {
+	tail := make([]byte, fileSize-offset)
+
+	if _, err := file.ReadAt(tail, offset); err != nil {
+		return err
+	}
+
+	for i := 1; i+journalHeaderSize <= len(tail); i++ {
+		end := int64(i) + int64(journalHeaderSize) + int64(binary.BigEndian.Uint32(tail[i:i+4]))
+
+		if end > int64(len(tail)) {
+			continue
+		}
+
+		if journalChecksum(tail[i:i+4], tail[i+journalHeaderSize:end]) == binary.BigEndian.Uint32(tail[i+4:i+journalHeaderSize]) {
+			return fmt.Errorf("%w: length of the record at offset %d exceeds the log", ErrJournalCorrupted, offset)
+		}
+	}
+
+	return nil
+}
+
+func readSnapshot(path string) (*portfolioSnapshot, error) {
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &portfolioSnapshot{}
+
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("decode snapshot %s: %w", path, err)
+	}
+
+	return snapshot, nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	return d.Sync()
+}
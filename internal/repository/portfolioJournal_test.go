@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/stretchr/testify/suite"
+)
+
+var ctx = context.Background()
+
+type PortfolioJournalSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestPortfolioJournalSuite(t *testing.T) {
+	suite.Run(t, new(PortfolioJournalSuite))
+}
+
+func (suite *PortfolioJournalSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *PortfolioJournalSuite) open(snapshotEvery int) *portfolioRepository {
+	p, err := NewDurablePortfolioRepository(suite.dir, snapshotEvery)
+	suite.Require().NoError(err)
+
+	return p
+}
+
+// crash releases the files without the final snapshot written by Close.
+func (suite *PortfolioJournalSuite) crash(p *portfolioRepository) {
+	suite.Require().NoError(p.journal.close())
+}
+
+func (suite *PortfolioJournalSuite) journalSize() int64 {
+	info, err := os.Stat(filepath.Join(suite.dir, journalFileName))
+	suite.Require().NoError(err)
+
+	return info.Size()
+}
+
+func (suite *PortfolioJournalSuite) seed(p *portfolioRepository) {
+	r := suite.Require()
+
+	for _, name := range []string{"portfolio-1", "portfolio-2", "portfolio-3"} {
+		_, err := p.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: name})
+		r.NoError(err)
+	}
+
+	second, err := p.GetPortfolioById(ctx, 2)
+	r.NoError(err)
+	second.IsActive = true
+	_, err = p.UpdatePortfolio(ctx, second)
+	r.NoError(err)
+
+	r.NoError(p.DeletePortfolio(ctx, 3, 0))
+}
+
+func (suite *PortfolioJournalSuite) requireSeeded(p *portfolioRepository) {
+	r := suite.Require()
+
+	portfolios, err := p.GetPortfolios(ctx)
+	r.NoError(err)
+	r.Len(portfolios, 2)
+
+	second, err := p.GetPortfolioById(ctx, 2)
+	r.NoError(err)
+	r.True(second.IsActive)
+
+	_, err = p.GetPortfolioById(ctx, 3)
+	r.ErrorIs(err, ErrPortfolioNotFound)
+
+	created, err := p.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-4"})
+	r.NoError(err)
+	r.Equal(4, created.Id, "counter must survive restarts even when the newest portfolio was deleted")
+}
+
+func (suite *PortfolioJournalSuite) TestReplayJournal() {
+	p := suite.open(0)
+	suite.seed(p)
+	suite.crash(p)
+
+	suite.requireSeeded(suite.open(0))
+}
+
+func (suite *PortfolioJournalSuite) TestReplaySnapshotAndJournal() {
+	r := suite.Require()
+	p := suite.open(2)
+	suite.seed(p)
+
+	r.Equal(1, p.journal.records, "5 writes with snapshots every 2 writes leave one record in the log")
+	r.FileExists(filepath.Join(suite.dir, snapshotFileName))
+	suite.crash(p)
+
+	suite.requireSeeded(suite.open(2))
+}
+
+func (suite *PortfolioJournalSuite) TestCloseWritesSnapshot() {
+	r := suite.Require()
+	p := suite.open(0)
+	suite.seed(p)
+	r.NoError(p.Close())
+
+	r.Zero(suite.journalSize())
+	suite.requireSeeded(suite.open(0))
+}
+
+func (suite *PortfolioJournalSuite) TestTornRecordIsTruncated() {
+	r := suite.Require()
+	p := suite.open(0)
+	suite.seed(p)
+	suite.crash(p)
+
+	validSize := suite.journalSize()
+	file, err := os.OpenFile(filepath.Join(suite.dir, journalFileName), os.O_WRONLY|os.O_APPEND, 0o644)
+	r.NoError(err)
+	_, err = file.Write([]byte{0, 0, 0, 120, 1, 2, 3, 4, '{', '"', 'o'})
+	r.NoError(err)
+	r.NoError(file.Close())
+
+	p = suite.open(0)
+	r.Equal(validSize, suite.journalSize())
+
+	suite.requireSeeded(p)
+	suite.crash(p)
+
+	p = suite.open(0)
+	_, err = p.GetPortfolioById(ctx, 4)
+	r.NoError(err, "records appended after a truncated tail must be replayed")
+}
+
+func (suite *PortfolioJournalSuite) TestCorruptedRecordIsTruncated() {
+	r := suite.Require()
+	p := suite.open(0)
+	suite.seed(p)
+	validSize := suite.journalSize()
+
+	_, err := p.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-5"})
+	r.NoError(err)
+	suite.crash(p)
+
+	path := filepath.Join(suite.dir, journalFileName)
+	data, err := os.ReadFile(path)
+	r.NoError(err)
+	data[len(data)-2] ^= 0xff
+	r.NoError(os.WriteFile(path, data, 0o644))
+
+	p = suite.open(0)
+	r.Equal(validSize, suite.journalSize())
+	suite.requireSeeded(p)
+}
+
+func (suite *PortfolioJournalSuite) TestCorruptedRecordInTheMiddleFails() {
+	r := suite.Require()
+	p := suite.open(0)
+	suite.seed(p)
+	suite.crash(p)
+
+	path := filepath.Join(suite.dir, journalFileName)
+	data, err := os.ReadFile(path)
+	r.NoError(err)
+	data[journalHeaderSize+2] ^= 0xff
+	r.NoError(os.WriteFile(path, data, 0o644))
+
+	_, err = NewDurablePortfolioRepository(suite.dir, 0)
+	r.ErrorIs(err, ErrJournalCorrupted)
+	r.Equal(int64(len(data)), suite.journalSize(), "a corrupted log must not be truncated")
+}
+
+func (suite *PortfolioJournalSuite) TestCorruptedLengthInTheMiddleFails() {
+	r := suite.Require()
+	p := suite.open(0)
+	suite.seed(p)
+	suite.crash(p)
+
+	path := filepath.Join(suite.dir, journalFileName)
+	original, err := os.ReadFile(path)
+	r.NoError(err)
+
+	// The first length longer than the log, and shorter than the first record.
+	for _, length := range []byte{0x7f, 0x00} {
+		data := append([]byte(nil), original...)
+		data[0] = length
+		data[3] ^= 0x01
+		r.NoError(os.WriteFile(path, data, 0o644))
+
+		_, err = NewDurablePortfolioRepository(suite.dir, 0)
+		r.ErrorIs(err, ErrJournalCorrupted)
+		r.Equal(int64(len(data)), suite.journalSize(), "a corrupted log must not be truncated")
+	}
+}
+
+func (suite *PortfolioJournalSuite) TestReplayBatch() {
+	r := suite.Require()
+	p := suite.open(0)
+
+	results, err := p.ApplyPortfolioOperations(ctx, []PortfolioOperation{
+		{Type: OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-1"}},
+		{Type: OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-2"}},
+		{Type: OperationDelete, Id: 1},
+	}, true)
+	r.NoError(err)
+	r.Len(results, 3)
+
+	size := suite.journalSize()
+	suite.crash(p)
+
+	p = suite.open(0)
+	defer p.Close()
+
+	r.Equal(size, suite.journalSize(), "a batch is a single record")
+
+	portfolios, err := p.GetPortfolios(ctx)
+	r.NoError(err)
+	r.Len(portfolios, 1)
+	r.Equal("portfolio-2", portfolios[0].Name)
+	r.Equal(2, p.counter)
+}
+
+func (suite *PortfolioJournalSuite) TestReplaySoftDeleteAndPurge() {
+	r := suite.Require()
+	p := suite.open(0)
+
+	deleted, err := p.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-1"})
+	r.NoError(err)
+	purged, err := p.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-2"})
+	r.NoError(err)
+	r.NoError(p.DeletePortfolio(ctx, purged.Id, 0))
+	r.NoError(p.DeletePortfolio(ctx, deleted.Id, 0))
+
+	count, err := p.PurgeDeletedPortfolios(ctx, *p.storage[deleted.Id].DeletedAt)
+	r.NoError(err)
+	r.Equal(1, count)
+
+	suite.crash(p)
+
+	p = suite.open(0)
+	defer p.Close()
+
+	r.Len(p.storage, 1)
+	r.NotNil(p.storage[deleted.Id].DeletedAt)
+
+	restored, err := p.RestorePortfolio(ctx, deleted.Id)
+	r.NoError(err)
+	r.Equal(3, restored.Version)
+}
+
+func (suite *PortfolioJournalSuite) TestReplayHistory() {
+	r := suite.Require()
+	p := suite.open(2)
+	suite.seed(p)
+	suite.crash(p)
+
+	p = suite.open(2)
+	defer p.Close()
+
+	page, err := p.GetPortfolioHistory(ctx, 3, &AuditQuery{Limit: 10})
+	r.NoError(err)
+	r.Len(page.Items, 2)
+	r.Equal(models.AuditDelete, page.Items[1].Operation)
+
+	r.NoError(p.PurgePortfolio(ctx, 3, 0))
+
+	page, err = p.GetPortfolioHistory(ctx, 3, &AuditQuery{Limit: 10})
+	r.NoError(err)
+	r.Len(page.Items, 3)
+	r.Equal(6, page.Items[2].Id, "entry ids must survive restarts")
+}
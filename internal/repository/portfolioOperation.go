@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+)
+
+type PortfolioOperationType string
+
+const (
+	OperationCreate PortfolioOperationType = "create"
+	OperationUpdate PortfolioOperationType = "update"
+	OperationDelete PortfolioOperationType = "delete"
+)
+
+// ErrPortfolioBatchRolledBack is the result of every operation of an atomic batch
+// that was rolled back because another operation failed.
+var ErrPortfolioBatchRolledBack = errors.New("portfolio batch rolled back")
+
+// PortfolioOperation is one change of a batch, with the arguments of the matching method.
+type PortfolioOperation struct {
+	Type PortfolioOperationType
+	// Create is the body of a create.
+	Create *requests.CreatePortfolioRequest
+	// Update is the portfolio to save with an update, as for UpdatePortfolio.
+	Update *models.Portfolio
+	// Id and Version identify the portfolio to delete, as for DeletePortfolio.
+	Id      int
+	Version int
+}
+
+// PortfolioOperationResult is the saved portfolio or the error of one operation.
+// Deletes have no portfolio.
+type PortfolioOperationResult struct {
+	Portfolio *models.Portfolio
+	Err       error
+}
+
+// isOperationError reports whether err fails a single operation rather than the whole batch.
+func isOperationError(err error) bool {
+	return errors.Is(err, ErrPortfolioNotFound) ||
+		errors.Is(err, ErrPortfolioAlreadyExists) ||
+		errors.Is(err, ErrPortfolioVersionMismatch) ||
+		errors.Is(err, ErrPortfolioNotVisible)
+}
+
+// rollBack marks all results but the failed one as rolled back.
+func rollBack(results []PortfolioOperationResult, failed int) {
+	for i := range results {
+		if i != failed {
+			results[i] = PortfolioOperationResult{Err: ErrPortfolioBatchRolledBack}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+// PortfolioFilter narrows a listing down to matching portfolios.
+// Nil and empty fields match everything. Time ranges include
+// their lower bound and exclude the upper one. Soft-deleted
+// portfolios never match.
+type PortfolioFilter struct {
+	IsActive   *bool
+	IsFinance  *bool
+	IsInternal *bool
+
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+
+	// NamePrefix matches names that start with it, case-sensitively.
+	NamePrefix string
+}
+
+// PortfolioQuery selects one page of filtered and sorted portfolios.
+type PortfolioQuery struct {
+	Filter PortfolioFilter
+	// Sort orders the portfolios, ties are always broken by id.
+	Sort []PortfolioSortKey
+	// Limit is the maximum number of portfolios in the page.
+	Limit int
+	// After skips portfolios up to and including it in Sort order.
+	// Only its id and the sorted fields are used, so it can be the last
+	// item of the previous page even if that portfolio was changed or deleted since.
+	After *models.Portfolio
+	// WithTotalCount asks for the number of matching portfolios regardless of paging.
+	WithTotalCount bool
+	// AsOf reads the portfolios as they were at this time instead of their current state.
+	AsOf *time.Time
+}
+
+type PortfolioPage struct {
+	Items []*models.Portfolio
+	// HasMore reports whether matching portfolios exist after the last item.
+	HasMore bool
+	// TotalCount is only filled when the query asks for it.
+	TotalCount int
+}
+
+func (f *PortfolioFilter) matches(model *models.Portfolio) bool {
+	switch {
+	case model.DeletedAt != nil:
+		return false
+	case f.IsActive != nil && *f.IsActive != model.IsActive:
+		return false
+	case f.IsFinance != nil && *f.IsFinance != model.IsFinance:
+		return false
+	case f.IsInternal != nil && *f.IsInternal != model.IsInternal:
+		return false
+	case !inRange(*model.CreatedAt, f.CreatedFrom, f.CreatedTo):
+		return false
+	case !inRange(*model.UpdatedAt, f.UpdatedFrom, f.UpdatedTo):
+		return false
+	}
+
+	return strings.HasPrefix(model.Name, f.NamePrefix)
+}
+
+func inRange(t time.Time, from, to *time.Time) bool {
+	if from != nil && t.Before(*from) {
+		return false
+	}
+
+	return to == nil || t.Before(*to)
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,53 +14,208 @@ import (
 
 type portfolioRepository struct {
 	storage map[int]models.Portfolio
+	// history holds the audit entries of every portfolio ever created, ordered by id.
+	history map[int][]models.AuditEntry
 
-	mu      sync.RWMutex
-	counter int
+	mu           sync.RWMutex
+	counter      int
+	auditCounter int
+
+	// journal is set only for durable repositories.
+	journal *portfolioJournal
+	// staged collects the records of a batch instead of committing them.
+	staged *[]journalRecord
 }
 
 var (
-	ErrPortfolioNotFound      = errors.New("portfolio not found")
-	ErrPortfolioAlreadyExists = errors.New("portfolio already exists")
+	ErrPortfolioNotFound        = errors.New("portfolio not found")
+	ErrPortfolioAlreadyExists   = errors.New("portfolio already exists")
+	ErrPortfolioVersionMismatch = errors.New("portfolio version mismatch")
+	ErrPortfolioNotDeleted      = errors.New("portfolio is not deleted")
 )
 
+// PortfolioRepository stores portfolios. Soft-deleted portfolios are invisible to all methods
+// except RestorePortfolio and the purges: they are not read, listed, updated or deleted again,
+// and their names can be used by other portfolios.
+//
 //go:generate mockery --name PortfolioRepository
 type PortfolioRepository interface {
-	GetPortfolios() ([]*models.Portfolio, error)
-	GetPortfolioById(int) (*models.Portfolio, error)
-	CreatePortfolio(*requests.CreatePortfolioRequest) (*models.Portfolio, error)
-	UpdatePortfolio(*models.Portfolio) (*models.Portfolio, error)
-	DeletePortfolio(int) error
+	AuditStore
+
+	GetPortfolios(context.Context) ([]*models.Portfolio, error)
+	ListPortfolios(context.Context, *PortfolioQuery) (*PortfolioPage, error)
+	// StreamPortfolios calls yield for every portfolio that matches the query in Sort order,
+	// without loading them all at once. Limit and WithTotalCount are ignored. It stops at
+	// the first error of yield or once the context is done and returns that error.
+	StreamPortfolios(context.Context, *PortfolioQuery, func(*models.Portfolio) error) error
+	GetPortfolioById(context.Context, int) (*models.Portfolio, error)
+	// GetPortfolioAsOf returns the portfolio as it was at the given time. It returns ErrPortfolioNotFound
+	// if the portfolio did not exist or was soft-deleted at that time. Like ListPortfolios with AsOf,
+	// it only knows states since the repository started recording history.
+	GetPortfolioAsOf(context.Context, int, time.Time) (*models.Portfolio, error)
+	// GetPortfolioVersions returns the known versions of the portfolio from and to the given
+	// version numbers, inclusive, ordered by version. Versions of deleted and purged portfolios
+	// are kept, a portfolio without such versions returns an empty slice.
+	GetPortfolioVersions(ctx context.Context, id int, from int, to int) ([]*models.Portfolio, error)
+	CreatePortfolio(context.Context, *requests.CreatePortfolioRequest) (*models.Portfolio, error)
+	// UpdatePortfolio saves the portfolio and increments its version. Unless Version is 0,
+	// it must match the stored version or ErrPortfolioVersionMismatch is returned.
+	UpdatePortfolio(context.Context, *models.Portfolio) (*models.Portfolio, error)
+	// UpdatePortfolioFunc atomically reads the portfolio, lets update change it and saves it.
+	// Nothing is saved when update returns an error, which is returned as is.
+	// Changes to Id, CreatedAt, UpdatedAt and Version are ignored.
+	UpdatePortfolioFunc(context.Context, int, func(*models.Portfolio) error) (*models.Portfolio, error)
+	// DeletePortfolio soft-deletes the portfolio with the given id and increments its version. Unless
+	// the version is 0, it must match the stored version or ErrPortfolioVersionMismatch is returned.
+	DeletePortfolio(context.Context, int, int) error
+	// RestorePortfolio undoes the soft delete of the portfolio with the given id and increments its version.
+	// It returns ErrPortfolioNotDeleted if the portfolio is not deleted and ErrPortfolioAlreadyExists
+	// if its name was taken in the meantime.
+	RestorePortfolio(context.Context, int) (*models.Portfolio, error)
+	// PurgePortfolio permanently deletes the portfolio with the given id, deleted or not.
+	// Unless the version is 0, it must match the stored version.
+	PurgePortfolio(context.Context, int, int) error
+	// PurgeDeletedPortfolios permanently deletes portfolios soft-deleted before the given time
+	// and returns how many there were.
+	PurgeDeletedPortfolios(context.Context, time.Time) (int, error)
+	// ApplyPortfolioOperations applies operations in order under a single lock or transaction
+	// and returns a result for each of them. Operations fail with the errors of the matching
+	// methods. When atomic, a failed operation rolls back the batch and all other operations
+	// fail with ErrPortfolioBatchRolledBack. An error is returned only if the batch could not run.
+	ApplyPortfolioOperations(context.Context, []PortfolioOperation, bool) ([]PortfolioOperationResult, error)
 }
 
-func (p *portfolioRepository) GetPortfolios() ([]*models.Portfolio, error) {
+func (p *portfolioRepository) GetPortfolios(ctx context.Context) ([]*models.Portfolio, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	items := make([]*models.Portfolio, 0, len(p.storage))
 
-	for _, v := range p.storage {
-		items = append(items, &v)
+	visibility := VisibilityFromContext(ctx)
+
+	for _, item := range p.sorted(totalOrder(nil), nil) {
+		if item.DeletedAt == nil && !visibility.Hides(item) {
+			items = append(items, item)
+		}
 	}
 
 	return items, nil
 }
 
-func (p *portfolioRepository) CreatePortfolio(body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+func (p *portfolioRepository) ListPortfolios(ctx context.Context, query *PortfolioQuery) (*PortfolioPage, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &PortfolioPage{Items: make([]*models.Portfolio, 0, query.Limit)}
+
+	keys := totalOrder(query.Sort)
+	visibility := VisibilityFromContext(ctx)
+
+	for _, item := range p.sorted(keys, query.AsOf) {
+		if !query.Filter.matches(item) || visibility.Hides(item) {
+			continue
+		}
+
+		if query.WithTotalCount {
+			page.TotalCount++
+		}
+
+		if page.HasMore || query.After != nil && comparePortfolios(item, query.After, keys) <= 0 {
+			continue
+		}
+
+		if len(page.Items) == query.Limit {
+			page.HasMore = true
+			continue
+		}
+
+		page.Items = append(page.Items, item)
+	}
+
+	return page, nil
+}
+
+func (p *portfolioRepository) StreamPortfolios(ctx context.Context, query *PortfolioQuery, yield func(*models.Portfolio) error) error {
+	keys := totalOrder(query.Sort)
+
+	// The portfolios are copied under the lock, so that a slow yield does not block writers.
+	p.mu.RLock()
+	items := p.sorted(keys, query.AsOf)
+	p.mu.RUnlock()
+
+	visibility := VisibilityFromContext(ctx)
+
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !query.Filter.matches(item) || visibility.Hides(item) || query.After != nil && comparePortfolios(item, query.After, keys) <= 0 {
+			continue
+		}
+
+		if err := yield(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *portfolioRepository) GetPortfolioHistory(ctx context.Context, id int, query *AuditQuery) (*AuditPage, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &AuditPage{Items: make([]*models.AuditEntry, 0, query.Limit)}
+
+	if p.hidesHistory(ctx, id) {
+		return page, nil
+	}
+
+	for _, entry := range p.history[id] {
+		if entry.Id <= query.After {
+			continue
+		}
+
+		if len(page.Items) == query.Limit {
+			page.HasMore = true
+			break
+		}
+
+		page.Items = append(page.Items, copyAuditEntry(entry))
+	}
+
+	return page, nil
+}
+
+func (p *portfolioRepository) CreatePortfolio(ctx context.Context, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	name := body.Name
 
-	for _, v := range p.storage {
-		if v.Name == name {
-			return nil, ErrPortfolioNotFound
-		}
+	if p.nameTaken(name, 0) {
+		return nil, ErrPortfolioAlreadyExists
 	}
 
 	now := time.Now()
-	p.counter = p.counter + 1
-	id := p.counter
+	id := p.counter + 1
 
 	model := models.Portfolio{
 		Id:         id,
@@ -67,59 +225,549 @@ func (p *portfolioRepository) CreatePortfolio(body *requests.CreatePortfolioRequ
 		IsInternal: body.IsInternal,
 		CreatedAt:  &now,
 		UpdatedAt:  &now,
+		Version:    1,
 	}
 
-	p.storage[id] = model
+	if err := checkVisible(ctx, nil, &model); err != nil {
+		return nil, err
+	}
+
+	if err := p.commit(journalRecord{Op: journalOpCreate, Id: id, Portfolio: &model, Audit: p.audit(ctx, models.AuditCreate, nil, &model)}); err != nil {
+		return nil, err
+	}
 
 	return &model, nil
 }
 
-func (p *portfolioRepository) GetPortfolioById(id int) (*models.Portfolio, error) {
+func (p *portfolioRepository) GetPortfolioById(ctx context.Context, id int) (*models.Portfolio, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	model, ok := p.storage[id]
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	model, ok := p.live(id)
 
-	if !ok {
+	if !ok || VisibilityFromContext(ctx).Hides(&model) {
 		return nil, ErrPortfolioNotFound
 	}
 
 	return &model, nil
 }
 
-func (p *portfolioRepository) DeletePortfolio(id int) error {
+func (p *portfolioRepository) GetPortfolioAsOf(ctx context.Context, id int, asOf time.Time) (*models.Portfolio, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	model := p.versionAt(id, asOf)
+
+	if model == nil || model.DeletedAt != nil || VisibilityFromContext(ctx).Hides(model) {
+		return nil, ErrPortfolioNotFound
+	}
+
+	return model, nil
+}
+
+func (p *portfolioRepository) GetPortfolioVersions(ctx context.Context, id int, from int, to int) ([]*models.Portfolio, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	versions := make([]*models.Portfolio, 0)
+
+	if p.hidesHistory(ctx, id) {
+		return versions, nil
+	}
+
+	for _, entry := range p.history[id] {
+		if entry.After != nil && entry.After.Version >= from && entry.After.Version <= to {
+			versions = append(versions, copyPortfolio(entry.After))
+		}
+	}
+
+	return versions, nil
+}
+
+func (p *portfolioRepository) DeletePortfolio(ctx context.Context, id int, version int) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if _, ok := p.storage[id]; !ok {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	stored, ok := p.live(id)
+
+	if !ok || VisibilityFromContext(ctx).Hides(&stored) {
 		return ErrPortfolioNotFound
 	}
 
-	delete(p.storage, id)
+	if version != 0 && version != stored.Version {
+		return ErrPortfolioVersionMismatch
+	}
 
-	return nil
+	now := time.Now()
+	deleted := stored
+	deleted.UpdatedAt = &now
+	deleted.DeletedAt = &now
+	deleted.Version = stored.Version + 1
+
+	return p.commit(journalRecord{Op: journalOpUpdate, Id: id, Portfolio: &deleted, Audit: p.audit(ctx, models.AuditDelete, &stored, &deleted)})
 }
 
-func (p *portfolioRepository) UpdatePortfolio(model *models.Portfolio) (*models.Portfolio, error) {
+func (p *portfolioRepository) RestorePortfolio(ctx context.Context, id int) (*models.Portfolio, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	for _, v := range p.storage {
-		if v.Name == model.Name && v.Id != model.Id {
-			return nil, ErrPortfolioAlreadyExists
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	stored, ok := p.storage[id]
+
+	if !ok || VisibilityFromContext(ctx).Hides(&stored) {
+		return nil, ErrPortfolioNotFound
+	}
+
+	if stored.DeletedAt == nil {
+		return nil, ErrPortfolioNotDeleted
+	}
+
+	if p.nameTaken(stored.Name, id) {
+		return nil, ErrPortfolioAlreadyExists
+	}
+
+	now := time.Now()
+	restored := stored
+	restored.UpdatedAt = &now
+	restored.DeletedAt = nil
+	restored.Version = stored.Version + 1
+
+	if err := p.commit(journalRecord{Op: journalOpUpdate, Id: id, Portfolio: &restored, Audit: p.audit(ctx, models.AuditRestore, &stored, &restored)}); err != nil {
+		return nil, err
+	}
+
+	return &restored, nil
+}
+
+func (p *portfolioRepository) PurgePortfolio(ctx context.Context, id int, version int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	stored, ok := p.storage[id]
+
+	if !ok || VisibilityFromContext(ctx).Hides(&stored) {
+		return ErrPortfolioNotFound
+	}
+
+	if version != 0 && version != stored.Version {
+		return ErrPortfolioVersionMismatch
+	}
+
+	return p.commit(journalRecord{Op: journalOpDelete, Id: id, Audit: p.audit(ctx, models.AuditPurge, &stored, nil)})
+}
+
+func (p *portfolioRepository) PurgeDeletedPortfolios(ctx context.Context, before time.Time) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	records := make([]journalRecord, 0)
+
+	for _, item := range p.sorted(totalOrder(nil), nil) {
+		if item.DeletedAt != nil && item.DeletedAt.Before(before) {
+			records = append(records, journalRecord{Op: journalOpDelete, Id: item.Id, Audit: p.audit(ctx, models.AuditPurge, item, nil)})
 		}
 	}
 
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	if err := p.commit(journalRecord{Op: journalOpBatch, Batch: records}); err != nil {
+		return 0, err
+	}
+
+	return len(records), nil
+}
+
+func (p *portfolioRepository) UpdatePortfolio(ctx context.Context, model *models.Portfolio) (*models.Portfolio, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	stored, ok := p.live(model.Id)
+
+	if !ok || VisibilityFromContext(ctx).Hides(&stored) {
+		return nil, ErrPortfolioNotFound
+	}
+
+	if model.Version != 0 && model.Version != stored.Version {
+		return nil, ErrPortfolioVersionMismatch
+	}
+
+	return p.update(ctx, &stored, model)
+}
+
+func (p *portfolioRepository) UpdatePortfolioFunc(ctx context.Context, id int, update func(*models.Portfolio) error) (*models.Portfolio, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	stored, ok := p.live(id)
+
+	if !ok || VisibilityFromContext(ctx).Hides(&stored) {
+		return nil, ErrPortfolioNotFound
+	}
+
+	model := stored
+
+	if err := update(&model); err != nil {
+		return nil, err
+	}
+
+	model.Id = id
+	model.Version = stored.Version
+
+	return p.update(ctx, &stored, &model)
+}
+
+// update replaces stored with model, keeping its CreatedAt and incrementing its version.
+// Must be called with the write lock held.
+func (p *portfolioRepository) update(ctx context.Context, stored, model *models.Portfolio) (*models.Portfolio, error) {
+	if p.nameTaken(model.Name, model.Id) {
+		return nil, ErrPortfolioAlreadyExists
+	}
+
 	now := time.Now()
-	model.UpdatedAt = &now
+	updated := *model
+	updated.CreatedAt = stored.CreatedAt
+	updated.UpdatedAt = &now
+	updated.Version = stored.Version + 1
 
-	p.storage[model.Id] = *model
+	if err := checkVisible(ctx, nil, &updated); err != nil {
+		return nil, err
+	}
 
-	return model, nil
+	if err := p.commit(journalRecord{Op: journalOpUpdate, Id: updated.Id, Portfolio: &updated, Audit: p.audit(ctx, models.AuditUpdate, stored, &updated)}); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (p *portfolioRepository) ApplyPortfolioOperations(ctx context.Context, operations []PortfolioOperation, atomic bool) ([]PortfolioOperationResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// The operations run against a copy of the storage, and their records are committed
+	// together once the batch is done, so a failed atomic batch leaves no trace.
+	records := make([]journalRecord, 0, len(operations))
+	staged := &portfolioRepository{
+		storage:      make(map[int]models.Portfolio, len(p.storage)),
+		history:      make(map[int][]models.AuditEntry),
+		counter:      p.counter,
+		auditCounter: p.auditCounter,
+		staged:       &records,
+	}
+
+	for id, portfolio := range p.storage {
+		staged.storage[id] = portfolio
+	}
+
+	results := make([]PortfolioOperationResult, len(operations))
+
+	for i, operation := range operations {
+		var portfolio *models.Portfolio
+		var err error
+
+		switch operation.Type {
+		case OperationCreate:
+			portfolio, err = staged.CreatePortfolio(ctx, operation.Create)
+		case OperationUpdate:
+			portfolio, err = staged.UpdatePortfolio(ctx, operation.Update)
+		case OperationDelete:
+			err = staged.DeletePortfolio(ctx, operation.Id, operation.Version)
+		default:
+			err = fmt.Errorf("unknown portfolio operation %q", operation.Type)
+		}
+
+		if err != nil && !isOperationError(err) {
+			return nil, err
+		}
+
+		results[i] = PortfolioOperationResult{Portfolio: portfolio, Err: err}
+
+		if err != nil && atomic {
+			rollBack(results, i)
+			return results, nil
+		}
+	}
+
+	if len(records) > 0 {
+		if err := p.commit(journalRecord{Op: journalOpBatch, Batch: records}); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// Close flushes a final snapshot of a durable repository and releases its files.
+func (p *portfolioRepository) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.journal == nil {
+		return nil
+	}
+
+	if err := p.journal.snapshot(p.snapshot()); err != nil {
+		p.journal.close()
+		return err
+	}
+
+	return p.journal.close()
+}
+
+// commit makes record durable if the repository has a journal and then applies it.
+// Must be called with the write lock held.
+func (p *portfolioRepository) commit(record journalRecord) error {
+	if p.staged != nil {
+		*p.staged = append(*p.staged, record)
+	}
+
+	if p.journal == nil {
+		p.apply(record)
+		return nil
+	}
+
+	if err := p.journal.append(record); err != nil {
+		return err
+	}
+
+	p.apply(record)
+
+	if p.journal.shouldSnapshot() {
+		// The record is already durable in the log, so a failed snapshot
+		// is not an error for the caller and is retried on the next write.
+		p.journal.snapshot(p.snapshot())
+	}
+
+	return nil
+}
+
+// apply is idempotent, which lets the journal replay records
+// that are already contained in the snapshot.
+func (p *portfolioRepository) apply(record journalRecord) {
+	switch record.Op {
+	case journalOpCreate, journalOpUpdate:
+		p.storage[record.Id] = withVersion(*record.Portfolio)
+	case journalOpDelete:
+		delete(p.storage, record.Id)
+	case journalOpBatch:
+		for _, batched := range record.Batch {
+			p.apply(batched)
+		}
+	}
+
+	if record.Id > p.counter {
+		p.counter = record.Id
+	}
+
+	if record.Audit != nil {
+		p.applyAudit(*record.Audit)
+	}
+}
+
+// applyAudit adds the entry to the history unless it is already there.
+func (p *portfolioRepository) applyAudit(entry models.AuditEntry) {
+	history := p.history[entry.PortfolioId]
+
+	if len(history) == 0 || history[len(history)-1].Id < entry.Id {
+		p.history[entry.PortfolioId] = append(history, entry)
+	}
+
+	if entry.Id > p.auditCounter {
+		p.auditCounter = entry.Id
+	}
+}
+
+// audit returns the entry of a change with the next id. The id is taken right away,
+// so that the records of a batch get different ids before any of them is applied.
+// Must be called with the write lock held.
+func (p *portfolioRepository) audit(ctx context.Context, operation models.AuditOperation, before, after *models.Portfolio) *models.AuditEntry {
+	p.auditCounter++
+	entry := newAuditEntry(ctx, operation, before, after)
+	entry.Id = p.auditCounter
+
+	return entry
+}
+
+// hidesHistory reports whether any version of the portfolio was hidden from the caller,
+// which hides its history and versions as well. Must be called with the lock held.
+func (p *portfolioRepository) hidesHistory(ctx context.Context, id int) bool {
+	visibility := VisibilityFromContext(ctx)
+
+	for _, entry := range p.history[id] {
+		if visibility.Hides(entry.Before) || visibility.Hides(entry.After) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// live returns the stored portfolio unless it does not exist or is soft-deleted.
+// Must be called with the lock held.
+func (p *portfolioRepository) live(id int) (models.Portfolio, bool) {
+	stored, ok := p.storage[id]
+
+	return stored, ok && stored.DeletedAt == nil
+}
+
+// nameTaken reports whether a portfolio other than the one with the given id uses the name.
+// Soft-deleted portfolios do not count. Must be called with the lock held.
+func (p *portfolioRepository) nameTaken(name string, id int) bool {
+	for _, v := range p.storage {
+		if v.Name == name && v.Id != id && v.DeletedAt == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sorted returns copies of all portfolios, or of their versions at asOf when it is set,
+// ordered by keys, which must not allow ties so the result does not depend on map iteration order.
+// Must be called with the lock held.
+func (p *portfolioRepository) sorted(keys []PortfolioSortKey, asOf *time.Time) []*models.Portfolio {
+	items := make([]*models.Portfolio, 0, len(p.storage))
+
+	if asOf == nil {
+		for _, v := range p.storage {
+			model := v
+			items = append(items, &model)
+		}
+	} else {
+		for id := range p.history {
+			if model := p.versionAt(id, *asOf); model != nil {
+				items = append(items, model)
+			}
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return comparePortfolios(items[i], items[j], keys) < 0
+	})
+
+	return items
+}
+
+// versionAt returns a copy of the portfolio as it was after the last change made at or before
+// the given time, or nil if it did not exist then. Must be called with the lock held.
+func (p *portfolioRepository) versionAt(id int, at time.Time) *models.Portfolio {
+	var version *models.Portfolio
+
+	for _, entry := range p.history[id] {
+		if entry.At.After(at) {
+			break
+		}
+
+		version = entry.After
+	}
+
+	return copyPortfolio(version)
+}
+
+func (p *portfolioRepository) snapshot() *portfolioSnapshot {
+	snapshot := &portfolioSnapshot{
+		Counter:    p.counter,
+		Portfolios: make([]models.Portfolio, 0, len(p.storage)),
+	}
+
+	for _, v := range p.sorted(totalOrder(nil), nil) {
+		snapshot.Portfolios = append(snapshot.Portfolios, *v)
+	}
+
+	for _, history := range p.history {
+		snapshot.Audit = append(snapshot.Audit, history...)
+	}
+
+	sort.Slice(snapshot.Audit, func(i, j int) bool {
+		return snapshot.Audit[i].Id < snapshot.Audit[j].Id
+	})
+
+	return snapshot
+}
+
+func (p *portfolioRepository) restore(snapshot *portfolioSnapshot) {
+	p.counter = snapshot.Counter
+
+	for _, v := range snapshot.Portfolios {
+		p.storage[v.Id] = withVersion(v)
+	}
+
+	for _, entry := range snapshot.Audit {
+		p.applyAudit(entry)
+	}
+}
+
+// withVersion starts versions of portfolios written before versioning at 1.
+func withVersion(model models.Portfolio) models.Portfolio {
+	if model.Version == 0 {
+		model.Version = 1
+	}
+
+	return model
 }
 
 func NewPortfolioRepository() *portfolioRepository {
 	return &portfolioRepository{
 		storage: make(map[int]models.Portfolio),
+		history: make(map[int][]models.AuditEntry),
 	}
 }
+
+// NewDurablePortfolioRepository returns an in-memory repository that survives restarts.
+// Every mutation is appended to a write-ahead log in dir, and after snapshotEvery
+// mutations the storage is compacted into a snapshot file. On startup the snapshot
+// and the log are replayed to restore storage and the id counter.
+func NewDurablePortfolioRepository(dir string, snapshotEvery int) (*portfolioRepository, error) {
+	p := NewPortfolioRepository()
+
+	journal, err := openPortfolioJournal(dir, snapshotEvery, p.restore, p.apply)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.journal = journal
+
+	return p, nil
+}
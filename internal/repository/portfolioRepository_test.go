@@ -0,0 +1,41 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/repotest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.PortfolioRepository {
+		return repository.NewPortfolioRepository()
+	})
+}
+
+func TestDurablePortfolioRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.PortfolioRepository {
+		portfolioRepository, err := repository.NewDurablePortfolioRepository(t.TempDir(), 10)
+		require.NoError(t, err)
+		t.Cleanup(func() { portfolioRepository.Close() })
+
+		return portfolioRepository
+	})
+}
+
+func TestPortfolioStore(t *testing.T) {
+	repotest.RunIsolation(t, func(t *testing.T) repository.PortfolioStore {
+		return repository.NewPortfolioStore()
+	})
+}
+
+func TestDurablePortfolioStore(t *testing.T) {
+	repotest.RunIsolation(t, func(t *testing.T) repository.PortfolioStore {
+		portfolioStore, err := repository.NewDurablePortfolioStore(t.TempDir(), 10)
+		require.NoError(t, err)
+		t.Cleanup(func() { portfolioStore.Close() })
+
+		return portfolioStore
+	})
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+// PortfolioSortField names a sortable field of models.Portfolio the way it is named in JSON.
+type PortfolioSortField string
+
+const (
+	SortById         PortfolioSortField = "id"
+	SortByName       PortfolioSortField = "name"
+	SortByIsInternal PortfolioSortField = "isInternal"
+	SortByIsFinance  PortfolioSortField = "isFinance"
+	SortByIsActive   PortfolioSortField = "isActive"
+	SortByCreatedAt  PortfolioSortField = "createdAt"
+	SortByUpdatedAt  PortfolioSortField = "updatedAt"
+)
+
+var sortColumns = map[PortfolioSortField]string{
+	SortById:         "id",
+	SortByName:       "name",
+	SortByIsInternal: "is_internal",
+	SortByIsFinance:  "is_finance",
+	SortByIsActive:   "is_active",
+	SortByCreatedAt:  "created_at",
+	SortByUpdatedAt:  "updated_at",
+}
+
+func (f PortfolioSortField) IsValid() bool {
+	_, ok := sortColumns[f]
+	return ok
+}
+
+type PortfolioSortKey struct {
+	Field PortfolioSortField
+	Desc  bool
+}
+
+// totalOrder appends id to the sort keys unless they already contain it.
+// Ids are unique, so the result orders portfolios without ties, which keeps
+// keyset pagination from skipping or repeating items.
+func totalOrder(keys []PortfolioSortKey) []PortfolioSortKey {
+	for _, key := range keys {
+		if key.Field == SortById {
+			return keys
+		}
+	}
+
+	result := make([]PortfolioSortKey, 0, len(keys)+1)
+	result = append(result, keys...)
+
+	return append(result, PortfolioSortKey{Field: SortById})
+}
+
+// comparePortfolios returns a negative number when a goes before b in keys order,
+// a positive number when it goes after b and zero when all keys are equal.
+func comparePortfolios(a, b *models.Portfolio, keys []PortfolioSortKey) int {
+	for _, key := range keys {
+		result := compareField(a, b, key.Field)
+
+		if key.Desc {
+			result = -result
+		}
+
+		if result != 0 {
+			return result
+		}
+	}
+
+	return 0
+}
+
+func compareField(a, b *models.Portfolio, field PortfolioSortField) int {
+	switch field {
+	case SortById:
+		return compareInts(a.Id, b.Id)
+	case SortByName:
+		return strings.Compare(a.Name, b.Name)
+	case SortByIsInternal:
+		return compareBools(a.IsInternal, b.IsInternal)
+	case SortByIsFinance:
+		return compareBools(a.IsFinance, b.IsFinance)
+	case SortByIsActive:
+		return compareBools(a.IsActive, b.IsActive)
+	case SortByCreatedAt:
+		return compareTimes(*a.CreatedAt, *b.CreatedAt)
+	case SortByUpdatedAt:
+		return compareTimes(*a.UpdatedAt, *b.UpdatedAt)
+	}
+
+	return 0
+}
+
+// sortValue returns the value of field as it is bound to SQL statements.
+func sortValue(model *models.Portfolio, field PortfolioSortField) interface{} {
+	switch field {
+	case SortByName:
+		return model.Name
+	case SortByIsInternal:
+		return model.IsInternal
+	case SortByIsFinance:
+		return model.IsFinance
+	case SortByIsActive:
+		return model.IsActive
+	case SortByCreatedAt:
+		return model.CreatedAt.UTC()
+	case SortByUpdatedAt:
+		return model.UpdatedAt.UTC()
+	}
+
+	return model.Id
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case !a && b:
+		return -1
+	case a && !b:
+		return 1
+	}
+
+	return 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+
+	return 0
+}
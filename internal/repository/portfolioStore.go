@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+)
+
+// tenantsDirName is the directory of durable stores that holds a data directory for every tenant
+// except DefaultTenant, whose data stays in the root where it was before tenants were introduced.
+const tenantsDirName = "tenants"
+
+// memoryPortfolioStore keeps a separate in-memory repository for every tenant.
+type memoryPortfolioStore struct {
+	mu      sync.Mutex
+	tenants map[string]*portfolioRepository
+	// open creates the repository of a tenant that has none yet.
+	open func(tenant string) (*portfolioRepository, error)
+}
+
+// ForTenant returns the repository of a tenant that has portfolios. Other tenants are only
+// created by their first portfolio, so that requests for any tenant id that only read or fail
+// with ErrPortfolioNotFound do not keep a repository, or a data directory of durable stores, for it.
+func (s *memoryPortfolioStore) ForTenant(tenant string) (PortfolioRepository, error) {
+	if !ValidTenant(tenant) {
+		return nil, ErrInvalidTenant
+	}
+
+	if portfolioRepository := s.existing(tenant); portfolioRepository != nil {
+		return portfolioRepository, nil
+	}
+
+	return &unopenedTenantRepository{store: s, tenant: tenant}, nil
+}
+
+// existing returns the repository of the tenant, or nil if it has not been created.
+func (s *memoryPortfolioStore) existing(tenant string) *portfolioRepository {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tenants[tenant]
+}
+
+// create returns the repository of the tenant and creates it if it has none yet.
+func (s *memoryPortfolioStore) create(tenant string) (*portfolioRepository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if portfolioRepository, ok := s.tenants[tenant]; ok {
+		return portfolioRepository, nil
+	}
+
+	portfolioRepository, err := s.open(tenant)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.tenants[tenant] = portfolioRepository
+
+	return portfolioRepository, nil
+}
+
+func (s *memoryPortfolioStore) Tenants(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tenants := make([]string, 0, len(s.tenants))
+
+	for tenant := range s.tenants {
+		tenants = append(tenants, tenant)
+	}
+
+	sort.Strings(tenants)
+
+	return tenants, nil
+}
+
+// Close closes the repositories of all tenants and returns the first error.
+func (s *memoryPortfolioStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var first error
+
+	for _, portfolioRepository := range s.tenants {
+		if err := portfolioRepository.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+func NewPortfolioStore() *memoryPortfolioStore {
+	return &memoryPortfolioStore{
+		tenants: make(map[string]*portfolioRepository),
+		open: func(string) (*portfolioRepository, error) {
+			return NewPortfolioRepository(), nil
+		},
+	}
+}
+
+// NewDurablePortfolioStore returns a store that keeps every tenant in a durable repository,
+// see NewDurablePortfolioRepository, with its own data directory under dir. The tenants
+// found in dir are opened right away, so that Tenants lists them after a restart.
+func NewDurablePortfolioStore(dir string, snapshotEvery int) (*memoryPortfolioStore, error) {
+	s := &memoryPortfolioStore{
+		tenants: make(map[string]*portfolioRepository),
+		open: func(tenant string) (*portfolioRepository, error) {
+			if tenant == DefaultTenant {
+				return NewDurablePortfolioRepository(dir, snapshotEvery)
+			}
+
+			return NewDurablePortfolioRepository(filepath.Join(dir, tenantsDirName, tenant), snapshotEvery)
+		},
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, tenantsDirName))
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	tenants := []string{DefaultTenant}
+
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != DefaultTenant && ValidTenant(entry.Name()) {
+			tenants = append(tenants, entry.Name())
+		}
+	}
+
+	for _, tenant := range tenants {
+		if _, err := s.create(tenant); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// unopenedTenantRepository is the repository of a tenant of a memoryPortfolioStore that has not been
+// created yet. It behaves like an empty repository until a created portfolio creates the tenant in the store.
+type unopenedTenantRepository struct {
+	store  *memoryPortfolioStore
+	tenant string
+}
+
+// reader returns the repository of the tenant if it was created in the meantime, or an empty one.
+func (r *unopenedTenantRepository) reader() *portfolioRepository {
+	if portfolioRepository := r.store.existing(r.tenant); portfolioRepository != nil {
+		return portfolioRepository
+	}
+
+	return NewPortfolioRepository()
+}
+
+func (r *unopenedTenantRepository) GetPortfolioHistory(ctx context.Context, id int, query *AuditQuery) (*AuditPage, error) {
+	return r.reader().GetPortfolioHistory(ctx, id, query)
+}
+
+func (r *unopenedTenantRepository) GetPortfolios(ctx context.Context) ([]*models.Portfolio, error) {
+	return r.reader().GetPortfolios(ctx)
+}
+
+func (r *unopenedTenantRepository) ListPortfolios(ctx context.Context, query *PortfolioQuery) (*PortfolioPage, error) {
+	return r.reader().ListPortfolios(ctx, query)
+}
+
+func (r *unopenedTenantRepository) StreamPortfolios(ctx context.Context, query *PortfolioQuery, yield func(*models.Portfolio) error) error {
+	return r.reader().StreamPortfolios(ctx, query, yield)
+}
+
+func (r *unopenedTenantRepository) GetPortfolioById(ctx context.Context, id int) (*models.Portfolio, error) {
+	return r.reader().GetPortfolioById(ctx, id)
+}
+
+func (r *unopenedTenantRepository) GetPortfolioAsOf(ctx context.Context, id int, at time.Time) (*models.Portfolio, error) {
+	return r.reader().GetPortfolioAsOf(ctx, id, at)
+}
+
+func (r *unopenedTenantRepository) GetPortfolioVersions(ctx context.Context, id int, from int, to int) ([]*models.Portfolio, error) {
+	return r.reader().GetPortfolioVersions(ctx, id, from, to)
+}
+
+func (r *unopenedTenantRepository) CreatePortfolio(ctx context.Context, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+	portfolioRepository, err := r.store.create(r.tenant)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return portfolioRepository.CreatePortfolio(ctx, body)
+}
+
+func (r *unopenedTenantRepository) UpdatePortfolio(ctx context.Context, portfolio *models.Portfolio) (*models.Portfolio, error) {
+	return r.reader().UpdatePortfolio(ctx, portfolio)
+}
+
+func (r *unopenedTenantRepository) UpdatePortfolioFunc(ctx context.Context, id int, update func(*models.Portfolio) error) (*models.Portfolio, error) {
+	return r.reader().UpdatePortfolioFunc(ctx, id, update)
+}
+
+func (r *unopenedTenantRepository) DeletePortfolio(ctx context.Context, id int, version int) error {
+	return r.reader().DeletePortfolio(ctx, id, version)
+}
+
+func (r *unopenedTenantRepository) RestorePortfolio(ctx context.Context, id int) (*models.Portfolio, error) {
+	return r.reader().RestorePortfolio(ctx, id)
+}
+
+func (r *unopenedTenantRepository) PurgePortfolio(ctx context.Context, id int, version int) error {
+	return r.reader().PurgePortfolio(ctx, id, version)
+}
+
+func (r *unopenedTenantRepository) PurgeDeletedPortfolios(ctx context.Context, before time.Time) (int, error) {
+	return r.reader().PurgeDeletedPortfolios(ctx, before)
+}
+
+// ApplyPortfolioOperations only creates the tenant for batches that create portfolios,
+// the other operations fail like those of the methods above.
+func (r *unopenedTenantRepository) ApplyPortfolioOperations(ctx context.Context, operations []PortfolioOperation, atomic bool) ([]PortfolioOperationResult, error) {
+	if !hasCreate(operations) {
+		return r.reader().ApplyPortfolioOperations(ctx, operations, atomic)
+	}
+
+	portfolioRepository, err := r.store.create(r.tenant)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return portfolioRepository.ApplyPortfolioOperations(ctx, operations, atomic)
+}
+
+func hasCreate(operations []PortfolioOperation) bool {
+	for _, operation := range operations {
+		if operation.Type == OperationCreate {
+			return true
+		}
+	}
+
+	return false
+}
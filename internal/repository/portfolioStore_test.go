@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/stretchr/testify/suite"
+)
+
+type DurablePortfolioStoreSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestDurablePortfolioStoreSuite(t *testing.T) {
+	suite.Run(t, new(DurablePortfolioStoreSuite))
+}
+
+func (suite *DurablePortfolioStoreSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *DurablePortfolioStoreSuite) open() *memoryPortfolioStore {
+	s, err := NewDurablePortfolioStore(suite.dir, 0)
+	suite.Require().NoError(err)
+
+	return s
+}
+
+func (suite *DurablePortfolioStoreSuite) create(s *memoryPortfolioStore, tenant string, name string) {
+	portfolioRepository, err := s.ForTenant(tenant)
+	suite.Require().NoError(err)
+
+	_, err = portfolioRepository.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: name})
+	suite.Require().NoError(err)
+}
+
+func (suite *DurablePortfolioStoreSuite) names(s *memoryPortfolioStore, tenant string) []string {
+	portfolioRepository, err := s.ForTenant(tenant)
+	suite.Require().NoError(err)
+
+	portfolios, err := portfolioRepository.GetPortfolios(ctx)
+	suite.Require().NoError(err)
+
+	names := make([]string, 0, len(portfolios))
+
+	for _, portfolio := range portfolios {
+		names = append(names, portfolio.Name)
+	}
+
+	return names
+}
+
+func (suite *DurablePortfolioStoreSuite) TestTenantsSurviveRestarts() {
+	r := suite.Require()
+	s := suite.open()
+	suite.create(s, DefaultTenant, "portfolio-1")
+	suite.create(s, "tenant-a", "portfolio-1")
+	suite.create(s, "tenant-a", "portfolio-2")
+	r.NoError(s.Close())
+
+	s = suite.open()
+	defer s.Close()
+
+	tenants, err := s.Tenants(ctx)
+	r.NoError(err)
+	r.Equal([]string{DefaultTenant, "tenant-a"}, tenants)
+	r.Equal([]string{"portfolio-1"}, suite.names(s, DefaultTenant))
+	r.Equal([]string{"portfolio-1", "portfolio-2"}, suite.names(s, "tenant-a"))
+	r.DirExists(filepath.Join(suite.dir, tenantsDirName, "tenant-a"))
+}
+
+func (suite *DurablePortfolioStoreSuite) TestReadsAndMissingIdsDoNotCreateTenantDirectories() {
+	r := suite.Require()
+	s := suite.open()
+	defer s.Close()
+
+	r.Empty(suite.names(s, "tenant-a"))
+	portfolioRepository, err := s.ForTenant("tenant-a")
+	r.NoError(err)
+	r.ErrorIs(portfolioRepository.DeletePortfolio(ctx, 1, 0), ErrPortfolioNotFound)
+
+	tenants, err := s.Tenants(ctx)
+	r.NoError(err)
+	r.NotContains(tenants, "tenant-a")
+	r.NoDirExists(filepath.Join(suite.dir, tenantsDirName, "tenant-a"))
+
+	suite.create(s, "tenant-a", "portfolio-1")
+	r.DirExists(filepath.Join(suite.dir, tenantsDirName, "tenant-a"))
+}
+
+func (suite *DurablePortfolioStoreSuite) TestDefaultTenantKeepsDataOfRepository() {
+	r := suite.Require()
+	p, err := NewDurablePortfolioRepository(suite.dir, 0)
+	r.NoError(err)
+	_, err = p.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-1"})
+	r.NoError(err)
+	r.NoError(p.Close())
+
+	s := suite.open()
+	defer s.Close()
+
+	r.Equal([]string{"portfolio-1"}, suite.names(s, DefaultTenant))
+}
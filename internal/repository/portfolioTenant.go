@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// DefaultTenant owns the portfolios of callers without a tenant, and those stored before tenants were introduced.
+const DefaultTenant = "default"
+
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ValidTenant reports whether the tenant id can be used: 1 to 64 lowercase letters,
+// digits, underscores and hyphens, starting with a letter or digit.
+func ValidTenant(tenant string) bool {
+	return tenantPattern.MatchString(tenant)
+}
+
+// PortfolioStore holds the portfolios of all tenants. Tenants are fully separated: every tenant
+// has its own names, history and versions, and its portfolios can only be reached through
+// the repository that ForTenant binds to it. Repositories never read the tenant from a context.
+// Ids are unique within a tenant and only found through its repository. Memory stores count
+// them per tenant, while SQL stores take them from one sequence shared by all tenants,
+// so the ids of a tenant may have gaps and the same id is never used by two tenants.
+//
+//go:generate mockery --name PortfolioStore
+type PortfolioStore interface {
+	// ForTenant returns the repository of the tenant's portfolios, or ErrInvalidTenant.
+	ForTenant(tenant string) (PortfolioRepository, error)
+	// Tenants lists the tenants that may have portfolios, for jobs that run for all of them.
+	Tenants(context.Context) ([]string, error)
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a context that carries the tenant of the request, which services
+// use to pick the repository of the tenant from a PortfolioStore.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the request, ok is false if it has none.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+
+	return tenant, ok
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+// ErrPortfolioNotVisible is returned for changes that would leave a portfolio hidden from the caller.
+var ErrPortfolioNotVisible = errors.New("portfolio would not be visible")
+
+// Visibility hides internal or finance portfolios from callers that may not see them.
+// The zero value hides nothing.
+type Visibility struct {
+	HideInternal bool
+	HideFinance  bool
+}
+
+type visibilityKey struct{}
+
+// ContextWithVisibility returns a context in which the repository acts as if hidden portfolios did
+// not exist. They are left out of lists and their counts, and reading or changing them fails with
+// ErrPortfolioNotFound. Changes that would hide a portfolio fail with ErrPortfolioNotVisible.
+func ContextWithVisibility(ctx context.Context, visibility Visibility) context.Context {
+	return context.WithValue(ctx, visibilityKey{}, visibility)
+}
+
+// VisibilityFromContext returns the visibility of portfolios to changes and reads made with the context.
+func VisibilityFromContext(ctx context.Context) Visibility {
+	visibility, _ := ctx.Value(visibilityKey{}).(Visibility)
+
+	return visibility
+}
+
+// Hides reports whether the portfolio is hidden, nil portfolios are not.
+func (v Visibility) Hides(portfolio *models.Portfolio) bool {
+	if portfolio == nil {
+		return false
+	}
+
+	return v.HideInternal && portfolio.IsInternal || v.HideFinance && portfolio.IsFinance
+}
+
+// checkVisible fails with ErrPortfolioNotFound if the caller cannot see the portfolio before a change,
+// and with ErrPortfolioNotVisible if they could not see it after the change.
+func checkVisible(ctx context.Context, before, after *models.Portfolio) error {
+	visibility := VisibilityFromContext(ctx)
+
+	if visibility.Hides(before) {
+		return ErrPortfolioNotFound
+	}
+
+	if visibility.Hides(after) {
+		return ErrPortfolioNotVisible
+	}
+
+	return nil
+}
+
+// visibilityConditions match the portfolios that are visible to the caller.
+func visibilityConditions(ctx context.Context, args *sqlArgs) []string {
+	visibility := VisibilityFromContext(ctx)
+	conditions := make([]string, 0, 2)
+
+	if visibility.HideInternal {
+		conditions = append(conditions, "is_internal = "+args.add(false))
+	}
+
+	if visibility.HideFinance {
+		conditions = append(conditions, "is_finance = "+args.add(false))
+	}
+
+	return conditions
+}
+
+// visibleHistoryCondition matches nothing if any version of the portfolio was hidden from the caller,
+// so that its history and versions do not show what it was. It is empty when nothing is hidden.
+func visibleHistoryCondition(ctx context.Context, tenant sqlTenant, args *sqlArgs, id int) string {
+	if VisibilityFromContext(ctx) == (Visibility{}) {
+		return ""
+	}
+
+	idCondition := "id = " + args.add(id) + " AND " + tenant.condition(args)
+
+	return ` AND NOT EXISTS (SELECT 1 FROM portfolio_versions WHERE ` + idCondition +
+		` AND NOT (` + strings.Join(visibilityConditions(ctx, args), " AND ") + `))`
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/lib/pq"
+)
+
+const postgresUniqueViolation = "23505"
+
+type postgresPortfolioRepository struct {
+	db     *sql.DB
+	tenant sqlTenant
+}
+
+func (p *postgresPortfolioRepository) GetPortfolios(ctx context.Context) ([]*models.Portfolio, error) {
+	return getSQLPortfolios(ctx, p.db, p.tenant)
+}
+
+func (p *postgresPortfolioRepository) ListPortfolios(ctx context.Context, query *PortfolioQuery) (*PortfolioPage, error) {
+	return listSQLPortfolios(ctx, p.db, query, p.tenant)
+}
+
+func (p *postgresPortfolioRepository) StreamPortfolios(ctx context.Context, query *PortfolioQuery, yield func(*models.Portfolio) error) error {
+	return streamSQLPortfolios(ctx, p.db, query, p.tenant, yield)
+}
+
+func (p *postgresPortfolioRepository) GetPortfolioHistory(ctx context.Context, id int, query *AuditQuery) (*AuditPage, error) {
+	return listSQLPortfolioHistory(ctx, p.db, p.tenant, id, query)
+}
+
+func (p *postgresPortfolioRepository) CreatePortfolio(ctx context.Context, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+	var created *models.Portfolio
+
+	err := inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		var err error
+		created, err = p.create(ctx, tx, body)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (p *postgresPortfolioRepository) create(ctx context.Context, tx *sql.Tx, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+	now := time.Now()
+
+	row := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO portfolios (name, is_internal, is_finance, is_active, created_at, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $5, $6)
+		RETURNING `+portfolioColumns,
+		body.Name, body.IsInternal, body.IsFinance, body.IsActive, now, p.tenant.id,
+	)
+
+	model, err := scanPortfolio(row)
+
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+
+	if err := recordChange(ctx, tx, p.tenant, models.AuditCreate, nil, model); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+func (p *postgresPortfolioRepository) GetPortfolioById(ctx context.Context, id int) (*models.Portfolio, error) {
+	return getSQLPortfolioById(ctx, p.db, p.tenant, id)
+}
+
+func (p *postgresPortfolioRepository) GetPortfolioAsOf(ctx context.Context, id int, asOf time.Time) (*models.Portfolio, error) {
+	return getSQLPortfolioAsOf(ctx, p.db, p.tenant, id, asOf)
+}
+
+func (p *postgresPortfolioRepository) GetPortfolioVersions(ctx context.Context, id int, from int, to int) ([]*models.Portfolio, error) {
+	return listSQLPortfolioVersions(ctx, p.db, p.tenant, id, from, to)
+}
+
+func (p *postgresPortfolioRepository) DeletePortfolio(ctx context.Context, id int, version int) error {
+	return inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		return p.delete(ctx, tx, id, version)
+	})
+}
+
+func (p *postgresPortfolioRepository) delete(ctx context.Context, tx *sql.Tx, id int, version int) error {
+	before, err := selectForChange(ctx, tx, p.tenant, id, false)
+
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && version != before.Version {
+		return ErrPortfolioVersionMismatch
+	}
+
+	row := tx.QueryRowContext(
+		ctx,
+		`UPDATE portfolios
+		SET deleted_at = $2, updated_at = $2, version = version + 1
+		WHERE id = $1 AND tenant_id = $3
+		RETURNING `+portfolioColumns,
+		id, time.Now(), p.tenant.id,
+	)
+
+	deleted, err := scanPortfolio(row)
+
+	if err != nil {
+		return mapPostgresError(err)
+	}
+
+	return recordChange(ctx, tx, p.tenant, models.AuditDelete, before, deleted)
+}
+
+func (p *postgresPortfolioRepository) RestorePortfolio(ctx context.Context, id int) (*models.Portfolio, error) {
+	var restored *models.Portfolio
+
+	err := inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		before, err := selectForChange(ctx, tx, p.tenant, id, true)
+
+		if err != nil {
+			return err
+		}
+
+		if before.DeletedAt == nil {
+			return ErrPortfolioNotDeleted
+		}
+
+		row := tx.QueryRowContext(
+			ctx,
+			`UPDATE portfolios
+			SET deleted_at = NULL, updated_at = $2, version = version + 1
+			WHERE id = $1 AND tenant_id = $3
+			RETURNING `+portfolioColumns,
+			id, time.Now(), p.tenant.id,
+		)
+
+		if restored, err = scanPortfolio(row); err != nil {
+			return mapPostgresError(err)
+		}
+
+		return recordChange(ctx, tx, p.tenant, models.AuditRestore, before, restored)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func (p *postgresPortfolioRepository) PurgePortfolio(ctx context.Context, id int, version int) error {
+	return inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		before, err := selectForChange(ctx, tx, p.tenant, id, true)
+
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && version != before.Version {
+			return ErrPortfolioVersionMismatch
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM portfolios WHERE id = $1 AND tenant_id = $2`, id, p.tenant.id); err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, p.tenant, models.AuditPurge, before, nil)
+	})
+}
+
+func (p *postgresPortfolioRepository) PurgeDeletedPortfolios(ctx context.Context, before time.Time) (int, error) {
+	return purgeSQLPortfolios(ctx, p.db, p.tenant, `DELETE FROM portfolios WHERE tenant_id = $1 AND deleted_at < $2 RETURNING `+portfolioColumns, p.tenant.id, before)
+}
+
+func (p *postgresPortfolioRepository) UpdatePortfolio(ctx context.Context, model *models.Portfolio) (*models.Portfolio, error) {
+	var updated *models.Portfolio
+
+	err := inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		var err error
+		updated, err = p.update(ctx, tx, model)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (p *postgresPortfolioRepository) ApplyPortfolioOperations(ctx context.Context, operations []PortfolioOperation, atomic bool) ([]PortfolioOperationResult, error) {
+	return applySQLOperations(ctx, p.db, p, operations, atomic)
+}
+
+func (p *postgresPortfolioRepository) UpdatePortfolioFunc(ctx context.Context, id int, update func(*models.Portfolio) error) (*models.Portfolio, error) {
+	var updated *models.Portfolio
+
+	err := inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		before, err := selectForChange(ctx, tx, p.tenant, id, false)
+
+		if err != nil {
+			return err
+		}
+
+		model := *before
+
+		if err := update(&model); err != nil {
+			return err
+		}
+
+		model.Id = id
+		updated, err = p.save(ctx, tx, before, &model)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (p *postgresPortfolioRepository) update(ctx context.Context, tx *sql.Tx, model *models.Portfolio) (*models.Portfolio, error) {
+	before, err := selectForChange(ctx, tx, p.tenant, model.Id, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if model.Version != 0 && model.Version != before.Version {
+		return nil, ErrPortfolioVersionMismatch
+	}
+
+	return p.save(ctx, tx, before, model)
+}
+
+// save writes the fields of model over before, which the transaction has locked.
+func (p *postgresPortfolioRepository) save(ctx context.Context, tx *sql.Tx, before *models.Portfolio, model *models.Portfolio) (*models.Portfolio, error) {
+	row := tx.QueryRowContext(
+		ctx,
+		`UPDATE portfolios
+		SET name = $2, is_internal = $3, is_finance = $4, is_active = $5, updated_at = $6, version = version + 1
+		WHERE id = $1 AND tenant_id = $7
+		RETURNING `+portfolioColumns,
+		before.Id, model.Name, model.IsInternal, model.IsFinance, model.IsActive, time.Now(), p.tenant.id,
+	)
+
+	updated, err := scanPortfolio(row)
+
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+
+	if err := recordChange(ctx, tx, p.tenant, models.AuditUpdate, before, updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func mapPostgresError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPortfolioNotFound
+	}
+
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == postgresUniqueViolation {
+		return ErrPortfolioAlreadyExists
+	}
+
+	return err
+}
+
+// NewPostgresPortfolioRepository returns a repository of the portfolios that belong to the tenant.
+func NewPostgresPortfolioRepository(db *sql.DB, tenant string) *postgresPortfolioRepository {
+	return &postgresPortfolioRepository{
+		db:     db,
+		tenant: sqlTenant{dialect: postgresDialect, id: tenant},
+	}
+}
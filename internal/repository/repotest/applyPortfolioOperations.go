@@ -0,0 +1,155 @@
+package repotest
+
+import (
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+func (suite *PortfolioRepositorySuite) createOperation(name string) repository.PortfolioOperation {
+	return repository.PortfolioOperation{
+		Type:   repository.OperationCreate,
+		Create: &requests.CreatePortfolioRequest{Name: name},
+	}
+}
+
+func (suite *PortfolioRepositorySuite) updateOperation(portfolio *models.Portfolio) repository.PortfolioOperation {
+	return repository.PortfolioOperation{Type: repository.OperationUpdate, Update: portfolio}
+}
+
+func (suite *PortfolioRepositorySuite) deleteOperation(id, version int) repository.PortfolioOperation {
+	return repository.PortfolioOperation{Type: repository.OperationDelete, Id: id, Version: version}
+}
+
+func (suite *PortfolioRepositorySuite) names() []string {
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	suite.Require().NoError(err)
+
+	names := make([]string, 0, len(portfolios))
+
+	for _, portfolio := range portfolios {
+		names = append(names, portfolio.Name)
+	}
+
+	return names
+}
+
+func (suite *PortfolioRepositorySuite) TestApplyPortfolioOperations() {
+	for _, atomic := range []bool{true, false} {
+		suite.SetupTest()
+		r := suite.Require()
+		first := suite.create("portfolio-1")
+		second := suite.create("portfolio-2")
+
+		renamed := *first
+		renamed.Name = "portfolio-renamed"
+		renamed.IsActive = true
+
+		results, err := suite.portfolioRepository.ApplyPortfolioOperations(suite.ctx, []repository.PortfolioOperation{
+			suite.createOperation("portfolio-3"),
+			suite.updateOperation(&renamed),
+			suite.deleteOperation(second.Id, second.Version),
+			suite.createOperation("portfolio-1"),
+		}, atomic)
+
+		r.NoError(err)
+		r.Len(results, 4)
+
+		for i, result := range results {
+			r.NoError(result.Err, "operation %d", i)
+		}
+
+		r.Equal("portfolio-3", results[0].Portfolio.Name)
+		r.Equal(1, results[0].Portfolio.Version)
+		r.Greater(results[0].Portfolio.Id, second.Id)
+		r.Equal("portfolio-renamed", results[1].Portfolio.Name)
+		r.True(results[1].Portfolio.IsActive)
+		r.Equal(first.Version+1, results[1].Portfolio.Version)
+		r.Nil(results[2].Portfolio)
+		r.Equal("portfolio-1", results[3].Portfolio.Name, "operations see the changes of earlier ones")
+
+		r.Equal([]string{"portfolio-renamed", "portfolio-3", "portfolio-1"}, suite.names())
+
+		found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, results[0].Portfolio.Id)
+		r.NoError(err)
+		suite.requireEqualPortfolio(results[0].Portfolio, found)
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestApplyPortfolioOperationsBestEffort() {
+	r := suite.Require()
+	first := suite.create("portfolio-1")
+	second := suite.create("portfolio-2")
+
+	missing := *first
+	missing.Id = second.Id + 100
+
+	results, err := suite.portfolioRepository.ApplyPortfolioOperations(suite.ctx, []repository.PortfolioOperation{
+		suite.createOperation("portfolio-3"),
+		suite.createOperation("portfolio-2"),
+		suite.updateOperation(&missing),
+		suite.deleteOperation(first.Id, first.Version+1),
+		suite.deleteOperation(second.Id, 0),
+		suite.createOperation("portfolio-3"),
+	}, false)
+
+	r.NoError(err)
+	r.Len(results, 6)
+	r.NoError(results[0].Err)
+	r.ErrorIs(results[1].Err, repository.ErrPortfolioAlreadyExists)
+	r.ErrorIs(results[2].Err, repository.ErrPortfolioNotFound)
+	r.ErrorIs(results[3].Err, repository.ErrPortfolioVersionMismatch)
+	r.NoError(results[4].Err)
+	r.ErrorIs(results[5].Err, repository.ErrPortfolioAlreadyExists)
+
+	for _, i := range []int{1, 2, 3, 5} {
+		r.Nil(results[i].Portfolio)
+	}
+
+	r.Equal([]string{"portfolio-1", "portfolio-3"}, suite.names())
+}
+
+func (suite *PortfolioRepositorySuite) TestApplyPortfolioOperationsAtomicRollback() {
+	r := suite.Require()
+	first := suite.create("portfolio-1")
+	second := suite.create("portfolio-2")
+
+	renamed := *first
+	renamed.Name = "portfolio-renamed"
+
+	results, err := suite.portfolioRepository.ApplyPortfolioOperations(suite.ctx, []repository.PortfolioOperation{
+		suite.createOperation("portfolio-3"),
+		suite.updateOperation(&renamed),
+		suite.deleteOperation(second.Id, second.Version+1),
+		suite.createOperation("portfolio-4"),
+	}, true)
+
+	r.NoError(err)
+	r.Len(results, 4)
+	r.ErrorIs(results[0].Err, repository.ErrPortfolioBatchRolledBack)
+	r.ErrorIs(results[1].Err, repository.ErrPortfolioBatchRolledBack)
+	r.ErrorIs(results[2].Err, repository.ErrPortfolioVersionMismatch)
+	r.ErrorIs(results[3].Err, repository.ErrPortfolioBatchRolledBack)
+
+	for _, result := range results {
+		r.Nil(result.Portfolio)
+	}
+
+	r.Equal([]string{"portfolio-1", "portfolio-2"}, suite.names())
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, first.Id)
+	r.NoError(err)
+	suite.requireEqualPortfolio(first, found)
+
+	created := suite.create("portfolio-3")
+	r.Greater(created.Id, second.Id)
+}
+
+func (suite *PortfolioRepositorySuite) TestApplyPortfolioOperationsEmpty() {
+	r := suite.Require()
+
+	results, err := suite.portfolioRepository.ApplyPortfolioOperations(suite.ctx, nil, true)
+
+	r.NoError(err)
+	r.Empty(results)
+}
@@ -0,0 +1,141 @@
+package repotest
+
+import (
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+// instant returns a time strictly between the changes made before and after it.
+func (suite *PortfolioRepositorySuite) instant() time.Time {
+	time.Sleep(2 * time.Millisecond)
+	at := time.Now()
+	time.Sleep(2 * time.Millisecond)
+
+	return at
+}
+
+func (suite *PortfolioRepositorySuite) TestGetPortfolioAsOf() {
+	r := suite.Require()
+	beforeCreate := suite.instant()
+	created := suite.create("portfolio-1")
+	afterCreate := suite.instant()
+
+	portfolio := *created
+	portfolio.Name = "portfolio-renamed"
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.NoError(err)
+	afterUpdate := suite.instant()
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, created.Id, 0))
+	afterDelete := suite.instant()
+
+	restored, err := suite.portfolioRepository.RestorePortfolio(suite.ctx, created.Id)
+	r.NoError(err)
+	afterRestore := suite.instant()
+
+	r.NoError(suite.portfolioRepository.PurgePortfolio(suite.ctx, created.Id, 0))
+	afterPurge := suite.instant()
+
+	for _, at := range []time.Time{beforeCreate, afterDelete, afterPurge} {
+		_, err := suite.portfolioRepository.GetPortfolioAsOf(suite.ctx, created.Id, at)
+		r.ErrorIs(err, repository.ErrPortfolioNotFound, at)
+	}
+
+	for _, tc := range []struct {
+		at       time.Time
+		expected *models.Portfolio
+	}{
+		{*created.UpdatedAt, created},
+		{afterCreate, created},
+		{afterUpdate, updated},
+		{afterRestore, restored},
+	} {
+		found, err := suite.portfolioRepository.GetPortfolioAsOf(suite.ctx, created.Id, tc.at)
+		r.NoError(err)
+		suite.requireEqualPortfolio(tc.expected, found)
+		r.Nil(found.DeletedAt)
+	}
+
+	_, err = suite.portfolioRepository.GetPortfolioAsOf(suite.ctx, created.Id+100, afterCreate)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosAsOf() {
+	r := suite.Require()
+	first := suite.create("portfolio-1")
+	second := suite.create("portfolio-2")
+	before := suite.instant()
+
+	portfolio := *first
+	portfolio.Name = "portfolio-3"
+	portfolio.IsActive = true
+	renamed, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.NoError(err)
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, second.Id, 0))
+	third := suite.create("portfolio-2")
+	after := time.Now()
+
+	page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 10, AsOf: &before, WithTotalCount: true})
+	r.NoError(err)
+	r.Len(page.Items, 2)
+	suite.requireEqualPortfolio(first, page.Items[0])
+	suite.requireEqualPortfolio(second, page.Items[1])
+	r.Equal(2, page.TotalCount)
+
+	page, err = suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 10, AsOf: &after})
+	r.NoError(err)
+	r.Len(page.Items, 2)
+	suite.requireEqualPortfolio(renamed, page.Items[0])
+	suite.requireEqualPortfolio(third, page.Items[1])
+
+	sortByName := []repository.PortfolioSortKey{{Field: repository.SortByName, Desc: true}}
+	page, err = suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 1, Sort: sortByName, AsOf: &before})
+	r.NoError(err)
+	r.Equal([]int{second.Id}, ids(page.Items))
+	r.True(page.HasMore)
+
+	page, err = suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 1, Sort: sortByName, AsOf: &before, After: page.Items[0]})
+	r.NoError(err)
+	r.Equal([]int{first.Id}, ids(page.Items))
+	r.False(page.HasMore)
+
+	isActive := true
+	active := repository.PortfolioQuery{Filter: repository.PortfolioFilter{IsActive: &isActive}}
+	active.AsOf = &before
+	r.Empty(suite.streamAll(active))
+	active.AsOf = &after
+	r.Equal([]int{first.Id}, ids(suite.streamAll(active)))
+
+	r.Equal([]int{first.Id, second.Id}, ids(suite.streamAll(repository.PortfolioQuery{AsOf: &before})))
+}
+
+func (suite *PortfolioRepositorySuite) TestAsOfBatches() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+	before := suite.instant()
+
+	results, err := suite.portfolioRepository.ApplyPortfolioOperations(suite.ctx, []repository.PortfolioOperation{
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-2"}},
+		{Type: repository.OperationUpdate, Update: &models.Portfolio{Id: created.Id, Name: "portfolio-renamed"}},
+	}, true)
+	r.NoError(err)
+	after := time.Now()
+
+	found, err := suite.portfolioRepository.GetPortfolioAsOf(suite.ctx, created.Id, before)
+	r.NoError(err)
+	r.Equal("portfolio-1", found.Name)
+
+	found, err = suite.portfolioRepository.GetPortfolioAsOf(suite.ctx, created.Id, after)
+	r.NoError(err)
+	r.Equal("portfolio-renamed", found.Name)
+
+	_, err = suite.portfolioRepository.GetPortfolioAsOf(suite.ctx, results[0].Portfolio.Id, before)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	found, err = suite.portfolioRepository.GetPortfolioAsOf(suite.ctx, results[0].Portfolio.Id, after)
+	r.NoError(err)
+	r.Equal("portfolio-2", found.Name)
+}
@@ -0,0 +1,43 @@
+package repotest
+
+func (suite *PortfolioRepositorySuite) TestGetPortfolioVersions() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	portfolio := *created
+	portfolio.Name = "portfolio-2"
+	second, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.NoError(err)
+
+	portfolio = *second
+	portfolio.IsActive = true
+	third, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.NoError(err)
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, created.Id, 0))
+	r.NoError(suite.portfolioRepository.PurgePortfolio(suite.ctx, created.Id, 0))
+
+	versions, err := suite.portfolioRepository.GetPortfolioVersions(suite.ctx, created.Id, 1, 10)
+	r.NoError(err)
+	r.Len(versions, 4)
+	suite.requireEqualPortfolio(created, versions[0])
+	suite.requireEqualPortfolio(second, versions[1])
+	suite.requireEqualPortfolio(third, versions[2])
+	r.Equal(4, versions[3].Version)
+	r.NotNil(versions[3].DeletedAt)
+	r.Nil(versions[2].DeletedAt)
+
+	versions, err = suite.portfolioRepository.GetPortfolioVersions(suite.ctx, created.Id, 2, 3)
+	r.NoError(err)
+	r.Len(versions, 2)
+	r.Equal("portfolio-2", versions[0].Name)
+	r.True(versions[1].IsActive)
+
+	versions, err = suite.portfolioRepository.GetPortfolioVersions(suite.ctx, created.Id, 5, 10)
+	r.NoError(err)
+	r.Empty(versions)
+
+	versions, err = suite.portfolioRepository.GetPortfolioVersions(suite.ctx, created.Id+100, 1, 10)
+	r.NoError(err)
+	r.Empty(versions)
+}
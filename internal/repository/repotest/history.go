@@ -0,0 +1,279 @@
+package repotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+func (suite *PortfolioRepositorySuite) TestHistoryRecordsEveryChange() {
+	r := suite.Require()
+	ctx := repository.ContextWithActor(suite.ctx, "alice")
+
+	created, err := suite.portfolioRepository.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-1"})
+	r.NoError(err)
+
+	portfolio := *created
+	portfolio.Name = "portfolio-renamed"
+	portfolio.IsActive = true
+	updated, err := suite.portfolioRepository.UpdatePortfolio(ctx, &portfolio)
+	r.NoError(err)
+
+	patched, err := suite.portfolioRepository.UpdatePortfolioFunc(ctx, created.Id, func(portfolio *models.Portfolio) error {
+		portfolio.IsFinance = true
+		return nil
+	})
+	r.NoError(err)
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(ctx, created.Id, 0))
+
+	restored, err := suite.portfolioRepository.RestorePortfolio(ctx, created.Id)
+	r.NoError(err)
+
+	r.NoError(suite.portfolioRepository.PurgePortfolio(suite.ctx, created.Id, 0))
+
+	entries := suite.history(created.Id)
+	r.Len(entries, 6)
+
+	operations := make([]models.AuditOperation, len(entries))
+
+	for i, entry := range entries {
+		operations[i] = entry.Operation
+		r.Equal(created.Id, entry.PortfolioId)
+
+		if i > 0 {
+			r.Greater(entry.Id, entries[i-1].Id)
+			r.False(entry.At.Before(entries[i-1].At))
+		}
+	}
+
+	r.Equal([]models.AuditOperation{
+		models.AuditCreate, models.AuditUpdate, models.AuditUpdate, models.AuditDelete, models.AuditRestore, models.AuditPurge,
+	}, operations)
+
+	r.Equal("alice", entries[0].Actor)
+	r.Equal(repository.SystemActor, entries[5].Actor)
+
+	r.Nil(entries[0].Before)
+	suite.requireEqualPortfolio(created, entries[0].After)
+	r.True(created.UpdatedAt.Equal(entries[0].At))
+
+	suite.requireEqualPortfolio(created, entries[1].Before)
+	suite.requireEqualPortfolio(updated, entries[1].After)
+	r.Equal(map[string][2]string{
+		"name":     {`"portfolio-1"`, `"portfolio-renamed"`},
+		"isActive": {`false`, `true`},
+	}, changes(entries[1]))
+
+	suite.requireEqualPortfolio(patched, entries[2].After)
+	r.Equal(map[string][2]string{"isFinance": {`false`, `true`}}, changes(entries[2]))
+
+	r.NotNil(entries[3].After.DeletedAt)
+	r.Equal([]string{"deletedAt"}, fields(entries[3]))
+	r.Equal("null", string(entries[3].Changes[0].From))
+
+	suite.requireEqualPortfolio(restored, entries[4].After)
+	r.Equal([]string{"deletedAt"}, fields(entries[4]))
+	r.Equal("null", string(entries[4].Changes[0].To))
+
+	suite.requireEqualPortfolio(restored, entries[5].Before)
+	r.Nil(entries[5].After)
+	r.Equal([]string{"name", "isInternal", "isFinance", "isActive"}, fields(entries[5]))
+}
+
+func (suite *PortfolioRepositorySuite) TestHistoryIsKeptPerPortfolio() {
+	r := suite.Require()
+	first := suite.create("portfolio-1")
+	second := suite.create("portfolio-2")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, second.Id, 0))
+
+	r.Len(suite.history(first.Id), 1)
+	r.Len(suite.history(second.Id), 2)
+	r.Empty(suite.history(second.Id + 100))
+}
+
+func (suite *PortfolioRepositorySuite) TestHistoryPages() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	for i := 2; i <= 5; i++ {
+		portfolio := *created
+		portfolio.Version = 0
+		portfolio.Name = fmt.Sprintf("portfolio-%d", i)
+
+		_, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+		r.NoError(err)
+	}
+
+	page, err := suite.portfolioRepository.GetPortfolioHistory(suite.ctx, created.Id, &repository.AuditQuery{Limit: 2})
+	r.NoError(err)
+	r.Len(page.Items, 2)
+	r.True(page.HasMore)
+
+	page, err = suite.portfolioRepository.GetPortfolioHistory(suite.ctx, created.Id, &repository.AuditQuery{Limit: 2, After: page.Items[1].Id})
+	r.NoError(err)
+	r.Len(page.Items, 2)
+	r.True(page.HasMore)
+	r.Equal(`"portfolio-3"`, string(page.Items[0].Changes[0].To))
+
+	page, err = suite.portfolioRepository.GetPortfolioHistory(suite.ctx, created.Id, &repository.AuditQuery{Limit: 2, After: page.Items[1].Id})
+	r.NoError(err)
+	r.Len(page.Items, 1)
+	r.False(page.HasMore)
+	r.Equal(`"portfolio-5"`, string(page.Items[0].Changes[0].To))
+}
+
+func (suite *PortfolioRepositorySuite) TestHistorySkipsFailedChanges() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+	suite.create("portfolio-2")
+
+	portfolio := *created
+	portfolio.Version = created.Version + 1
+	_, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.ErrorIs(err, repository.ErrPortfolioVersionMismatch)
+
+	portfolio.Version = 0
+	portfolio.Name = "portfolio-2"
+	_, err = suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.ErrorIs(err, repository.ErrPortfolioAlreadyExists)
+
+	r.ErrorIs(suite.portfolioRepository.DeletePortfolio(suite.ctx, created.Id, created.Version+1), repository.ErrPortfolioVersionMismatch)
+
+	_, err = suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, created.Id, func(*models.Portfolio) error {
+		return repository.ErrPortfolioVersionMismatch
+	})
+	r.ErrorIs(err, repository.ErrPortfolioVersionMismatch)
+
+	_, err = suite.portfolioRepository.ApplyPortfolioOperations(suite.ctx, []repository.PortfolioOperation{
+		{Type: repository.OperationUpdate, Update: &models.Portfolio{Id: created.Id, Name: "portfolio-renamed"}},
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-2"}},
+	}, true)
+	r.NoError(err)
+
+	entries := suite.history(created.Id)
+	r.Len(entries, 1)
+	r.Equal(models.AuditCreate, entries[0].Operation)
+}
+
+func (suite *PortfolioRepositorySuite) TestHistoryOfBatches() {
+	r := suite.Require()
+	ctx := repository.ContextWithActor(suite.ctx, "alice")
+	created := suite.create("portfolio-1")
+
+	results, err := suite.portfolioRepository.ApplyPortfolioOperations(ctx, []repository.PortfolioOperation{
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-2"}},
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-1"}},
+		{Type: repository.OperationDelete, Id: created.Id},
+	}, false)
+	r.NoError(err)
+	r.ErrorIs(results[1].Err, repository.ErrPortfolioAlreadyExists)
+
+	entries := suite.history(results[0].Portfolio.Id)
+	r.Len(entries, 1)
+	r.Equal(models.AuditCreate, entries[0].Operation)
+	r.Equal("alice", entries[0].Actor)
+
+	entries = suite.history(created.Id)
+	r.Len(entries, 2)
+	r.Equal(models.AuditDelete, entries[1].Operation)
+	r.Equal("alice", entries[1].Actor)
+}
+
+func (suite *PortfolioRepositorySuite) TestHistoryOfPurgedPortfolios() {
+	r := suite.Require()
+	purged := suite.create("portfolio-1")
+	kept := suite.create("portfolio-2")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, purged.Id, 0))
+
+	count, err := suite.portfolioRepository.PurgeDeletedPortfolios(suite.ctx, time.Now().Add(time.Second))
+	r.NoError(err)
+	r.Equal(1, count)
+
+	entries := suite.history(purged.Id)
+	r.Len(entries, 3)
+	r.Equal(models.AuditPurge, entries[2].Operation)
+	r.Equal(repository.SystemActor, entries[2].Actor)
+	r.NotNil(entries[2].Before.DeletedAt)
+	r.Nil(entries[2].After)
+
+	r.Len(suite.history(kept.Id), 1)
+}
+
+func (suite *PortfolioRepositorySuite) TestHistoryIsNotChangedByCallers() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+	created.Name = "mutated-by-caller"
+	*created.UpdatedAt = created.UpdatedAt.Add(time.Hour)
+
+	changed := *created
+	changed.Name = "portfolio-renamed"
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &changed)
+	r.NoError(err)
+	updated.Name = "mutated-by-caller"
+
+	entries := suite.history(created.Id)
+	entries[0].After.Name = "mutated-by-caller"
+	entries[1].Before.Name = "mutated-by-caller"
+
+	entries = suite.history(created.Id)
+	r.Len(entries, 2)
+	r.Equal("portfolio-1", entries[0].After.Name)
+	r.Equal("portfolio-1", entries[1].Before.Name)
+	r.Equal("portfolio-renamed", entries[1].After.Name)
+
+	versions, err := suite.portfolioRepository.GetPortfolioVersions(suite.ctx, created.Id, 1, 2)
+	r.NoError(err)
+	r.Len(versions, 2)
+	r.Equal("portfolio-1", versions[0].Name)
+	r.Equal("portfolio-renamed", versions[1].Name)
+	r.True(versions[0].UpdatedAt.Before(*versions[1].UpdatedAt))
+}
+
+// history reads the whole history of a portfolio.
+func (suite *PortfolioRepositorySuite) history(id int) []*models.AuditEntry {
+	page, err := suite.portfolioRepository.GetPortfolioHistory(suite.ctx, id, &repository.AuditQuery{Limit: 100})
+	suite.Require().NoError(err)
+	suite.Require().False(page.HasMore)
+
+	return page.Items
+}
+
+func fields(entry *models.AuditEntry) []string {
+	names := make([]string, len(entry.Changes))
+
+	for i, change := range entry.Changes {
+		names[i] = change.Field
+	}
+
+	return names
+}
+
+func changes(entry *models.AuditEntry) map[string][2]string {
+	values := map[string][2]string{}
+
+	for _, change := range entry.Changes {
+		values[change.Field] = [2]string{compact(change.From), compact(change.To)}
+	}
+
+	return values
+}
+
+// compact removes the whitespace that JSON columns may add to values.
+func compact(value json.RawMessage) string {
+	var data interface{}
+
+	if err := json.Unmarshal(value, &data); err != nil {
+		return string(value)
+	}
+
+	compacted, _ := json.Marshal(data)
+
+	return string(compacted)
+}
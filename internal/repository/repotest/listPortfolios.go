@@ -0,0 +1,285 @@
+package repotest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+// listAll pages through ListPortfolios until the last page and returns every item.
+func (suite *PortfolioRepositorySuite) listAll(query repository.PortfolioQuery) []*models.Portfolio {
+	r := suite.Require()
+	items := make([]*models.Portfolio, 0)
+
+	for {
+		page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &query)
+		r.NoError(err)
+		r.LessOrEqual(len(page.Items), query.Limit)
+
+		items = append(items, page.Items...)
+
+		if !page.HasMore {
+			return items
+		}
+
+		r.NotEmpty(page.Items, "HasMore is set on an empty page")
+		query.After = page.Items[len(page.Items)-1]
+	}
+}
+
+func ids(portfolios []*models.Portfolio) []int {
+	result := make([]int, 0, len(portfolios))
+
+	for _, portfolio := range portfolios {
+		result = append(result, portfolio.Id)
+	}
+
+	return result
+}
+
+func (suite *PortfolioRepositorySuite) TestGetPortfoliosOrderedById() {
+	r := suite.Require()
+	expected := make([]int, 0)
+
+	for i := 1; i <= 5; i++ {
+		expected = append(expected, suite.create(fmt.Sprintf("portfolio-%d", i)).Id)
+	}
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.Equal(expected, ids(portfolios))
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosEmpty() {
+	r := suite.Require()
+
+	page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 10, WithTotalCount: true})
+	r.NoError(err)
+	r.NotNil(page.Items)
+	r.Empty(page.Items)
+	r.False(page.HasMore)
+	r.Zero(page.TotalCount)
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosPages() {
+	r := suite.Require()
+	created := map[int]*models.Portfolio{}
+	expected := make([]int, 0)
+
+	for i := 1; i <= 7; i++ {
+		portfolio := suite.create(fmt.Sprintf("portfolio-%d", i))
+		created[portfolio.Id] = portfolio
+		expected = append(expected, portfolio.Id)
+	}
+
+	for _, limit := range []int{1, 2, 3, 7, 10} {
+		items := suite.listAll(repository.PortfolioQuery{Limit: limit})
+		r.Equal(expected, ids(items), "limit %d", limit)
+
+		for _, item := range items {
+			suite.requireEqualPortfolio(created[item.Id], item)
+		}
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosHasMore() {
+	r := suite.Require()
+
+	for i := 1; i <= 4; i++ {
+		suite.create(fmt.Sprintf("portfolio-%d", i))
+	}
+
+	page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 2})
+	r.NoError(err)
+	r.Len(page.Items, 2)
+	r.True(page.HasMore)
+
+	page, err = suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 2, After: page.Items[1]})
+	r.NoError(err)
+	r.Len(page.Items, 2)
+	r.False(page.HasMore, "a full last page must not report more items")
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosTotalCount() {
+	r := suite.Require()
+
+	for i := 1; i <= 5; i++ {
+		suite.create(fmt.Sprintf("portfolio-%d", i))
+	}
+
+	page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 2, WithTotalCount: true})
+	r.NoError(err)
+	r.Equal(5, page.TotalCount)
+
+	page, err = suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 2, After: page.Items[1], WithTotalCount: true})
+	r.NoError(err)
+	r.Equal(5, page.TotalCount, "TotalCount must not depend on the cursor")
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosCursorSurvivesChanges() {
+	r := suite.Require()
+	portfolios := make([]*models.Portfolio, 0)
+
+	for i := 1; i <= 4; i++ {
+		portfolios = append(portfolios, suite.create(fmt.Sprintf("portfolio-%d", i)))
+	}
+
+	page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 2})
+	r.NoError(err)
+	cursor := page.Items[1]
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, cursor.Id, 0))
+	added := suite.create("portfolio-5")
+
+	page, err = suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 10, After: cursor})
+	r.NoError(err)
+	r.Equal([]int{portfolios[2].Id, portfolios[3].Id, added.Id}, ids(page.Items))
+}
+
+func (suite *PortfolioRepositorySuite) createWithFlags(name string, isActive, isFinance, isInternal bool) *models.Portfolio {
+	portfolio, err := suite.portfolioRepository.CreatePortfolio(suite.ctx, &requests.CreatePortfolioRequest{
+		Name:       name,
+		IsActive:   isActive,
+		IsFinance:  isFinance,
+		IsInternal: isInternal,
+	})
+	suite.Require().NoError(err)
+
+	return portfolio
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosFlagFilters() {
+	r := suite.Require()
+	yes, no := true, false
+
+	activeFinance := suite.createWithFlags("active-finance", true, true, false)
+	activeInternal := suite.createWithFlags("active-internal", true, false, true)
+	inactiveFinance := suite.createWithFlags("inactive-finance", false, true, true)
+	inactive := suite.createWithFlags("inactive", false, false, false)
+
+	cases := []struct {
+		filter   repository.PortfolioFilter
+		expected []*models.Portfolio
+	}{
+		{repository.PortfolioFilter{}, []*models.Portfolio{activeFinance, activeInternal, inactiveFinance, inactive}},
+		{repository.PortfolioFilter{IsActive: &yes}, []*models.Portfolio{activeFinance, activeInternal}},
+		{repository.PortfolioFilter{IsActive: &no}, []*models.Portfolio{inactiveFinance, inactive}},
+		{repository.PortfolioFilter{IsFinance: &yes}, []*models.Portfolio{activeFinance, inactiveFinance}},
+		{repository.PortfolioFilter{IsInternal: &no}, []*models.Portfolio{activeFinance, inactive}},
+		{repository.PortfolioFilter{IsActive: &yes, IsFinance: &yes}, []*models.Portfolio{activeFinance}},
+		{repository.PortfolioFilter{IsActive: &no, IsFinance: &yes, IsInternal: &yes}, []*models.Portfolio{inactiveFinance}},
+		{repository.PortfolioFilter{IsActive: &yes, IsInternal: &yes, IsFinance: &yes}, []*models.Portfolio{}},
+	}
+
+	for i, c := range cases {
+		items := suite.listAll(repository.PortfolioQuery{Filter: c.filter, Limit: 1})
+		r.Equal(ids(c.expected), ids(items), "case %d", i)
+
+		page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Filter: c.filter, Limit: 1, WithTotalCount: true})
+		r.NoError(err)
+		r.Equal(len(c.expected), page.TotalCount, "case %d", i)
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosTimeFilters() {
+	r := suite.Require()
+	portfolios := make([]*models.Portfolio, 0)
+
+	for i := 1; i <= 4; i++ {
+		portfolios = append(portfolios, suite.create(fmt.Sprintf("portfolio-%d", i)))
+		time.Sleep(time.Millisecond)
+	}
+
+	updated := *portfolios[1]
+	updated.IsActive = true
+	second, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &updated)
+	r.NoError(err)
+
+	cases := []struct {
+		filter   repository.PortfolioFilter
+		expected []*models.Portfolio
+	}{
+		{repository.PortfolioFilter{CreatedFrom: portfolios[1].CreatedAt}, portfolios[1:]},
+		{repository.PortfolioFilter{CreatedTo: portfolios[2].CreatedAt}, portfolios[:2]},
+		{repository.PortfolioFilter{CreatedFrom: portfolios[1].CreatedAt, CreatedTo: portfolios[3].CreatedAt}, portfolios[1:3]},
+		{repository.PortfolioFilter{UpdatedFrom: second.UpdatedAt}, []*models.Portfolio{second}},
+		{repository.PortfolioFilter{UpdatedTo: second.UpdatedAt}, []*models.Portfolio{portfolios[0], portfolios[2], portfolios[3]}},
+		{repository.PortfolioFilter{CreatedTo: portfolios[2].CreatedAt, UpdatedTo: second.UpdatedAt}, portfolios[:1]},
+	}
+
+	for i, c := range cases {
+		items := suite.listAll(repository.PortfolioQuery{Filter: c.filter, Limit: 10})
+		r.Equal(ids(c.expected), ids(items), "case %d", i)
+	}
+
+	// Bounds in another time zone describe the same instants.
+	local := portfolios[2].CreatedAt.In(time.FixedZone("UTC+3", 3*60*60))
+	items := suite.listAll(repository.PortfolioQuery{Filter: repository.PortfolioFilter{CreatedFrom: &local}, Limit: 10})
+	r.Equal(ids(portfolios[2:]), ids(items))
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosNamePrefix() {
+	r := suite.Require()
+	names := []string{"alpha-1", "alpha-2", "Alpha-3", "al%pha", "al_pha", "a*b", "a?b", "a[b]", `a\b`}
+	created := map[string]int{}
+
+	for _, name := range names {
+		created[name] = suite.create(name).Id
+	}
+
+	cases := map[string][]string{
+		"alpha": {"alpha-1", "alpha-2"},
+		"Alpha": {"Alpha-3"},
+		"al%":   {"al%pha"},
+		"al_":   {"al_pha"},
+		"a*":    {"a*b"},
+		"a?":    {"a?b"},
+		"a[":    {"a[b]"},
+		`a\`:    {`a\b`},
+		"beta":  {},
+	}
+
+	for prefix, expected := range cases {
+		expectedIds := make([]int, 0)
+
+		for _, name := range expected {
+			expectedIds = append(expectedIds, created[name])
+		}
+
+		items := suite.listAll(repository.PortfolioQuery{Filter: repository.PortfolioFilter{NamePrefix: prefix}, Limit: 10})
+		r.Equal(expectedIds, ids(items), "prefix %q", prefix)
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosFilterWithCursor() {
+	r := suite.Require()
+	active := make([]int, 0)
+
+	for i := 1; i <= 9; i++ {
+		portfolio := suite.createWithFlags(fmt.Sprintf("portfolio-%d", i), i%3 != 0, false, false)
+
+		if portfolio.IsActive {
+			active = append(active, portfolio.Id)
+		}
+	}
+
+	yes := true
+	query := repository.PortfolioQuery{Filter: repository.PortfolioFilter{IsActive: &yes}, Limit: 4, WithTotalCount: true}
+
+	page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &query)
+	r.NoError(err)
+	r.Equal(active[:4], ids(page.Items))
+	r.True(page.HasMore)
+	r.Equal(len(active), page.TotalCount)
+
+	query.After = page.Items[3]
+
+	page, err = suite.portfolioRepository.ListPortfolios(suite.ctx, &query)
+	r.NoError(err)
+	r.Equal(active[4:], ids(page.Items))
+	r.False(page.HasMore)
+	r.Equal(len(active), page.TotalCount)
+}
@@ -0,0 +1,472 @@
+// Package repotest is a behavioural test suite that every
+// repository.PortfolioRepository implementation must pass.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/stretchr/testify/suite"
+)
+
+// Factory returns an empty repository. It is called once per test,
+// cleanup should be registered with t.Cleanup.
+type Factory func(t *testing.T) repository.PortfolioRepository
+
+type PortfolioRepositorySuite struct {
+	suite.Suite
+	factory             Factory
+	portfolioRepository repository.PortfolioRepository
+	ctx                 context.Context
+}
+
+// Run runs the whole suite against repositories built by factory.
+func Run(t *testing.T, factory Factory) {
+	suite.Run(t, &PortfolioRepositorySuite{factory: factory})
+}
+
+func (suite *PortfolioRepositorySuite) SetupTest() {
+	suite.portfolioRepository = suite.factory(suite.T())
+	suite.ctx = context.Background()
+}
+
+func (suite *PortfolioRepositorySuite) create(name string) *models.Portfolio {
+	portfolio, err := suite.portfolioRepository.CreatePortfolio(suite.ctx, &requests.CreatePortfolioRequest{Name: name})
+	suite.Require().NoError(err)
+
+	return portfolio
+}
+
+func (suite *PortfolioRepositorySuite) TestCreatePortfolio() {
+	r := suite.Require()
+	body := &requests.CreatePortfolioRequest{
+		Name:       "portfolio-1",
+		IsInternal: true,
+		IsFinance:  false,
+		IsActive:   true,
+	}
+
+	before := time.Now()
+	portfolio, err := suite.portfolioRepository.CreatePortfolio(suite.ctx, body)
+	after := time.Now()
+
+	r.NoError(err)
+	r.Positive(portfolio.Id)
+	r.Equal(body.Name, portfolio.Name)
+	r.Equal(body.IsInternal, portfolio.IsInternal)
+	r.Equal(body.IsFinance, portfolio.IsFinance)
+	r.Equal(body.IsActive, portfolio.IsActive)
+	r.NotNil(portfolio.CreatedAt)
+	r.NotNil(portfolio.UpdatedAt)
+	r.True(portfolio.CreatedAt.Equal(*portfolio.UpdatedAt))
+	suite.requireBetween(*portfolio.CreatedAt, before, after)
+}
+
+func (suite *PortfolioRepositorySuite) TestCreatePortfolioAssignsIncreasingIds() {
+	r := suite.Require()
+
+	first := suite.create("portfolio-1")
+	second := suite.create("portfolio-2")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, second.Id, 0))
+
+	third := suite.create("portfolio-3")
+
+	r.Greater(second.Id, first.Id)
+	r.Greater(third.Id, second.Id, "ids of deleted portfolios must not be reused")
+}
+
+func (suite *PortfolioRepositorySuite) TestCreatePortfolioDuplicateName() {
+	r := suite.Require()
+	suite.create("portfolio-1")
+
+	portfolio, err := suite.portfolioRepository.CreatePortfolio(suite.ctx, &requests.CreatePortfolioRequest{Name: "portfolio-1"})
+
+	r.ErrorIs(err, repository.ErrPortfolioAlreadyExists)
+	r.Nil(portfolio)
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.Len(portfolios, 1)
+}
+
+func (suite *PortfolioRepositorySuite) TestGetPortfolioById() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+
+	r.NoError(err)
+	suite.requireEqualPortfolio(created, found)
+}
+
+func (suite *PortfolioRepositorySuite) TestGetPortfolioByIdNotFound() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	portfolio, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id+1)
+
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+	r.Nil(portfolio)
+}
+
+func (suite *PortfolioRepositorySuite) TestGetPortfolios() {
+	r := suite.Require()
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.NotNil(portfolios)
+	r.Empty(portfolios)
+
+	created := map[int]*models.Portfolio{}
+
+	for i := 1; i <= 5; i++ {
+		portfolio := suite.create(fmt.Sprintf("portfolio-%d", i))
+		created[portfolio.Id] = portfolio
+	}
+
+	portfolios, err = suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.Len(portfolios, len(created))
+
+	for _, portfolio := range portfolios {
+		expected, ok := created[portfolio.Id]
+		r.True(ok, "unexpected portfolio %d", portfolio.Id)
+		suite.requireEqualPortfolio(expected, portfolio)
+		delete(created, portfolio.Id)
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestGetPortfoliosReturnsDistinctPointers() {
+	r := suite.Require()
+
+	for i := 1; i <= 3; i++ {
+		suite.create(fmt.Sprintf("portfolio-%d", i))
+	}
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+
+	seen := map[*models.Portfolio]bool{}
+	ids := map[int]bool{}
+
+	for _, portfolio := range portfolios {
+		r.False(seen[portfolio], "GetPortfolios returned the same pointer twice")
+		seen[portfolio] = true
+		ids[portfolio.Id] = true
+	}
+
+	r.Len(ids, len(portfolios))
+}
+
+func (suite *PortfolioRepositorySuite) TestReturnedPortfoliosAreCopies() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	created.Name = "changed-by-caller"
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	portfolios[0].Name = "changed-by-caller"
+	portfolios[0].IsActive = true
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	r.Equal("portfolio-1", found.Name)
+	r.False(found.IsActive)
+
+	found.Name = "changed-by-caller"
+
+	found, err = suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	r.Equal("portfolio-1", found.Name)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolio() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+	createdAt := *created.CreatedAt
+
+	time.Sleep(time.Millisecond)
+
+	portfolio := *created
+	portfolio.Name = "portfolio-renamed"
+	portfolio.IsActive = true
+	portfolio.IsFinance = true
+	portfolio.IsInternal = true
+
+	before := time.Now()
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	after := time.Now()
+
+	r.NoError(err)
+	r.Equal(created.Id, updated.Id)
+	r.Equal("portfolio-renamed", updated.Name)
+	r.True(updated.IsActive)
+	r.True(updated.IsFinance)
+	r.True(updated.IsInternal)
+	r.True(updated.CreatedAt.Equal(createdAt), "CreatedAt must not change on update")
+	suite.requireBetween(*updated.UpdatedAt, before, after)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	suite.requireEqualPortfolio(updated, found)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioKeepsCreatedAt() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	portfolio := *created
+	portfolio.CreatedAt = nil
+
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+
+	r.NoError(err)
+	r.NotNil(updated.CreatedAt)
+	r.True(updated.CreatedAt.Equal(*created.CreatedAt))
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioKeepsOwnName() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	portfolio := *created
+	portfolio.IsActive = true
+
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+
+	r.NoError(err)
+	r.Equal("portfolio-1", updated.Name)
+	r.True(updated.IsActive)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioDuplicateName() {
+	r := suite.Require()
+	suite.create("portfolio-1")
+	second := suite.create("portfolio-2")
+
+	portfolio := *second
+	portfolio.Name = "portfolio-1"
+
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+
+	r.ErrorIs(err, repository.ErrPortfolioAlreadyExists)
+	r.Nil(updated)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, second.Id)
+	r.NoError(err)
+	r.Equal("portfolio-2", found.Name)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioNotFound() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	portfolio := *created
+	portfolio.Id = created.Id + 1
+	portfolio.Name = "portfolio-2"
+
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+	r.Nil(updated)
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.Len(portfolios, 1)
+}
+
+func (suite *PortfolioRepositorySuite) TestDeletePortfolio() {
+	r := suite.Require()
+	first := suite.create("portfolio-1")
+	second := suite.create("portfolio-2")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, first.Id, 0))
+
+	_, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, first.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	r.ErrorIs(suite.portfolioRepository.DeletePortfolio(suite.ctx, first.Id, 0), repository.ErrPortfolioNotFound)
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.Len(portfolios, 1)
+	r.Equal(second.Id, portfolios[0].Id)
+}
+
+func (suite *PortfolioRepositorySuite) TestDeletedNameCanBeReused() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, created.Id, 0))
+
+	recreated := suite.create("portfolio-1")
+	r.NotEqual(created.Id, recreated.Id)
+}
+
+func (suite *PortfolioRepositorySuite) TestConcurrentAccess() {
+	r := suite.Require()
+	workers := 8
+	perWorker := 10
+	errs := make(chan error, workers*perWorker*4)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < perWorker; i++ {
+				name := fmt.Sprintf("portfolio-%d-%d", w, i)
+				portfolio, err := suite.portfolioRepository.CreatePortfolio(suite.ctx, &requests.CreatePortfolioRequest{Name: name})
+
+				if err != nil {
+					errs <- err
+					continue
+				}
+
+				if _, err := suite.portfolioRepository.GetPortfolios(suite.ctx); err != nil {
+					errs <- err
+				}
+
+				portfolio.IsActive = true
+
+				if _, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, portfolio); err != nil {
+					errs <- err
+				}
+
+				if _, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, portfolio.Id); err != nil {
+					errs <- err
+				}
+
+				if i%2 == 0 {
+					if err := suite.portfolioRepository.DeletePortfolio(suite.ctx, portfolio.Id, 0); err != nil {
+						errs <- err
+					}
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		r.NoError(err)
+	}
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.Len(portfolios, workers*perWorker/2)
+
+	ids := map[int]bool{}
+
+	for _, portfolio := range portfolios {
+		r.False(ids[portfolio.Id], "duplicate id %d", portfolio.Id)
+		r.True(portfolio.IsActive)
+		ids[portfolio.Id] = true
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestConcurrentCreateWithSameName() {
+	r := suite.Require()
+	workers := 8
+	errs := make(chan error, workers)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := suite.portfolioRepository.CreatePortfolio(suite.ctx, &requests.CreatePortfolioRequest{Name: "portfolio-1"})
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	created := 0
+
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+
+		r.ErrorIs(err, repository.ErrPortfolioAlreadyExists)
+	}
+
+	r.Equal(1, created)
+}
+
+func (suite *PortfolioRepositorySuite) TestCancelledContext() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	_, err := suite.portfolioRepository.GetPortfolios(ctx)
+	r.ErrorIs(err, context.Canceled)
+
+	_, err = suite.portfolioRepository.GetPortfolioById(ctx, created.Id)
+	r.ErrorIs(err, context.Canceled)
+
+	_, err = suite.portfolioRepository.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "portfolio-2"})
+	r.ErrorIs(err, context.Canceled)
+
+	portfolio := *created
+	portfolio.Name = "portfolio-renamed"
+	_, err = suite.portfolioRepository.UpdatePortfolio(ctx, &portfolio)
+	r.ErrorIs(err, context.Canceled)
+
+	_, err = suite.portfolioRepository.UpdatePortfolioFunc(ctx, created.Id, func(portfolio *models.Portfolio) error {
+		portfolio.Name = "portfolio-renamed"
+		return nil
+	})
+	r.ErrorIs(err, context.Canceled)
+
+	r.ErrorIs(suite.portfolioRepository.DeletePortfolio(ctx, created.Id, 0), context.Canceled)
+
+	_, err = suite.portfolioRepository.ApplyPortfolioOperations(ctx, []repository.PortfolioOperation{
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "portfolio-2"}},
+	}, true)
+	r.ErrorIs(err, context.Canceled)
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.Len(portfolios, 1)
+	r.Equal("portfolio-1", portfolios[0].Name)
+}
+
+func (suite *PortfolioRepositorySuite) requireEqualPortfolio(expected, actual *models.Portfolio) {
+	r := suite.Require()
+
+	r.Equal(expected.Id, actual.Id)
+	r.Equal(expected.Name, actual.Name)
+	r.Equal(expected.IsInternal, actual.IsInternal)
+	r.Equal(expected.IsFinance, actual.IsFinance)
+	r.Equal(expected.IsActive, actual.IsActive)
+	r.Equal(expected.Version, actual.Version)
+	r.True(expected.CreatedAt.Equal(*actual.CreatedAt), "CreatedAt: expected %s, got %s", expected.CreatedAt, actual.CreatedAt)
+	r.True(expected.UpdatedAt.Equal(*actual.UpdatedAt), "UpdatedAt: expected %s, got %s", expected.UpdatedAt, actual.UpdatedAt)
+}
+
+// requireBetween allows for backends that store timestamps with microsecond precision.
+func (suite *PortfolioRepositorySuite) requireBetween(actual, before, after time.Time) {
+	r := suite.Require()
+
+	r.False(actual.Before(before.Truncate(time.Microsecond)), "%s is before %s", actual, before)
+	r.False(actual.After(after), "%s is after %s", actual, after)
+}
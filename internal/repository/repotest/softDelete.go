@@ -0,0 +1,132 @@
+package repotest
+
+import (
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+func (suite *PortfolioRepositorySuite) TestDeletePortfolioHidesPortfolio() {
+	r := suite.Require()
+	deleted := suite.create("portfolio-1")
+	kept := suite.create("portfolio-2")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, deleted.Id, deleted.Version))
+
+	_, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, deleted.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(suite.ctx)
+	r.NoError(err)
+	r.Equal([]int{kept.Id}, ids(portfolios))
+
+	page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &repository.PortfolioQuery{Limit: 10, WithTotalCount: true})
+	r.NoError(err)
+	r.Equal([]int{kept.Id}, ids(page.Items))
+	r.Equal(1, page.TotalCount)
+	r.Equal([]int{kept.Id}, ids(suite.streamAll(repository.PortfolioQuery{})))
+
+	portfolio := *deleted
+	portfolio.Version = 0
+	_, err = suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	_, err = suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, deleted.Id, func(*models.Portfolio) error { return nil })
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	r.ErrorIs(suite.portfolioRepository.DeletePortfolio(suite.ctx, deleted.Id, 0), repository.ErrPortfolioNotFound)
+}
+
+func (suite *PortfolioRepositorySuite) TestRestorePortfolio() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, created.Id, 0))
+
+	restored, err := suite.portfolioRepository.RestorePortfolio(suite.ctx, created.Id)
+	r.NoError(err)
+	r.Equal(created.Id, restored.Id)
+	r.Equal(created.Name, restored.Name)
+	r.Equal(3, restored.Version)
+	r.Nil(restored.DeletedAt)
+	r.True(created.CreatedAt.Equal(*restored.CreatedAt))
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	r.Equal(3, found.Version)
+
+	_, err = suite.portfolioRepository.RestorePortfolio(suite.ctx, created.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotDeleted)
+
+	_, err = suite.portfolioRepository.RestorePortfolio(suite.ctx, created.Id+100)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+}
+
+func (suite *PortfolioRepositorySuite) TestDeletedNamesCanBeReused() {
+	r := suite.Require()
+	deleted := suite.create("portfolio-1")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, deleted.Id, 0))
+
+	reused := suite.create("portfolio-1")
+	r.NotEqual(deleted.Id, reused.Id)
+
+	_, err := suite.portfolioRepository.RestorePortfolio(suite.ctx, deleted.Id)
+	r.ErrorIs(err, repository.ErrPortfolioAlreadyExists)
+
+	other := suite.create("portfolio-2")
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, other.Id, 0))
+
+	renamed := *reused
+	renamed.Name = "portfolio-2"
+	_, err = suite.portfolioRepository.UpdatePortfolio(suite.ctx, &renamed)
+	r.NoError(err)
+
+	restored, err := suite.portfolioRepository.RestorePortfolio(suite.ctx, deleted.Id)
+	r.NoError(err)
+	r.Equal("portfolio-1", restored.Name)
+}
+
+func (suite *PortfolioRepositorySuite) TestPurgePortfolio() {
+	r := suite.Require()
+	live := suite.create("portfolio-1")
+	deleted := suite.create("portfolio-2")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, deleted.Id, 0))
+
+	r.ErrorIs(suite.portfolioRepository.PurgePortfolio(suite.ctx, live.Id, live.Version+1), repository.ErrPortfolioVersionMismatch)
+	r.NoError(suite.portfolioRepository.PurgePortfolio(suite.ctx, live.Id, live.Version))
+	r.NoError(suite.portfolioRepository.PurgePortfolio(suite.ctx, deleted.Id, 0))
+	r.ErrorIs(suite.portfolioRepository.PurgePortfolio(suite.ctx, deleted.Id, 0), repository.ErrPortfolioNotFound)
+
+	_, err := suite.portfolioRepository.RestorePortfolio(suite.ctx, deleted.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	_, err = suite.portfolioRepository.GetPortfolioById(suite.ctx, live.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+}
+
+func (suite *PortfolioRepositorySuite) TestPurgeDeletedPortfolios() {
+	r := suite.Require()
+	live := suite.create("portfolio-1")
+	first := suite.create("portfolio-2")
+	second := suite.create("portfolio-3")
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, first.Id, 0))
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, second.Id, 0))
+
+	purged, err := suite.portfolioRepository.PurgeDeletedPortfolios(suite.ctx, time.Now().Add(-time.Hour))
+	r.NoError(err)
+	r.Zero(purged)
+
+	purged, err = suite.portfolioRepository.PurgeDeletedPortfolios(suite.ctx, time.Now().Add(time.Second))
+	r.NoError(err)
+	r.Equal(2, purged)
+
+	_, err = suite.portfolioRepository.RestorePortfolio(suite.ctx, first.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	_, err = suite.portfolioRepository.GetPortfolioById(suite.ctx, live.Id)
+	r.NoError(err)
+}
@@ -0,0 +1,166 @@
+package repotest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+// createForSorting creates portfolios with repeated flag values so that
+// sorting by a single flag leaves ties to be broken by the next keys.
+func (suite *PortfolioRepositorySuite) createForSorting() []*models.Portfolio {
+	names := []string{"delta", "alpha", "charlie", "bravo", "echo", "alpha2", "foxtrot", "bravo2"}
+	portfolios := make([]*models.Portfolio, 0, len(names))
+
+	for i, name := range names {
+		portfolios = append(portfolios, suite.createWithFlags(name, i%2 == 0, i%3 == 0, i < 4))
+	}
+
+	// Touch some portfolios so that updatedAt order differs from id order.
+	for _, i := range []int{5, 1, 6} {
+		portfolio := *portfolios[i]
+		portfolio.IsActive = !portfolio.IsActive
+
+		updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+		suite.Require().NoError(err)
+
+		portfolios[i] = updated
+	}
+
+	return portfolios
+}
+
+// expectedOrder sorts portfolios independently of the repository implementation.
+func expectedOrder(portfolios []*models.Portfolio, keys []repository.PortfolioSortKey) []int {
+	sorted := append([]*models.Portfolio{}, portfolios...)
+	keys = append(append([]repository.PortfolioSortKey{}, keys...), repository.PortfolioSortKey{Field: repository.SortById})
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+
+		for _, key := range keys {
+			var less, greater bool
+
+			switch key.Field {
+			case repository.SortById:
+				less, greater = a.Id < b.Id, a.Id > b.Id
+			case repository.SortByName:
+				less, greater = a.Name < b.Name, a.Name > b.Name
+			case repository.SortByIsInternal:
+				less, greater = !a.IsInternal && b.IsInternal, a.IsInternal && !b.IsInternal
+			case repository.SortByIsFinance:
+				less, greater = !a.IsFinance && b.IsFinance, a.IsFinance && !b.IsFinance
+			case repository.SortByIsActive:
+				less, greater = !a.IsActive && b.IsActive, a.IsActive && !b.IsActive
+			case repository.SortByCreatedAt:
+				less, greater = a.CreatedAt.Before(*b.CreatedAt), a.CreatedAt.After(*b.CreatedAt)
+			case repository.SortByUpdatedAt:
+				less, greater = a.UpdatedAt.Before(*b.UpdatedAt), a.UpdatedAt.After(*b.UpdatedAt)
+			}
+
+			if key.Desc {
+				less, greater = greater, less
+			}
+
+			if less || greater {
+				return less
+			}
+		}
+
+		return false
+	})
+
+	return ids(sorted)
+}
+
+func sortKeys(spec string) []repository.PortfolioSortKey {
+	keys := make([]repository.PortfolioSortKey, 0)
+
+	for _, part := range strings.Split(spec, ",") {
+		keys = append(keys, repository.PortfolioSortKey{
+			Field: repository.PortfolioSortField(strings.TrimPrefix(part, "-")),
+			Desc:  strings.HasPrefix(part, "-"),
+		})
+	}
+
+	return keys
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosSort() {
+	r := suite.Require()
+	portfolios := suite.createForSorting()
+	specs := []string{
+		"id", "-id", "name", "-name", "createdAt", "-createdAt", "updatedAt", "-updatedAt",
+		"isActive", "-isFinance", "isInternal,-name", "-isActive,isFinance", "-updatedAt,name",
+		"isInternal,isActive,-isFinance", "-isActive,-id", "isFinance,id,name",
+	}
+
+	for _, spec := range specs {
+		keys := sortKeys(spec)
+		expected := expectedOrder(portfolios, keys)
+
+		for _, limit := range []int{1, 3, 8} {
+			items := suite.listAll(repository.PortfolioQuery{Sort: keys, Limit: limit})
+			r.Equal(expected, ids(items), "sort %s, limit %d", spec, limit)
+		}
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestListPortfoliosSortWithFilter() {
+	r := suite.Require()
+	portfolios := suite.createForSorting()
+	keys := sortKeys("-isFinance,name")
+	yes := true
+
+	active := make([]*models.Portfolio, 0)
+
+	for _, portfolio := range portfolios {
+		if portfolio.IsActive {
+			active = append(active, portfolio)
+		}
+	}
+
+	items := suite.listAll(repository.PortfolioQuery{Filter: repository.PortfolioFilter{IsActive: &yes}, Sort: keys, Limit: 2})
+	r.Equal(expectedOrder(active, keys), ids(items))
+}
+
+// TestListPortfoliosSortCursorSurvivesUpdates moves an already listed portfolio
+// to the end of the order while paging. Keyset pagination must neither repeat nor skip
+// the portfolios that did not move.
+func (suite *PortfolioRepositorySuite) TestListPortfoliosSortCursorSurvivesUpdates() {
+	r := suite.Require()
+
+	for i := 1; i <= 6; i++ {
+		suite.create(fmt.Sprintf("portfolio%d", i))
+	}
+
+	keys := sortKeys("updatedAt")
+	query := repository.PortfolioQuery{Sort: keys, Limit: 3}
+
+	page, err := suite.portfolioRepository.ListPortfolios(suite.ctx, &query)
+	r.NoError(err)
+	r.Len(page.Items, 3)
+	r.True(page.HasMore)
+
+	firstPage := page.Items
+	moved := *firstPage[0]
+	moved.IsActive = true
+	_, err = suite.portfolioRepository.UpdatePortfolio(suite.ctx, &moved)
+	r.NoError(err)
+
+	query.After = firstPage[len(firstPage)-1]
+	rest := suite.listAll(query)
+
+	seen := map[int]bool{}
+
+	for _, item := range append(firstPage, rest...) {
+		seen[item.Id] = true
+	}
+
+	r.Len(seen, 6, "every portfolio is listed")
+	r.Len(rest, 4, "only the moved portfolio is listed twice")
+	r.Equal(moved.Id, rest[len(rest)-1].Id)
+}
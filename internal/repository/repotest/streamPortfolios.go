@@ -0,0 +1,92 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+// streamAll collects everything StreamPortfolios yields for the query.
+func (suite *PortfolioRepositorySuite) streamAll(query repository.PortfolioQuery) []*models.Portfolio {
+	items := make([]*models.Portfolio, 0)
+
+	err := suite.portfolioRepository.StreamPortfolios(suite.ctx, &query, func(portfolio *models.Portfolio) error {
+		items = append(items, portfolio)
+		return nil
+	})
+
+	suite.Require().NoError(err)
+
+	return items
+}
+
+func (suite *PortfolioRepositorySuite) TestStreamPortfoliosMatchesList() {
+	r := suite.Require()
+	isActive := true
+
+	for i := 1; i <= 7; i++ {
+		portfolio := suite.create(fmt.Sprintf("portfolio-%d", i))
+
+		if i%2 == 0 {
+			portfolio.IsActive = true
+			_, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, portfolio)
+			r.NoError(err)
+		}
+	}
+
+	queries := []repository.PortfolioQuery{
+		{},
+		{Filter: repository.PortfolioFilter{IsActive: &isActive}},
+		{Sort: []repository.PortfolioSortKey{{Field: repository.SortByName, Desc: true}}},
+		{Filter: repository.PortfolioFilter{NamePrefix: "portfolio-1"}},
+	}
+
+	for _, query := range queries {
+		listQuery := query
+		listQuery.Limit = 3
+
+		r.Equal(ids(suite.listAll(listQuery)), ids(suite.streamAll(query)), "%+v", query)
+	}
+
+	all := suite.streamAll(repository.PortfolioQuery{})
+	r.Len(all, 7)
+	r.Equal(ids(all[3:]), ids(suite.streamAll(repository.PortfolioQuery{After: all[2]})))
+}
+
+func (suite *PortfolioRepositorySuite) TestStreamPortfoliosStops() {
+	r := suite.Require()
+	errStop := errors.New("stop")
+
+	for i := 1; i <= 5; i++ {
+		suite.create(fmt.Sprintf("portfolio-%d", i))
+	}
+
+	yielded := 0
+	err := suite.portfolioRepository.StreamPortfolios(suite.ctx, &repository.PortfolioQuery{}, func(*models.Portfolio) error {
+		yielded++
+
+		if yielded == 2 {
+			return errStop
+		}
+
+		return nil
+	})
+
+	r.ErrorIs(err, errStop)
+	r.Equal(2, yielded)
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	yielded = 0
+	err = suite.portfolioRepository.StreamPortfolios(ctx, &repository.PortfolioQuery{}, func(*models.Portfolio) error {
+		yielded++
+		cancel()
+
+		return nil
+	})
+
+	r.ErrorIs(err, context.Canceled)
+	r.Equal(1, yielded)
+}
@@ -0,0 +1,274 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/stretchr/testify/suite"
+)
+
+// StoreFactory returns an empty store. It is called once per test,
+// cleanup should be registered with t.Cleanup.
+type StoreFactory func(t *testing.T) repository.PortfolioStore
+
+// TenantIsolationSuite proves that the repositories of a store never reach the portfolios of other tenants.
+type TenantIsolationSuite struct {
+	suite.Suite
+	factory StoreFactory
+	store   repository.PortfolioStore
+	ctx     context.Context
+	// tenantA and tenantB are the repositories of two tenants of the store.
+	tenantA repository.PortfolioRepository
+	tenantB repository.PortfolioRepository
+}
+
+// RunIsolation runs the tenant isolation suite against stores built by factory.
+func RunIsolation(t *testing.T, factory StoreFactory) {
+	suite.Run(t, &TenantIsolationSuite{factory: factory})
+}
+
+func (suite *TenantIsolationSuite) SetupTest() {
+	suite.store = suite.factory(suite.T())
+	suite.ctx = context.Background()
+	suite.tenantA = suite.forTenant("tenant-a")
+	suite.tenantB = suite.forTenant("tenant-b")
+}
+
+func (suite *TenantIsolationSuite) forTenant(tenant string) repository.PortfolioRepository {
+	portfolioRepository, err := suite.store.ForTenant(tenant)
+	suite.Require().NoError(err)
+
+	return portfolioRepository
+}
+
+func (suite *TenantIsolationSuite) create(portfolioRepository repository.PortfolioRepository, name string) *models.Portfolio {
+	portfolio, err := portfolioRepository.CreatePortfolio(suite.ctx, &requests.CreatePortfolioRequest{Name: name})
+	suite.Require().NoError(err)
+
+	return portfolio
+}
+
+func (suite *TenantIsolationSuite) names(portfolioRepository repository.PortfolioRepository) []string {
+	portfolios, err := portfolioRepository.GetPortfolios(suite.ctx)
+	suite.Require().NoError(err)
+
+	names := make([]string, 0, len(portfolios))
+
+	for _, portfolio := range portfolios {
+		names = append(names, portfolio.Name)
+	}
+
+	return names
+}
+
+func (suite *TenantIsolationSuite) TestForTenantRejectsInvalidTenants() {
+	for _, tenant := range []string{"", "Tenant", "tenant a", "../tenant", "tenant/a", "-tenant", string(make([]byte, 65))} {
+		portfolioRepository, err := suite.store.ForTenant(tenant)
+
+		suite.ErrorIs(err, repository.ErrInvalidTenant, "tenant %q", tenant)
+		suite.Nil(portfolioRepository)
+	}
+}
+
+func (suite *TenantIsolationSuite) TestTenants() {
+	r := suite.Require()
+	suite.create(suite.tenantA, "portfolio-1")
+	suite.create(suite.tenantB, "portfolio-1")
+
+	tenants, err := suite.store.Tenants(suite.ctx)
+
+	r.NoError(err)
+	r.Contains(tenants, "tenant-a")
+	r.Contains(tenants, "tenant-b")
+}
+
+func (suite *TenantIsolationSuite) TestReadsAndMissingIdsDoNotCreateTenants() {
+	r := suite.Require()
+	suite.create(suite.tenantA, "portfolio-1")
+	unknown := suite.forTenant("tenant-unknown")
+
+	r.Empty(suite.names(unknown))
+	_, err := unknown.GetPortfolioById(suite.ctx, 1)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+	r.ErrorIs(unknown.DeletePortfolio(suite.ctx, 1, 0), repository.ErrPortfolioNotFound)
+	_, err = unknown.UpdatePortfolio(suite.ctx, &models.Portfolio{Id: 1, Name: "portfolio-1"})
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+	_, err = unknown.RestorePortfolio(suite.ctx, 1)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+	r.ErrorIs(unknown.PurgePortfolio(suite.ctx, 1, 0), repository.ErrPortfolioNotFound)
+
+	purged, err := unknown.PurgeDeletedPortfolios(suite.ctx, time.Now())
+	r.NoError(err)
+	r.Zero(purged)
+
+	results, err := unknown.ApplyPortfolioOperations(suite.ctx, []repository.PortfolioOperation{
+		{Type: repository.OperationDelete, Id: 1},
+	}, false)
+	r.NoError(err)
+	r.ErrorIs(results[0].Err, repository.ErrPortfolioNotFound)
+
+	tenants, err := suite.store.Tenants(suite.ctx)
+	r.NoError(err)
+	r.NotContains(tenants, "tenant-unknown")
+
+	suite.create(unknown, "portfolio-1")
+	r.Equal([]string{"portfolio-1"}, suite.names(suite.forTenant("tenant-unknown")))
+
+	tenants, err = suite.store.Tenants(suite.ctx)
+	r.NoError(err)
+	r.Contains(tenants, "tenant-unknown")
+}
+
+func (suite *TenantIsolationSuite) TestNamesAreUniquePerTenant() {
+	r := suite.Require()
+	first := suite.create(suite.tenantA, "portfolio-1")
+	second := suite.create(suite.tenantB, "portfolio-1")
+
+	_, err := suite.tenantA.CreatePortfolio(suite.ctx, &requests.CreatePortfolioRequest{Name: "portfolio-1"})
+	r.ErrorIs(err, repository.ErrPortfolioAlreadyExists)
+
+	r.NoError(suite.tenantA.DeletePortfolio(suite.ctx, first.Id, 0))
+	renamed := *second
+	renamed.Name = "portfolio-2"
+	_, err = suite.tenantB.UpdatePortfolio(suite.ctx, &renamed)
+	r.NoError(err)
+	suite.create(suite.tenantB, "portfolio-1")
+
+	restored, err := suite.tenantA.RestorePortfolio(suite.ctx, first.Id)
+	r.NoError(err, "a name taken in another tenant does not block a restore")
+	r.Equal("portfolio-1", restored.Name)
+
+	r.Equal([]string{"portfolio-1"}, suite.names(suite.tenantA))
+	r.Equal([]string{"portfolio-2", "portfolio-1"}, suite.names(suite.tenantB))
+}
+
+func (suite *TenantIsolationSuite) TestListsArePerTenant() {
+	r := suite.Require()
+	suite.create(suite.tenantA, "portfolio-a1")
+	suite.create(suite.tenantA, "portfolio-a2")
+	suite.create(suite.tenantB, "portfolio-b1")
+	asOf := time.Now().Add(time.Second)
+
+	for _, query := range []*repository.PortfolioQuery{
+		{Limit: 10, WithTotalCount: true},
+		{Limit: 10, WithTotalCount: true, AsOf: &asOf},
+	} {
+		page, err := suite.tenantA.ListPortfolios(suite.ctx, query)
+		r.NoError(err)
+		r.Equal(2, page.TotalCount)
+		r.Len(page.Items, 2)
+
+		page, err = suite.tenantB.ListPortfolios(suite.ctx, query)
+		r.NoError(err)
+		r.Equal(1, page.TotalCount)
+		r.Len(page.Items, 1)
+		r.Equal("portfolio-b1", page.Items[0].Name)
+
+		streamed := make([]string, 0)
+		err = suite.tenantB.StreamPortfolios(suite.ctx, query, func(portfolio *models.Portfolio) error {
+			streamed = append(streamed, portfolio.Name)
+			return nil
+		})
+		r.NoError(err)
+		r.Equal([]string{"portfolio-b1"}, streamed)
+	}
+
+	r.Equal([]string{"portfolio-a1", "portfolio-a2"}, suite.names(suite.tenantA))
+	r.Equal([]string{"portfolio-b1"}, suite.names(suite.tenantB))
+}
+
+// TestIdsArePerTenant runs every method of an empty tenant with the ids of another tenant's portfolios.
+func (suite *TenantIsolationSuite) TestIdsArePerTenant() {
+	r := suite.Require()
+	live := suite.create(suite.tenantA, "portfolio-1")
+	deleted := suite.create(suite.tenantA, "portfolio-2")
+	r.NoError(suite.tenantA.DeletePortfolio(suite.ctx, deleted.Id, 0))
+	now := time.Now().Add(time.Second)
+
+	for _, id := range []int{live.Id, deleted.Id} {
+		_, err := suite.tenantB.GetPortfolioById(suite.ctx, id)
+		r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+		_, err = suite.tenantB.GetPortfolioAsOf(suite.ctx, id, now)
+		r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+		versions, err := suite.tenantB.GetPortfolioVersions(suite.ctx, id, 1, 10)
+		r.NoError(err)
+		r.Empty(versions)
+
+		history, err := suite.tenantB.GetPortfolioHistory(suite.ctx, id, &repository.AuditQuery{Limit: 10})
+		r.NoError(err)
+		r.Empty(history.Items)
+
+		changed := models.Portfolio{Id: id, Name: "portfolio-taken"}
+		_, err = suite.tenantB.UpdatePortfolio(suite.ctx, &changed)
+		r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+		_, err = suite.tenantB.UpdatePortfolioFunc(suite.ctx, id, func(portfolio *models.Portfolio) error {
+			portfolio.Name = "portfolio-taken"
+			return nil
+		})
+		r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+		r.ErrorIs(suite.tenantB.DeletePortfolio(suite.ctx, id, 0), repository.ErrPortfolioNotFound)
+
+		_, err = suite.tenantB.RestorePortfolio(suite.ctx, id)
+		r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+		r.ErrorIs(suite.tenantB.PurgePortfolio(suite.ctx, id, 0), repository.ErrPortfolioNotFound)
+
+		results, err := suite.tenantB.ApplyPortfolioOperations(suite.ctx, []repository.PortfolioOperation{
+			{Type: repository.OperationUpdate, Update: &changed},
+			{Type: repository.OperationDelete, Id: id},
+		}, false)
+		r.NoError(err)
+		r.ErrorIs(results[0].Err, repository.ErrPortfolioNotFound)
+		r.ErrorIs(results[1].Err, repository.ErrPortfolioNotFound)
+	}
+
+	found, err := suite.tenantA.GetPortfolioById(suite.ctx, live.Id)
+	r.NoError(err)
+	r.Equal("portfolio-1", found.Name)
+	r.Equal(live.Version, found.Version)
+
+	restored, err := suite.tenantA.RestorePortfolio(suite.ctx, deleted.Id)
+	r.NoError(err)
+	r.Equal("portfolio-2", restored.Name)
+
+	history, err := suite.tenantA.GetPortfolioHistory(suite.ctx, deleted.Id, &repository.AuditQuery{Limit: 10})
+	r.NoError(err)
+	r.Len(history.Items, 3, "create, delete and restore")
+}
+
+func (suite *TenantIsolationSuite) TestPurgeDeletedPortfoliosIsPerTenant() {
+	r := suite.Require()
+	inA := suite.create(suite.tenantA, "portfolio-1")
+	inB := suite.create(suite.tenantB, "portfolio-1")
+	r.NoError(suite.tenantA.DeletePortfolio(suite.ctx, inA.Id, 0))
+	r.NoError(suite.tenantB.DeletePortfolio(suite.ctx, inB.Id, 0))
+
+	purged, err := suite.tenantA.PurgeDeletedPortfolios(suite.ctx, time.Now().Add(time.Second))
+	r.NoError(err)
+	r.Equal(1, purged)
+
+	_, err = suite.tenantA.RestorePortfolio(suite.ctx, inA.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	restored, err := suite.tenantB.RestorePortfolio(suite.ctx, inB.Id)
+	r.NoError(err)
+	r.Equal("portfolio-1", restored.Name)
+}
+
+func (suite *TenantIsolationSuite) TestForTenantIsStable() {
+	r := suite.Require()
+	created := suite.create(suite.tenantA, "portfolio-1")
+
+	found, err := suite.forTenant("tenant-a").GetPortfolioById(suite.ctx, created.Id)
+
+	r.NoError(err)
+	r.Equal(created.Name, found.Name)
+}
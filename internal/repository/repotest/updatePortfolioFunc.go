@@ -0,0 +1,162 @@
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioFunc() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	before := time.Now()
+	updated, err := suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, created.Id, func(portfolio *models.Portfolio) error {
+		suite.requireEqualPortfolio(created, portfolio)
+
+		portfolio.Name = "portfolio-renamed"
+		portfolio.IsFinance = true
+
+		return nil
+	})
+	after := time.Now()
+
+	r.NoError(err)
+	r.Equal(created.Id, updated.Id)
+	r.Equal("portfolio-renamed", updated.Name)
+	r.True(updated.IsFinance)
+	r.True(created.CreatedAt.Equal(*updated.CreatedAt))
+	suite.requireBetween(*updated.UpdatedAt, before, after)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	suite.requireEqualPortfolio(updated, found)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioFuncError() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+	updateErr := errors.New("rejected")
+
+	updated, err := suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, created.Id, func(portfolio *models.Portfolio) error {
+		portfolio.Name = "portfolio-renamed"
+		return updateErr
+	})
+
+	r.ErrorIs(err, updateErr)
+	r.Nil(updated)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	suite.requireEqualPortfolio(created, found)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioFuncNotFound() {
+	r := suite.Require()
+	called := false
+
+	updated, err := suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, 100, func(portfolio *models.Portfolio) error {
+		called = true
+		return nil
+	})
+
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+	r.Nil(updated)
+	r.False(called)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioFuncDuplicateName() {
+	r := suite.Require()
+	suite.create("portfolio-1")
+	second := suite.create("portfolio-2")
+
+	_, err := suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, second.Id, func(portfolio *models.Portfolio) error {
+		portfolio.Name = "portfolio-1"
+		return nil
+	})
+
+	r.ErrorIs(err, repository.ErrPortfolioAlreadyExists)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, second.Id)
+	r.NoError(err)
+	suite.requireEqualPortfolio(second, found)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioFuncIgnoresReadOnlyFields() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+	other := suite.create("portfolio-2")
+
+	updated, err := suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, created.Id, func(portfolio *models.Portfolio) error {
+		createdAt := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		portfolio.Id = other.Id
+		portfolio.CreatedAt = &createdAt
+		portfolio.Version = 100
+		portfolio.IsActive = true
+
+		return nil
+	})
+
+	r.NoError(err)
+	r.Equal(created.Id, updated.Id)
+	r.True(created.CreatedAt.Equal(*updated.CreatedAt))
+	r.Equal(created.Version+1, updated.Version)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, other.Id)
+	r.NoError(err)
+	suite.requireEqualPortfolio(other, found)
+}
+
+// TestUpdatePortfolioFuncIsAtomic increments a counter kept in the name from many goroutines.
+// Lost updates would leave the counter below the number of increments.
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioFuncIsAtomic() {
+	r := suite.Require()
+	created := suite.create("counter-0")
+	workers := 10
+	increments := 5
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, workers*increments)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < increments; j++ {
+				_, err := suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, created.Id, func(portfolio *models.Portfolio) error {
+					counter, err := strconv.Atoi(strings.TrimPrefix(portfolio.Name, "counter-"))
+
+					if err != nil {
+						return err
+					}
+
+					portfolio.Name = fmt.Sprintf("counter-%d", counter+1)
+
+					return nil
+				})
+
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		r.NoError(err)
+	}
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	r.Equal(fmt.Sprintf("counter-%d", workers*increments), found.Name)
+	r.Equal(created.Version+workers*increments, found.Version)
+}
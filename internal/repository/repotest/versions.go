@@ -0,0 +1,143 @@
+package repotest
+
+import (
+	"sync"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+func (suite *PortfolioRepositorySuite) TestVersionIsIncrementedOnUpdate() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+	r.Equal(1, created.Version)
+
+	portfolio := *created
+	portfolio.IsActive = true
+
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.NoError(err)
+	r.Equal(2, updated.Version)
+
+	updated, err = suite.portfolioRepository.UpdatePortfolioFunc(suite.ctx, created.Id, func(portfolio *models.Portfolio) error {
+		portfolio.IsFinance = true
+		return nil
+	})
+	r.NoError(err)
+	r.Equal(3, updated.Version)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	r.Equal(3, found.Version)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioVersionMismatch() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	portfolio := *created
+	portfolio.Name = "portfolio-renamed"
+	_, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+	r.NoError(err)
+
+	stale := *created
+	stale.IsActive = true
+
+	updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &stale)
+
+	r.ErrorIs(err, repository.ErrPortfolioVersionMismatch)
+	r.Nil(updated)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	r.Equal("portfolio-renamed", found.Name)
+	r.False(found.IsActive)
+	r.Equal(2, found.Version)
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioWithoutVersion() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	for i := 0; i < 2; i++ {
+		portfolio := *created
+		portfolio.Version = 0
+		portfolio.IsActive = i == 0
+
+		updated, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+
+		r.NoError(err)
+		r.Equal(created.Version+i+1, updated.Version)
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestUpdatePortfolioVersionMismatchNotFound() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	portfolio := *created
+	portfolio.Id = created.Id + 1
+
+	_, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+}
+
+func (suite *PortfolioRepositorySuite) TestDeletePortfolioVersionMismatch() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+
+	r.ErrorIs(suite.portfolioRepository.DeletePortfolio(suite.ctx, created.Id, created.Version+1), repository.ErrPortfolioVersionMismatch)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	suite.requireEqualPortfolio(created, found)
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, created.Id, created.Version))
+	r.ErrorIs(suite.portfolioRepository.DeletePortfolio(suite.ctx, created.Id, created.Version), repository.ErrPortfolioNotFound)
+}
+
+// TestConcurrentUpdatesWithSameVersion checks that of many writers
+// that read the same version exactly one succeeds.
+func (suite *PortfolioRepositorySuite) TestConcurrentUpdatesWithSameVersion() {
+	r := suite.Require()
+	created := suite.create("portfolio-1")
+	workers := 8
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			portfolio := *created
+			portfolio.IsActive = i%2 == 0
+
+			_, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &portfolio)
+			errs <- err
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	updated := 0
+
+	for err := range errs {
+		if err == nil {
+			updated++
+			continue
+		}
+
+		r.ErrorIs(err, repository.ErrPortfolioVersionMismatch)
+	}
+
+	r.Equal(1, updated)
+
+	found, err := suite.portfolioRepository.GetPortfolioById(suite.ctx, created.Id)
+	r.NoError(err)
+	r.Equal(created.Version+1, found.Version)
+}
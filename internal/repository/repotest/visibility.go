@@ -0,0 +1,240 @@
+package repotest
+
+import (
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+var (
+	hideInternal = repository.Visibility{HideInternal: true}
+	hideFinance  = repository.Visibility{HideFinance: true}
+)
+
+// createForVisibility creates a plain, an internal, a finance and an internal finance portfolio.
+func (suite *PortfolioRepositorySuite) createForVisibility() (plain, internal, finance, internalFinance *models.Portfolio) {
+	return suite.createWithFlags("plain", true, false, false),
+		suite.createWithFlags("internal", true, false, true),
+		suite.createWithFlags("finance", true, true, false),
+		suite.createWithFlags("internal-finance", true, true, true)
+}
+
+// listVisible pages through ListPortfolios with the visibility, one portfolio per page,
+// and checks that every page counts the same visible portfolios.
+func (suite *PortfolioRepositorySuite) listVisible(visibility repository.Visibility, query repository.PortfolioQuery) []int {
+	r := suite.Require()
+	ctx := repository.ContextWithVisibility(suite.ctx, visibility)
+	query.Limit = 1
+	query.WithTotalCount = true
+	items := make([]*models.Portfolio, 0)
+
+	for {
+		page, err := suite.portfolioRepository.ListPortfolios(ctx, &query)
+		r.NoError(err)
+
+		items = append(items, page.Items...)
+
+		if !page.HasMore {
+			r.Equal(len(items), page.TotalCount)
+			return ids(items)
+		}
+
+		query.After = page.Items[len(page.Items)-1]
+	}
+}
+
+func (suite *PortfolioRepositorySuite) TestVisibilityOfLists() {
+	r := suite.Require()
+	plain, internal, finance, internalFinance := suite.createForVisibility()
+	all := repository.PortfolioQuery{}
+
+	r.Equal(ids([]*models.Portfolio{plain, internal, finance, internalFinance}), suite.listVisible(repository.Visibility{}, all))
+	r.Equal(ids([]*models.Portfolio{plain, finance}), suite.listVisible(hideInternal, all))
+	r.Equal(ids([]*models.Portfolio{plain, internal}), suite.listVisible(hideFinance, all))
+	r.Equal(ids([]*models.Portfolio{plain}), suite.listVisible(repository.Visibility{HideInternal: true, HideFinance: true}, all))
+
+	yes := true
+	r.Empty(suite.listVisible(hideFinance, repository.PortfolioQuery{Filter: repository.PortfolioFilter{IsFinance: &yes}}))
+	r.Equal(ids([]*models.Portfolio{finance}), suite.listVisible(hideInternal, repository.PortfolioQuery{Filter: repository.PortfolioFilter{IsFinance: &yes}}))
+
+	ctx := repository.ContextWithVisibility(suite.ctx, hideFinance)
+	streamed := make([]*models.Portfolio, 0)
+	r.NoError(suite.portfolioRepository.StreamPortfolios(ctx, &repository.PortfolioQuery{}, func(portfolio *models.Portfolio) error {
+		streamed = append(streamed, portfolio)
+		return nil
+	}))
+	r.Equal(ids([]*models.Portfolio{plain, internal}), ids(streamed))
+
+	portfolios, err := suite.portfolioRepository.GetPortfolios(ctx)
+	r.NoError(err)
+	r.Equal(ids([]*models.Portfolio{plain, internal}), ids(portfolios))
+}
+
+func (suite *PortfolioRepositorySuite) TestVisibilityOfReads() {
+	r := suite.Require()
+	plain, internal, finance, _ := suite.createForVisibility()
+	at := suite.instant()
+	ctx := repository.ContextWithVisibility(suite.ctx, hideFinance)
+
+	for _, visible := range []*models.Portfolio{plain, internal} {
+		found, err := suite.portfolioRepository.GetPortfolioById(ctx, visible.Id)
+		r.NoError(err)
+		suite.requireEqualPortfolio(visible, found)
+
+		_, err = suite.portfolioRepository.GetPortfolioAsOf(ctx, visible.Id, at)
+		r.NoError(err)
+	}
+
+	_, err := suite.portfolioRepository.GetPortfolioById(ctx, finance.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	_, err = suite.portfolioRepository.GetPortfolioAsOf(ctx, finance.Id, at)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	r.Equal(ids([]*models.Portfolio{plain, internal}), suite.listVisible(hideFinance, repository.PortfolioQuery{AsOf: &at}))
+}
+
+func (suite *PortfolioRepositorySuite) TestVisibilityOfChanges() {
+	r := suite.Require()
+	plain, _, finance, _ := suite.createForVisibility()
+	ctx := repository.ContextWithVisibility(suite.ctx, hideFinance)
+
+	hidden := *finance
+	hidden.Name = "finance-renamed"
+	_, err := suite.portfolioRepository.UpdatePortfolio(ctx, &hidden)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	// Hidden portfolios are not found before their version is compared.
+	hidden.Version = finance.Version + 1
+	_, err = suite.portfolioRepository.UpdatePortfolio(ctx, &hidden)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	_, err = suite.portfolioRepository.UpdatePortfolioFunc(ctx, finance.Id, func(*models.Portfolio) error {
+		r.Fail("updated a hidden portfolio")
+		return nil
+	})
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	r.ErrorIs(suite.portfolioRepository.DeletePortfolio(ctx, finance.Id, 0), repository.ErrPortfolioNotFound)
+	r.ErrorIs(suite.portfolioRepository.PurgePortfolio(ctx, finance.Id, 0), repository.ErrPortfolioNotFound)
+
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, finance.Id, 0))
+	_, err = suite.portfolioRepository.RestorePortfolio(ctx, finance.Id)
+	r.ErrorIs(err, repository.ErrPortfolioNotFound)
+
+	_, err = suite.portfolioRepository.CreatePortfolio(ctx, &requests.CreatePortfolioRequest{Name: "finance-2", IsFinance: true})
+	r.ErrorIs(err, repository.ErrPortfolioNotVisible)
+
+	visible := *plain
+	visible.IsFinance = true
+	_, err = suite.portfolioRepository.UpdatePortfolio(ctx, &visible)
+	r.ErrorIs(err, repository.ErrPortfolioNotVisible)
+
+	_, err = suite.portfolioRepository.UpdatePortfolioFunc(ctx, plain.Id, func(portfolio *models.Portfolio) error {
+		portfolio.IsFinance = true
+		return nil
+	})
+	r.ErrorIs(err, repository.ErrPortfolioNotVisible)
+
+	stored, err := suite.portfolioRepository.GetPortfolioById(ctx, plain.Id)
+	r.NoError(err)
+	suite.requireEqualPortfolio(plain, stored)
+	r.Len(suite.history(plain.Id), 1)
+
+	names := suite.names()
+	r.NotContains(names, "finance-2")
+	r.NotContains(names, "finance-renamed")
+
+	// Changes that keep the portfolio visible go through.
+	visible.IsFinance = false
+	visible.IsInternal = true
+	_, err = suite.portfolioRepository.UpdatePortfolio(ctx, &visible)
+	r.NoError(err)
+}
+
+func (suite *PortfolioRepositorySuite) TestVisibilityOfBatches() {
+	r := suite.Require()
+	plain, _, finance, _ := suite.createForVisibility()
+	ctx := repository.ContextWithVisibility(suite.ctx, hideFinance)
+
+	flagged := *plain
+	flagged.IsFinance = true
+
+	results, err := suite.portfolioRepository.ApplyPortfolioOperations(ctx, []repository.PortfolioOperation{
+		suite.createOperation("portfolio-1"),
+		{Type: repository.OperationCreate, Create: &requests.CreatePortfolioRequest{Name: "finance-2", IsFinance: true}},
+		suite.updateOperation(&flagged),
+		suite.deleteOperation(finance.Id, 0),
+	}, false)
+	r.NoError(err)
+	r.NoError(results[0].Err)
+	r.ErrorIs(results[1].Err, repository.ErrPortfolioNotVisible)
+	r.ErrorIs(results[2].Err, repository.ErrPortfolioNotVisible)
+	r.ErrorIs(results[3].Err, repository.ErrPortfolioNotFound)
+
+	names := suite.names()
+	r.Contains(names, "portfolio-1")
+	r.NotContains(names, "finance-2")
+	r.Contains(names, "finance")
+
+	results, err = suite.portfolioRepository.ApplyPortfolioOperations(ctx, []repository.PortfolioOperation{
+		suite.createOperation("portfolio-2"),
+		suite.updateOperation(&flagged),
+	}, true)
+	r.NoError(err)
+	r.ErrorIs(results[0].Err, repository.ErrPortfolioBatchRolledBack)
+	r.ErrorIs(results[1].Err, repository.ErrPortfolioNotVisible)
+	r.NotContains(suite.names(), "portfolio-2")
+}
+
+func (suite *PortfolioRepositorySuite) TestVisibilityOfHistory() {
+	r := suite.Require()
+	plain, _, finance, _ := suite.createForVisibility()
+
+	// A portfolio that was once hidden keeps its history hidden, so it does not show what it was.
+	unflagged := *finance
+	unflagged.IsFinance = false
+	_, err := suite.portfolioRepository.UpdatePortfolio(suite.ctx, &unflagged)
+	r.NoError(err)
+
+	ctx := repository.ContextWithVisibility(suite.ctx, hideFinance)
+
+	for _, id := range []int{finance.Id, plain.Id} {
+		page, err := suite.portfolioRepository.GetPortfolioHistory(ctx, id, &repository.AuditQuery{Limit: 10})
+		r.NoError(err)
+
+		versions, err := suite.portfolioRepository.GetPortfolioVersions(ctx, id, 1, 2)
+		r.NoError(err)
+
+		if id == finance.Id {
+			r.Empty(page.Items)
+			r.Empty(versions)
+		} else {
+			r.Len(page.Items, 1)
+			r.Len(versions, 1)
+		}
+	}
+
+	r.Len(suite.history(finance.Id), 2)
+
+	// The portfolio itself is visible now.
+	_, err = suite.portfolioRepository.GetPortfolioById(ctx, finance.Id)
+	r.NoError(err)
+
+	versions, err := suite.portfolioRepository.GetPortfolioVersions(suite.ctx, finance.Id, 1, 2)
+	r.NoError(err)
+	r.Len(versions, 2)
+}
+
+// The retention job purges hidden portfolios too, it runs without a visibility.
+func (suite *PortfolioRepositorySuite) TestVisibilityOfPurges() {
+	r := suite.Require()
+	_, _, finance, _ := suite.createForVisibility()
+	r.NoError(suite.portfolioRepository.DeletePortfolio(suite.ctx, finance.Id, 0))
+
+	count, err := suite.portfolioRepository.PurgeDeletedPortfolios(suite.ctx, time.Now().Add(time.Second))
+	r.NoError(err)
+	r.Equal(1, count)
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+const auditColumns = `id, portfolio_id, actor, operation, at, before_state, after_state, changes`
+
+// selectForChange reads the portfolio that a transaction is about to change and locks its row where
+// the database supports it. Soft-deleted portfolios are only read when withDeleted is set.
+func selectForChange(ctx context.Context, tx *sql.Tx, tenant sqlTenant, id int, withDeleted bool) (*models.Portfolio, error) {
+	args := tenant.args()
+	statement := `SELECT ` + portfolioColumns + ` FROM portfolios WHERE id = ` + args.add(id) + ` AND ` + tenant.condition(args)
+
+	if !withDeleted {
+		statement += ` AND deleted_at IS NULL`
+	}
+
+	model, err := scanPortfolio(tx.QueryRowContext(ctx, statement+tenant.dialect.forUpdate, args.values...))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPortfolioNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVisible(ctx, model, nil); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// recordChange writes the audit entry and the portfolio version of a change in the transaction that made it.
+// Changes that would hide the portfolio from the caller fail, so the transaction is rolled back.
+func recordChange(ctx context.Context, tx *sql.Tx, tenant sqlTenant, operation models.AuditOperation, before, after *models.Portfolio) error {
+	if err := checkVisible(ctx, nil, after); err != nil {
+		return err
+	}
+
+	entry := newAuditEntry(ctx, operation, before, after)
+
+	if err := insertAuditEntry(ctx, tx, tenant, entry); err != nil {
+		return err
+	}
+
+	return insertPortfolioVersion(ctx, tx, tenant, entry)
+}
+
+func insertAuditEntry(ctx context.Context, tx *sql.Tx, tenant sqlTenant, entry *models.AuditEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+
+	if err != nil {
+		return err
+	}
+
+	args := tenant.args()
+	statement := `INSERT INTO portfolio_audit (tenant_id, portfolio_id, actor, operation, at, before_state, after_state, changes)
+		VALUES (` + args.add(tenant.id) + `, ` + args.add(entry.PortfolioId) + `, ` + args.add(entry.Actor) + `, ` + args.add(string(entry.Operation)) + `, ` +
+		args.add(entry.At) + `, ` + args.add(auditState(entry.Before)) + `, ` + args.add(auditState(entry.After)) + `, ` +
+		args.add(string(changes)) + `)`
+
+	_, err = tx.ExecContext(ctx, statement, args.values...)
+
+	return err
+}
+
+// auditState is the JSON of the portfolio, or NULL if it did not exist.
+func auditState(portfolio *models.Portfolio) interface{} {
+	if portfolio == nil {
+		return nil
+	}
+
+	data, _ := json.Marshal(portfolio)
+
+	return string(data)
+}
+
+// listSQLPortfolioHistory reads one page of audit entries. One extra row is fetched
+// to find out whether another page exists.
+func listSQLPortfolioHistory(ctx context.Context, db *sql.DB, tenant sqlTenant, id int, query *AuditQuery) (*AuditPage, error) {
+	args := tenant.args()
+	statement := `SELECT ` + auditColumns + ` FROM portfolio_audit
+		WHERE portfolio_id = ` + args.add(id) + ` AND ` + tenant.condition(args) + ` AND id > ` + args.add(query.After) +
+		visibleHistoryCondition(ctx, tenant, args, id) + ` ORDER BY id LIMIT ` + args.add(query.Limit+1)
+
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	page := &AuditPage{Items: make([]*models.AuditEntry, 0, query.Limit)}
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		page.Items = append(page.Items, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > query.Limit {
+		page.Items = page.Items[:query.Limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+func scanAuditEntry(row rowScanner) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	var operation string
+	var at time.Time
+	var before, after, changes []byte
+
+	if err := row.Scan(&entry.Id, &entry.PortfolioId, &entry.Actor, &operation, &at, &before, &after, &changes); err != nil {
+		return nil, err
+	}
+
+	entry.Operation = models.AuditOperation(operation)
+	entry.At = at.UTC()
+
+	for _, state := range []struct {
+		data []byte
+		into **models.Portfolio
+	}{{before, &entry.Before}, {after, &entry.After}} {
+		if state.data == nil {
+			continue
+		}
+
+		*state.into = &models.Portfolio{}
+
+		if err := json.Unmarshal(state.data, *state.into); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
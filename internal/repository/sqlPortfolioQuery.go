@@ -0,0 +1,468 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+)
+
+const portfolioColumns = `id, name, is_internal, is_finance, is_active, created_at, updated_at, version, deleted_at`
+
+// inTransaction runs fn in a transaction that is committed if fn succeeds and rolled back otherwise.
+func inTransaction(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sqlPortfolioWriter runs the writes of a SQL repository, with their audit entries, in a transaction.
+type sqlPortfolioWriter interface {
+	create(ctx context.Context, tx *sql.Tx, body *requests.CreatePortfolioRequest) (*models.Portfolio, error)
+	update(ctx context.Context, tx *sql.Tx, model *models.Portfolio) (*models.Portfolio, error)
+	delete(ctx context.Context, tx *sql.Tx, id int, version int) error
+}
+
+// errRollback makes inTransaction roll back a failed atomic batch.
+var errRollback = errors.New("roll back")
+
+// applySQLOperations runs a batch in one transaction. Without atomic every operation
+// runs in a savepoint, because a failed statement aborts the whole transaction on Postgres.
+func applySQLOperations(ctx context.Context, db *sql.DB, writer sqlPortfolioWriter, operations []PortfolioOperation, atomic bool) ([]PortfolioOperationResult, error) {
+	results := make([]PortfolioOperationResult, len(operations))
+
+	err := inTransaction(ctx, db, func(tx *sql.Tx) error {
+		for i, operation := range operations {
+			if !atomic {
+				if _, err := tx.ExecContext(ctx, `SAVEPOINT portfolio_operation`); err != nil {
+					return err
+				}
+			}
+
+			var portfolio *models.Portfolio
+			var err error
+
+			switch operation.Type {
+			case OperationCreate:
+				portfolio, err = writer.create(ctx, tx, operation.Create)
+			case OperationUpdate:
+				portfolio, err = writer.update(ctx, tx, operation.Update)
+			case OperationDelete:
+				err = writer.delete(ctx, tx, operation.Id, operation.Version)
+			default:
+				err = fmt.Errorf("unknown portfolio operation %q", operation.Type)
+			}
+
+			if err != nil && !isOperationError(err) {
+				return err
+			}
+
+			results[i] = PortfolioOperationResult{Portfolio: portfolio, Err: err}
+
+			if err != nil && atomic {
+				rollBack(results, i)
+				return errRollback
+			}
+
+			if atomic {
+				continue
+			}
+
+			statement := `RELEASE SAVEPOINT portfolio_operation`
+
+			if err != nil {
+				statement = `ROLLBACK TO SAVEPOINT portfolio_operation`
+			}
+
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errRollback) {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// sqlDialect holds the SQL that differs between database backends.
+type sqlDialect struct {
+	// placeholder renders the n-th (1-based) bind parameter.
+	placeholder func(n int) string
+	// namePrefix renders a case-sensitive, index-friendly name prefix match.
+	namePrefix func(args *sqlArgs, prefix string) string
+	// forUpdate locks the selected rows until the end of the transaction.
+	forUpdate string
+}
+
+var postgresDialect = sqlDialect{
+	placeholder: func(n int) string {
+		return fmt.Sprintf("$%d", n)
+	},
+	namePrefix: func(args *sqlArgs, prefix string) string {
+		return `name LIKE ` + args.add(likeEscaper.Replace(prefix)+"%") + ` ESCAPE '\'`
+	},
+	forUpdate: ` FOR UPDATE`,
+}
+
+var sqliteDialect = sqlDialect{
+	placeholder: func(int) string {
+		return "?"
+	},
+	// SQLite LIKE ignores case, GLOB does not and can still use the name index.
+	namePrefix: func(args *sqlArgs, prefix string) string {
+		return `name GLOB ` + args.add(globEscaper.Replace(prefix)+"*")
+	},
+	// Transactions take the write lock when they begin, see database.OpenSQLite.
+	forUpdate: ``,
+}
+
+var (
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	globEscaper = strings.NewReplacer(`*`, `[*]`, `?`, `[?]`, `[`, `[[]`)
+)
+
+type sqlArgs struct {
+	values  []interface{}
+	dialect sqlDialect
+}
+
+func (a *sqlArgs) add(value interface{}) string {
+	a.values = append(a.values, value)
+
+	return a.dialect.placeholder(len(a.values))
+}
+
+// sqlTenant is the tenant that a SQL repository is bound to, with the dialect of its database.
+// Every statement of the repository is built from it, so it only reads and writes rows of the tenant.
+type sqlTenant struct {
+	dialect sqlDialect
+	id      string
+}
+
+func (t sqlTenant) args() *sqlArgs {
+	return &sqlArgs{dialect: t.dialect}
+}
+
+// condition matches the rows of the tenant.
+func (t sqlTenant) condition(args *sqlArgs) string {
+	return "tenant_id = " + args.add(t.id)
+}
+
+// getSQLPortfolios reads all live portfolios that are visible to the caller ordered by id.
+func getSQLPortfolios(ctx context.Context, db *sql.DB, tenant sqlTenant) ([]*models.Portfolio, error) {
+	args := tenant.args()
+	conditions := append([]string{tenant.condition(args), "deleted_at IS NULL"}, visibilityConditions(ctx, args)...)
+
+	return queryPortfolios(ctx, db, `SELECT `+portfolioColumns+` FROM portfolios`+whereClause(conditions)+` ORDER BY id`, args.values...)
+}
+
+func getSQLPortfolioById(ctx context.Context, db *sql.DB, tenant sqlTenant, id int) (*models.Portfolio, error) {
+	args := tenant.args()
+	conditions := []string{"id = " + args.add(id), tenant.condition(args), "deleted_at IS NULL"}
+	conditions = append(conditions, visibilityConditions(ctx, args)...)
+	model, err := scanPortfolio(db.QueryRowContext(ctx, `SELECT `+portfolioColumns+` FROM portfolios`+whereClause(conditions), args.values...))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPortfolioNotFound
+	}
+
+	return model, err
+}
+
+// listSQLPortfolios runs a PortfolioQuery against the portfolios table, or the versions table when it is as of a time.
+// One extra row is fetched to find out whether another page exists.
+func listSQLPortfolios(ctx context.Context, db *sql.DB, query *PortfolioQuery, tenant sqlTenant) (*PortfolioPage, error) {
+	args := tenant.args()
+	table, conditions := portfolioSource(ctx, tenant, query, args)
+	page := &PortfolioPage{}
+
+	if query.WithTotalCount {
+		statement := `SELECT COUNT(*) FROM ` + table + whereClause(conditions)
+
+		if err := db.QueryRowContext(ctx, statement, args.values...).Scan(&page.TotalCount); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := totalOrder(query.Sort)
+
+	if query.After != nil {
+		conditions = append(conditions, keysetCondition(keys, query.After, args))
+	}
+
+	statement := `SELECT ` + portfolioColumns + ` FROM ` + table + whereClause(conditions) +
+		orderByClause(keys) + ` LIMIT ` + args.add(query.Limit+1)
+
+	items, err := queryPortfolios(ctx, db, statement, args.values...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page.Items = items
+
+	if len(page.Items) > query.Limit {
+		page.Items = page.Items[:query.Limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+// streamSQLPortfolios runs a PortfolioQuery without a limit and yields the rows as they are read.
+func streamSQLPortfolios(ctx context.Context, db *sql.DB, query *PortfolioQuery, tenant sqlTenant, yield func(*models.Portfolio) error) error {
+	args := tenant.args()
+	table, conditions := portfolioSource(ctx, tenant, query, args)
+	keys := totalOrder(query.Sort)
+
+	if query.After != nil {
+		conditions = append(conditions, keysetCondition(keys, query.After, args))
+	}
+
+	statement := `SELECT ` + portfolioColumns + ` FROM ` + table + whereClause(conditions) + orderByClause(keys)
+	rows, err := db.QueryContext(ctx, statement, args.values...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		// Drivers may keep returning rows they already read after the context is done.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		model, err := scanPortfolio(rows)
+
+		if err != nil {
+			return err
+		}
+
+		if err := yield(model); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Rows closed by a cancelled context end like the last row, without an error.
+	return ctx.Err()
+}
+
+func filterConditions(filter *PortfolioFilter, args *sqlArgs) []string {
+	conditions := []string{"deleted_at IS NULL"}
+
+	if filter.IsActive != nil {
+		conditions = append(conditions, "is_active = "+args.add(*filter.IsActive))
+	}
+
+	if filter.IsFinance != nil {
+		conditions = append(conditions, "is_finance = "+args.add(*filter.IsFinance))
+	}
+
+	if filter.IsInternal != nil {
+		conditions = append(conditions, "is_internal = "+args.add(*filter.IsInternal))
+	}
+
+	// Timestamps are compared in UTC because SQLite stores them as text.
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+args.add(filter.CreatedFrom.UTC()))
+	}
+
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+args.add(filter.CreatedTo.UTC()))
+	}
+
+	if filter.UpdatedFrom != nil {
+		conditions = append(conditions, "updated_at >= "+args.add(filter.UpdatedFrom.UTC()))
+	}
+
+	if filter.UpdatedTo != nil {
+		conditions = append(conditions, "updated_at < "+args.add(filter.UpdatedTo.UTC()))
+	}
+
+	if filter.NamePrefix != "" {
+		conditions = append(conditions, args.dialect.namePrefix(args, filter.NamePrefix))
+	}
+
+	return conditions
+}
+
+// keysetCondition matches rows that go after the given portfolio in keys order:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for descending keys.
+func keysetCondition(keys []PortfolioSortKey, after *models.Portfolio, args *sqlArgs) string {
+	alternatives := make([]string, 0, len(keys))
+
+	for i, key := range keys {
+		terms := make([]string, 0, i+1)
+
+		for _, equal := range keys[:i] {
+			terms = append(terms, sortColumns[equal.Field]+" = "+args.add(sortValue(after, equal.Field)))
+		}
+
+		operator := " > "
+
+		if key.Desc {
+			operator = " < "
+		}
+
+		terms = append(terms, sortColumns[key.Field]+operator+args.add(sortValue(after, key.Field)))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+func orderByClause(keys []PortfolioSortKey) string {
+	terms := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		direction := " ASC"
+
+		if key.Desc {
+			direction = " DESC"
+		}
+
+		terms = append(terms, sortColumns[key.Field]+direction)
+	}
+
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// purgeSQLPortfolios runs a DELETE ... RETURNING statement and records the purge of every deleted row.
+func purgeSQLPortfolios(ctx context.Context, db *sql.DB, tenant sqlTenant, statement string, args ...interface{}) (int, error) {
+	purged := 0
+
+	err := inTransaction(ctx, db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, statement, args...)
+
+		if err != nil {
+			return err
+		}
+
+		deleted := make([]*models.Portfolio, 0)
+
+		for rows.Next() {
+			model, err := scanPortfolio(rows)
+
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			deleted = append(deleted, model)
+		}
+
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, model := range deleted {
+			if err := recordChange(ctx, tx, tenant, models.AuditPurge, model, nil); err != nil {
+				return err
+			}
+		}
+
+		purged = len(deleted)
+
+		return nil
+	})
+
+	return purged, err
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+func queryPortfolios(ctx context.Context, db *sql.DB, statement string, args ...interface{}) ([]*models.Portfolio, error) {
+	rows, err := db.QueryContext(ctx, statement, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := make([]*models.Portfolio, 0)
+
+	for rows.Next() {
+		model, err := scanPortfolio(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, model)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func scanPortfolio(row rowScanner) (*models.Portfolio, error) {
+	var model models.Portfolio
+	var createdAt, updatedAt time.Time
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&model.Id,
+		&model.Name,
+		&model.IsInternal,
+		&model.IsFinance,
+		&model.IsActive,
+		&createdAt,
+		&updatedAt,
+		&model.Version,
+		&deletedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	model.CreatedAt = &createdAt
+	model.UpdatedAt = &updatedAt
+
+	if deletedAt.Valid {
+		model.DeletedAt = &deletedAt.Time
+	}
+
+	return &model, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// sqlPortfolioStore keeps the portfolios of all tenants in the same tables, every row
+// has the tenant_id of its tenant and the repositories only run statements built by sqlTenant.
+type sqlPortfolioStore struct {
+	db            *sql.DB
+	newRepository func(db *sql.DB, tenant string) PortfolioRepository
+}
+
+func (s *sqlPortfolioStore) ForTenant(tenant string) (PortfolioRepository, error) {
+	if !ValidTenant(tenant) {
+		return nil, ErrInvalidTenant
+	}
+
+	return s.newRepository(s.db, tenant), nil
+}
+
+func (s *sqlPortfolioStore) Tenants(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT tenant_id FROM portfolios ORDER BY tenant_id`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tenants := make([]string, 0)
+
+	for rows.Next() {
+		var tenant string
+
+		if err := rows.Scan(&tenant); err != nil {
+			return nil, err
+		}
+
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, rows.Err()
+}
+
+func NewSQLitePortfolioStore(db *sql.DB) *sqlPortfolioStore {
+	return &sqlPortfolioStore{
+		db: db,
+		newRepository: func(db *sql.DB, tenant string) PortfolioRepository {
+			return NewSQLitePortfolioRepository(db, tenant)
+		},
+	}
+}
+
+func NewPostgresPortfolioStore(db *sql.DB) *sqlPortfolioStore {
+	return &sqlPortfolioStore{
+		db: db,
+		newRepository: func(db *sql.DB, tenant string) PortfolioRepository {
+			return NewPostgresPortfolioRepository(db, tenant)
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+// insertPortfolioVersion ends the version of the portfolio that a change replaced and
+// starts the version it created, both at the time of the change.
+func insertPortfolioVersion(ctx context.Context, tx *sql.Tx, tenant sqlTenant, entry *models.AuditEntry) error {
+	if entry.Before != nil {
+		args := tenant.args()
+		statement := `UPDATE portfolio_versions SET valid_to = ` + args.add(entry.At) +
+			` WHERE id = ` + args.add(entry.PortfolioId) + ` AND ` + tenant.condition(args) + ` AND valid_to IS NULL`
+
+		if _, err := tx.ExecContext(ctx, statement, args.values...); err != nil {
+			return err
+		}
+	}
+
+	if entry.After == nil {
+		return nil
+	}
+
+	after := entry.After
+	args := tenant.args()
+	statement := `INSERT INTO portfolio_versions (` + portfolioColumns + `, valid_from, tenant_id) VALUES (` +
+		args.add(after.Id) + `, ` + args.add(after.Name) + `, ` + args.add(after.IsInternal) + `, ` +
+		args.add(after.IsFinance) + `, ` + args.add(after.IsActive) + `, ` + args.add(utc(after.CreatedAt)) + `, ` +
+		args.add(utc(after.UpdatedAt)) + `, ` + args.add(after.Version) + `, ` + args.add(utc(after.DeletedAt)) + `, ` +
+		args.add(entry.At) + `, ` + args.add(tenant.id) + `)`
+
+	_, err := tx.ExecContext(ctx, statement, args.values...)
+
+	return err
+}
+
+// portfolioSource returns the table that a query reads, the portfolios or their versions
+// when the query is as of a time, and the conditions that select the matching rows of the tenant.
+func portfolioSource(ctx context.Context, tenant sqlTenant, query *PortfolioQuery, args *sqlArgs) (string, []string) {
+	conditions := []string{tenant.condition(args)}
+	conditions = append(conditions, filterConditions(&query.Filter, args)...)
+	conditions = append(conditions, visibilityConditions(ctx, args)...)
+
+	if query.AsOf == nil {
+		return "portfolios", conditions
+	}
+
+	return "portfolio_versions", append(conditions, versionConditions(*query.AsOf, args)...)
+}
+
+// versionConditions select the versions that were current at the given time.
+func versionConditions(asOf time.Time, args *sqlArgs) []string {
+	// Timestamps are compared in UTC because SQLite stores them as text.
+	asOf = asOf.UTC()
+
+	return []string{"valid_from <= " + args.add(asOf), "(valid_to IS NULL OR valid_to > " + args.add(asOf) + ")"}
+}
+
+func getSQLPortfolioAsOf(ctx context.Context, db *sql.DB, tenant sqlTenant, id int, asOf time.Time) (*models.Portfolio, error) {
+	args := tenant.args()
+	conditions := append([]string{"id = " + args.add(id), tenant.condition(args), "deleted_at IS NULL"}, versionConditions(asOf, args)...)
+	conditions = append(conditions, visibilityConditions(ctx, args)...)
+	row := db.QueryRowContext(ctx, `SELECT `+portfolioColumns+` FROM portfolio_versions`+whereClause(conditions), args.values...)
+
+	model, err := scanPortfolio(row)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPortfolioNotFound
+	}
+
+	return model, err
+}
+
+func listSQLPortfolioVersions(ctx context.Context, db *sql.DB, tenant sqlTenant, id int, from int, to int) ([]*models.Portfolio, error) {
+	args := tenant.args()
+	statement := `SELECT ` + portfolioColumns + ` FROM portfolio_versions WHERE id = ` + args.add(id) +
+		` AND ` + tenant.condition(args) + ` AND version >= ` + args.add(from) + ` AND version <= ` + args.add(to) +
+		visibleHistoryCondition(ctx, tenant, args, id) + ` ORDER BY version`
+
+	return queryPortfolios(ctx, db, statement, args.values...)
+}
+
+func utc(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return t.UTC()
+}
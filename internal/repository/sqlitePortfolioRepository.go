@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type sqlitePortfolioRepository struct {
+	db     *sql.DB
+	tenant sqlTenant
+}
+
+func (p *sqlitePortfolioRepository) GetPortfolios(ctx context.Context) ([]*models.Portfolio, error) {
+	return getSQLPortfolios(ctx, p.db, p.tenant)
+}
+
+func (p *sqlitePortfolioRepository) ListPortfolios(ctx context.Context, query *PortfolioQuery) (*PortfolioPage, error) {
+	return listSQLPortfolios(ctx, p.db, query, p.tenant)
+}
+
+func (p *sqlitePortfolioRepository) StreamPortfolios(ctx context.Context, query *PortfolioQuery, yield func(*models.Portfolio) error) error {
+	return streamSQLPortfolios(ctx, p.db, query, p.tenant, yield)
+}
+
+func (p *sqlitePortfolioRepository) GetPortfolioHistory(ctx context.Context, id int, query *AuditQuery) (*AuditPage, error) {
+	return listSQLPortfolioHistory(ctx, p.db, p.tenant, id, query)
+}
+
+func (p *sqlitePortfolioRepository) CreatePortfolio(ctx context.Context, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+	var created *models.Portfolio
+
+	err := inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		var err error
+		created, err = p.create(ctx, tx, body)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (p *sqlitePortfolioRepository) create(ctx context.Context, tx *sql.Tx, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+	now := time.Now().UTC()
+
+	row := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO portfolios (tenant_id, name, is_internal, is_finance, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING `+portfolioColumns,
+		p.tenant.id, body.Name, body.IsInternal, body.IsFinance, body.IsActive, now, now,
+	)
+
+	model, err := scanPortfolio(row)
+
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+
+	if err := recordChange(ctx, tx, p.tenant, models.AuditCreate, nil, model); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+func (p *sqlitePortfolioRepository) GetPortfolioById(ctx context.Context, id int) (*models.Portfolio, error) {
+	return getSQLPortfolioById(ctx, p.db, p.tenant, id)
+}
+
+func (p *sqlitePortfolioRepository) GetPortfolioAsOf(ctx context.Context, id int, asOf time.Time) (*models.Portfolio, error) {
+	return getSQLPortfolioAsOf(ctx, p.db, p.tenant, id, asOf)
+}
+
+func (p *sqlitePortfolioRepository) GetPortfolioVersions(ctx context.Context, id int, from int, to int) ([]*models.Portfolio, error) {
+	return listSQLPortfolioVersions(ctx, p.db, p.tenant, id, from, to)
+}
+
+func (p *sqlitePortfolioRepository) DeletePortfolio(ctx context.Context, id int, version int) error {
+	return inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		return p.delete(ctx, tx, id, version)
+	})
+}
+
+func (p *sqlitePortfolioRepository) delete(ctx context.Context, tx *sql.Tx, id int, version int) error {
+	before, err := selectForChange(ctx, tx, p.tenant, id, false)
+
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && version != before.Version {
+		return ErrPortfolioVersionMismatch
+	}
+
+	now := time.Now().UTC()
+
+	row := tx.QueryRowContext(
+		ctx,
+		`UPDATE portfolios
+		SET deleted_at = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND tenant_id = ?
+		RETURNING `+portfolioColumns,
+		now, now, id, p.tenant.id,
+	)
+
+	deleted, err := scanPortfolio(row)
+
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+
+	return recordChange(ctx, tx, p.tenant, models.AuditDelete, before, deleted)
+}
+
+func (p *sqlitePortfolioRepository) RestorePortfolio(ctx context.Context, id int) (*models.Portfolio, error) {
+	var restored *models.Portfolio
+
+	err := inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		before, err := selectForChange(ctx, tx, p.tenant, id, true)
+
+		if err != nil {
+			return err
+		}
+
+		if before.DeletedAt == nil {
+			return ErrPortfolioNotDeleted
+		}
+
+		row := tx.QueryRowContext(
+			ctx,
+			`UPDATE portfolios
+			SET deleted_at = NULL, updated_at = ?, version = version + 1
+			WHERE id = ? AND tenant_id = ?
+			RETURNING `+portfolioColumns,
+			time.Now().UTC(), id, p.tenant.id,
+		)
+
+		if restored, err = scanPortfolio(row); err != nil {
+			return mapSQLiteError(err)
+		}
+
+		return recordChange(ctx, tx, p.tenant, models.AuditRestore, before, restored)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func (p *sqlitePortfolioRepository) PurgePortfolio(ctx context.Context, id int, version int) error {
+	return inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		before, err := selectForChange(ctx, tx, p.tenant, id, true)
+
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && version != before.Version {
+			return ErrPortfolioVersionMismatch
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM portfolios WHERE id = ? AND tenant_id = ?`, id, p.tenant.id); err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, p.tenant, models.AuditPurge, before, nil)
+	})
+}
+
+func (p *sqlitePortfolioRepository) PurgeDeletedPortfolios(ctx context.Context, before time.Time) (int, error) {
+	// Timestamps are compared in UTC because SQLite stores them as text.
+	return purgeSQLPortfolios(ctx, p.db, p.tenant, `DELETE FROM portfolios WHERE tenant_id = ? AND deleted_at < ? RETURNING `+portfolioColumns, p.tenant.id, before.UTC())
+}
+
+func (p *sqlitePortfolioRepository) UpdatePortfolio(ctx context.Context, model *models.Portfolio) (*models.Portfolio, error) {
+	var updated *models.Portfolio
+
+	err := inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		var err error
+		updated, err = p.update(ctx, tx, model)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (p *sqlitePortfolioRepository) ApplyPortfolioOperations(ctx context.Context, operations []PortfolioOperation, atomic bool) ([]PortfolioOperationResult, error) {
+	return applySQLOperations(ctx, p.db, p, operations, atomic)
+}
+
+func (p *sqlitePortfolioRepository) UpdatePortfolioFunc(ctx context.Context, id int, update func(*models.Portfolio) error) (*models.Portfolio, error) {
+	var updated *models.Portfolio
+
+	err := inTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		before, err := selectForChange(ctx, tx, p.tenant, id, false)
+
+		if err != nil {
+			return err
+		}
+
+		model := *before
+
+		if err := update(&model); err != nil {
+			return err
+		}
+
+		model.Id = id
+		updated, err = p.save(ctx, tx, before, &model)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (p *sqlitePortfolioRepository) update(ctx context.Context, tx *sql.Tx, model *models.Portfolio) (*models.Portfolio, error) {
+	before, err := selectForChange(ctx, tx, p.tenant, model.Id, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if model.Version != 0 && model.Version != before.Version {
+		return nil, ErrPortfolioVersionMismatch
+	}
+
+	return p.save(ctx, tx, before, model)
+}
+
+// save writes the fields of model over before, which the transaction has read.
+func (p *sqlitePortfolioRepository) save(ctx context.Context, tx *sql.Tx, before *models.Portfolio, model *models.Portfolio) (*models.Portfolio, error) {
+	row := tx.QueryRowContext(
+		ctx,
+		`UPDATE portfolios
+		SET name = ?, is_internal = ?, is_finance = ?, is_active = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND tenant_id = ?
+		RETURNING `+portfolioColumns,
+		model.Name, model.IsInternal, model.IsFinance, model.IsActive, time.Now().UTC(), before.Id, p.tenant.id,
+	)
+
+	updated, err := scanPortfolio(row)
+
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+
+	if err := recordChange(ctx, tx, p.tenant, models.AuditUpdate, before, updated); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func mapSQLiteError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPortfolioNotFound
+	}
+
+	var sqliteErr *sqlite.Error
+
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return ErrPortfolioAlreadyExists
+	}
+
+	return err
+}
+
+// NewSQLitePortfolioRepository returns a repository of the portfolios that belong to the tenant.
+func NewSQLitePortfolioRepository(db *sql.DB, tenant string) *sqlitePortfolioRepository {
+	return &sqlitePortfolioRepository{
+		db:     db,
+		tenant: sqlTenant{dialect: sqliteDialect, id: tenant},
+	}
+}
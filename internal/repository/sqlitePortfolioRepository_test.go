@@ -0,0 +1,38 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/database"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/migrations"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/repotest"
+	"github.com/stretchr/testify/require"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "portfolios.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db, database.DriverSQLite)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(context.Background()))
+
+	return db
+}
+
+func TestSQLitePortfolioRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.PortfolioRepository {
+		return repository.NewSQLitePortfolioRepository(openSQLite(t), repository.DefaultTenant)
+	})
+}
+
+func TestSQLitePortfolioStore(t *testing.T) {
+	repotest.RunIsolation(t, func(t *testing.T) repository.PortfolioStore {
+		return repository.NewSQLitePortfolioStore(openSQLite(t))
+	})
+}
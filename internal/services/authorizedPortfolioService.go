@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/auth"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// authorizedPortfolioService checks that the principal in the context may run an operation
+// before it is passed on, and responds with 403 otherwise. The operation only sees and changes
+// the portfolios that are visible to the principal.
+type authorizedPortfolioService struct {
+	portfolioService PortfolioService
+	policy           auth.Policy
+}
+
+// authorize returns the context for an operation with the permissions, in which the repository hides
+// the portfolios that the principal may not see.
+func (s *authorizedPortfolioService) authorize(ctx context.Context, permissions ...auth.Permission) (context.Context, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Not allowed to access portfolios")
+	}
+
+	for _, permission := range permissions {
+		if !s.policy.Allows(principal, permission) {
+			return nil, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Not allowed to %s portfolios", permission))
+		}
+	}
+
+	return repository.ContextWithVisibility(ctx, visibilityOf(principal)), nil
+}
+
+// visibilityOf hides internal portfolios from external principals, which lack the internal scope,
+// and finance portfolios from principals without the finance scope.
+func visibilityOf(principal *auth.Principal) repository.Visibility {
+	return repository.Visibility{
+		HideInternal: !principal.HasScope(auth.ScopeInternal),
+		HideFinance:  !principal.HasScope(auth.ScopeFinance),
+	}
+}
+
+func (s *authorizedPortfolioService) CreatePortfolio(ctx context.Context, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionCreate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.CreatePortfolio(ctx, body)
+}
+
+func (s *authorizedPortfolioService) AuthorizeCreatePortfolio(ctx context.Context) error {
+	ctx, err := s.authorize(ctx, auth.PermissionCreate)
+
+	if err != nil {
+		return err
+	}
+
+	return s.portfolioService.AuthorizeCreatePortfolio(ctx)
+}
+
+func (s *authorizedPortfolioService) UpdatePortfolio(ctx context.Context, id string, ifMatch string, body *requests.UpdatePortfolioRequest) (*models.Portfolio, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionUpdate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.UpdatePortfolio(ctx, id, ifMatch, body)
+}
+
+func (s *authorizedPortfolioService) MergePatchPortfolio(ctx context.Context, id string, ifMatch string, body []byte) (*models.Portfolio, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionUpdate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.MergePatchPortfolio(ctx, id, ifMatch, body)
+}
+
+func (s *authorizedPortfolioService) JSONPatchPortfolio(ctx context.Context, id string, ifMatch string, body []byte) (*models.Portfolio, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionUpdate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.JSONPatchPortfolio(ctx, id, ifMatch, body)
+}
+
+func (s *authorizedPortfolioService) GetPortfolios(ctx context.Context, query *requests.GetPortfoliosRequest) (*responses.PortfoliosResponse, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionRead)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.GetPortfolios(ctx, query)
+}
+
+func (s *authorizedPortfolioService) StreamPortfolios(ctx context.Context, query *requests.GetPortfoliosRequest, yield func(*models.Portfolio) error) error {
+	ctx, err := s.authorize(ctx, auth.PermissionRead)
+
+	if err != nil {
+		return err
+	}
+
+	return s.portfolioService.StreamPortfolios(ctx, query, yield)
+}
+
+func (s *authorizedPortfolioService) GetPortfolioById(ctx context.Context, id string) (*models.Portfolio, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionRead)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.GetPortfolioById(ctx, id)
+}
+
+func (s *authorizedPortfolioService) GetPortfolioAsOf(ctx context.Context, id string, asOf time.Time) (*models.Portfolio, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionRead)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.GetPortfolioAsOf(ctx, id, asOf)
+}
+
+// DeletePortfolio needs the purge permission to delete permanently.
+func (s *authorizedPortfolioService) DeletePortfolio(ctx context.Context, id string, ifMatch string, hard bool) error {
+	permission := auth.PermissionDelete
+
+	if hard {
+		permission = auth.PermissionPurge
+	}
+
+	ctx, err := s.authorize(ctx, permission)
+
+	if err != nil {
+		return err
+	}
+
+	return s.portfolioService.DeletePortfolio(ctx, id, ifMatch, hard)
+}
+
+// RestorePortfolio undoes a delete, so it needs the delete permission.
+func (s *authorizedPortfolioService) RestorePortfolio(ctx context.Context, id string) (*models.Portfolio, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionDelete)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.RestorePortfolio(ctx, id)
+}
+
+func (s *authorizedPortfolioService) GetPortfolioHistory(ctx context.Context, id string, query *requests.GetPortfolioHistoryRequest) (*responses.PortfolioHistoryResponse, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionRead)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.GetPortfolioHistory(ctx, id, query)
+}
+
+func (s *authorizedPortfolioService) DiffPortfolio(ctx context.Context, id string, query *requests.GetPortfolioDiffRequest) (*responses.PortfolioDiffResponse, error) {
+	ctx, err := s.authorize(ctx, auth.PermissionRead)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.DiffPortfolio(ctx, id, query)
+}
+
+// BatchPortfolios needs the permissions of all operations in the batch, so none of them runs if one is not allowed.
+func (s *authorizedPortfolioService) BatchPortfolios(ctx context.Context, body *requests.BatchPortfoliosRequest) (*responses.BatchPortfoliosResponse, error) {
+	permissions := make([]auth.Permission, 0, len(body.Operations))
+
+	for _, operation := range body.Operations {
+		// Unknown operations are left to the validation of the batch.
+		switch repository.PortfolioOperationType(operation.Op) {
+		case repository.OperationCreate:
+			permissions = append(permissions, auth.PermissionCreate)
+		case repository.OperationUpdate:
+			permissions = append(permissions, auth.PermissionUpdate)
+		case repository.OperationDelete:
+			permissions = append(permissions, auth.PermissionDelete)
+		}
+	}
+
+	ctx, err := s.authorize(ctx, permissions...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.BatchPortfolios(ctx, body)
+}
+
+func (s *authorizedPortfolioService) ExportPortfolios(ctx context.Context, query *requests.ExportPortfoliosRequest, yield func([]*models.Portfolio) error) error {
+	ctx, err := s.authorize(ctx, auth.PermissionRead)
+
+	if err != nil {
+		return err
+	}
+
+	return s.portfolioService.ExportPortfolios(ctx, query, yield)
+}
+
+// ImportPortfolios needs the update permission as well to upsert.
+func (s *authorizedPortfolioService) ImportPortfolios(ctx context.Context, query *requests.ImportPortfoliosRequest, file io.Reader) (*responses.ImportPortfoliosResponse, error) {
+	permissions := []auth.Permission{auth.PermissionCreate}
+
+	if query.Mode == importModeUpsert {
+		permissions = append(permissions, auth.PermissionUpdate)
+	}
+
+	ctx, err := s.authorize(ctx, permissions...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.portfolioService.ImportPortfolios(ctx, query, file)
+}
+
+// NewAuthorizedPortfolioService runs the operations of portfolioService that the policy allows
+// the principal of the context. Requests without a principal are not allowed anything.
+func NewAuthorizedPortfolioService(portfolioService PortfolioService, policy auth.Policy) *authorizedPortfolioService {
+	return &authorizedPortfolioService{
+		portfolioService,
+		policy,
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/go-playground/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// BatchPortfolios validates every operation like the single endpoints do and applies the valid
+// ones in one repository call. In an atomic batch an invalid operation fails the whole batch
+// before anything is applied.
+func (s *portfolioService) BatchPortfolios(ctx context.Context, body *requests.BatchPortfoliosRequest) (*responses.BatchPortfoliosResponse, error) {
+	validate := validator.New()
+
+	if err := validate.Struct(body); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors[0])
+	}
+
+	response := &responses.BatchPortfoliosResponse{
+		Succeeded: true,
+		Results:   make([]*responses.BatchPortfolioResult, len(body.Operations)),
+	}
+
+	operations := make([]repository.PortfolioOperation, 0, len(body.Operations))
+	// indexes maps the valid operations to their position in the request.
+	indexes := make([]int, 0, len(body.Operations))
+
+	for i := range body.Operations {
+		operation, err := s.batchOperation(&body.Operations[i])
+
+		if err != nil {
+			response.Succeeded = false
+			response.Results[i] = batchErrorResult(err)
+			continue
+		}
+
+		operations = append(operations, operation)
+		indexes = append(indexes, i)
+	}
+
+	if body.Atomic && !response.Succeeded {
+		for _, i := range indexes {
+			response.Results[i] = batchErrorResult(repository.ErrPortfolioBatchRolledBack)
+		}
+
+		return response, nil
+	}
+
+	results, err := s.portfolioRepository.ApplyPortfolioOperations(ctx, operations, body.Atomic)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for j, result := range results {
+		i := indexes[j]
+
+		if result.Err != nil {
+			response.Succeeded = false
+			response.Results[i] = batchErrorResult(result.Err)
+			continue
+		}
+
+		response.Results[i] = &responses.BatchPortfolioResult{
+			Status:    batchSuccessStatus(operations[j].Type),
+			Portfolio: result.Portfolio,
+		}
+	}
+
+	return response, nil
+}
+
+func (s *portfolioService) batchOperation(operation *requests.BatchPortfolioOperation) (repository.PortfolioOperation, error) {
+	if operation.Version < 0 {
+		return repository.PortfolioOperation{}, echo.NewHTTPError(http.StatusBadRequest, "version must not be negative")
+	}
+
+	switch repository.PortfolioOperationType(operation.Op) {
+	case repository.OperationCreate:
+		if operation.Id != 0 || operation.Version != 0 {
+			return repository.PortfolioOperation{}, echo.NewHTTPError(http.StatusBadRequest, "id and version are assigned on create")
+		}
+
+		body := &requests.CreatePortfolioRequest{
+			Name:       operation.Name,
+			IsInternal: operation.IsInternal,
+			IsFinance:  operation.IsFinance,
+			IsActive:   operation.IsActive,
+		}
+
+		if err := s.validatePortfolioCreateRequest(body); err != nil {
+			return repository.PortfolioOperation{}, err
+		}
+
+		return repository.PortfolioOperation{Type: repository.OperationCreate, Create: body}, nil
+	case repository.OperationUpdate:
+		body := &requests.UpdatePortfolioRequest{
+			Id:         operation.Id,
+			Name:       operation.Name,
+			IsInternal: operation.IsInternal,
+			IsFinance:  operation.IsFinance,
+			IsActive:   operation.IsActive,
+		}
+
+		if err := s.validatePortfolioUpdateRequest(strconv.Itoa(operation.Id), body); err != nil {
+			return repository.PortfolioOperation{}, err
+		}
+
+		return repository.PortfolioOperation{
+			Type: repository.OperationUpdate,
+			Update: &models.Portfolio{
+				Id:         body.Id,
+				Name:       body.Name,
+				IsInternal: body.IsInternal,
+				IsFinance:  body.IsFinance,
+				IsActive:   body.IsActive,
+				Version:    operation.Version,
+			},
+		}, nil
+	case repository.OperationDelete:
+		if operation.Id < 1 {
+			return repository.PortfolioOperation{}, echo.NewHTTPError(http.StatusBadRequest, "id is required")
+		}
+
+		return repository.PortfolioOperation{Type: repository.OperationDelete, Id: operation.Id, Version: operation.Version}, nil
+	default:
+		return repository.PortfolioOperation{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown op %q, expected create, update or delete", operation.Op))
+	}
+}
+
+func batchSuccessStatus(operationType repository.PortfolioOperationType) int {
+	switch operationType {
+	case repository.OperationCreate:
+		return http.StatusCreated
+	case repository.OperationDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+// batchErrorResult responds to a failed operation like the single endpoint would.
+func batchErrorResult(err error) *responses.BatchPortfolioResult {
+	switch {
+	case errors.Is(err, repository.ErrPortfolioNotFound):
+		err = echo.NewHTTPError(http.StatusNotFound, "Portfolio not found")
+	case errors.Is(err, repository.ErrPortfolioAlreadyExists):
+		err = echo.NewHTTPError(http.StatusConflict, "Portfolio with this name already exists")
+	case errors.Is(err, repository.ErrPortfolioVersionMismatch):
+		err = errPreconditionFailed
+	case errors.Is(err, repository.ErrPortfolioNotVisible):
+		err = errPortfolioNotVisible
+	case errors.Is(err, repository.ErrPortfolioBatchRolledBack):
+		err = echo.NewHTTPError(http.StatusFailedDependency, "Not applied because another operation failed")
+	}
+
+	var httpError *echo.HTTPError
+
+	if !errors.As(err, &httpError) {
+		return &responses.BatchPortfolioResult{Status: http.StatusInternalServerError, Error: "Internal Server Error"}
+	}
+
+	return &responses.BatchPortfolioResult{Status: httpError.Code, Error: fmt.Sprint(httpError.Message)}
+}
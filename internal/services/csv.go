@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/go-playground/validator"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	exportPageSize = 500
+	maxImportRows  = 10000
+)
+
+const (
+	importModeCreate = "create"
+	importModeUpsert = "upsert"
+)
+
+// PortfolioCSVHeader is the header row of exported files. Imports read name and the flags,
+// the other columns are read-only and ignored, so an export can be imported back.
+var PortfolioCSVHeader = []string{"id", "name", "isInternal", "isFinance", "isActive", "createdAt", "updatedAt", "version"}
+
+var readOnlyCSVColumns = map[string]bool{"id": true, "createdAt": true, "updatedAt": true, "version": true}
+
+// PortfolioCSVRecord formats the portfolio as a row under PortfolioCSVHeader.
+func PortfolioCSVRecord(portfolio *models.Portfolio) []string {
+	return []string{
+		strconv.Itoa(portfolio.Id),
+		escapeCSVFormula(portfolio.Name),
+		strconv.FormatBool(portfolio.IsInternal),
+		strconv.FormatBool(portfolio.IsFinance),
+		strconv.FormatBool(portfolio.IsActive),
+		formatCSVTime(portfolio.CreatedAt),
+		formatCSVTime(portfolio.UpdatedAt),
+		strconv.Itoa(portfolio.Version),
+	}
+}
+
+// csvEscapedPrefixes start the cells that spreadsheet applications evaluate as formulas,
+// and the quote that escapes them.
+const csvEscapedPrefixes = "=+-@\t\r'"
+
+// escapeCSVFormula prefixes cells that would be evaluated as formulas with a quote,
+// which spreadsheet applications show as text. Cells that start with a quote are escaped
+// as well, so that unescapeCSVFormula restores every exported name exactly on import.
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune(csvEscapedPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+func unescapeCSVFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(csvEscapedPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+
+	return value
+}
+
+func formatCSVTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+// ExportPortfolios passes all portfolios that match the filters, ordered by id, to write
+// one page at a time. After the query is validated write is called at least once,
+// with no portfolios if none match.
+func (s *portfolioService) ExportPortfolios(ctx context.Context, query *requests.ExportPortfoliosRequest, write func([]*models.Portfolio) error) error {
+	validate := validator.New()
+
+	if err := validate.Struct(query); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return echo.NewHTTPError(http.StatusBadRequest, errors[0])
+	}
+
+	if err := validatePortfolioFilter(&query.PortfolioFilterRequest); err != nil {
+		return err
+	}
+
+	var after *models.Portfolio
+
+	for {
+		page, err := s.portfolioRepository.ListPortfolios(ctx, &repository.PortfolioQuery{
+			Filter: portfolioFilter(&query.PortfolioFilterRequest),
+			Limit:  exportPageSize,
+			After:  after,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if err := write(page.Items); err != nil {
+			return err
+		}
+
+		if !page.HasMore || len(page.Items) == 0 {
+			return nil
+		}
+
+		after = page.Items[len(page.Items)-1]
+	}
+}
+
+// importRow is a parsed row with the operation it turns into.
+type importRow struct {
+	result    *responses.ImportPortfolioRow
+	operation repository.PortfolioOperation
+}
+
+// ImportPortfolios creates, or with the upsert mode also updates, a portfolio for every valid row
+// of the CSV file. Rows are validated like CreatePortfolioRequest, invalid rows are reported
+// and skipped. A file that cannot be read as a whole is rejected with 400.
+func (s *portfolioService) ImportPortfolios(ctx context.Context, query *requests.ImportPortfoliosRequest, file io.Reader) (*responses.ImportPortfoliosResponse, error) {
+	validate := validator.New()
+
+	if err := validate.Struct(query); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors[0])
+	}
+
+	rows, err := s.parseImport(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var existing map[string]*models.Portfolio
+
+	if query.DryRun || query.Mode == importModeUpsert {
+		if existing, err = s.portfoliosByName(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	response := &responses.ImportPortfoliosResponse{
+		DryRun: query.DryRun,
+		Rows:   make([]*responses.ImportPortfolioRow, 0, len(rows)),
+	}
+
+	operations := make([]repository.PortfolioOperation, 0, len(rows))
+	// applied maps the operations to their rows.
+	applied := make([]*responses.ImportPortfolioRow, 0, len(rows))
+
+	for _, row := range rows {
+		response.Rows = append(response.Rows, row.result)
+
+		if row.result.Status != 0 {
+			continue
+		}
+
+		if stored, ok := existing[row.result.Name]; ok {
+			if query.Mode != importModeUpsert {
+				setImportError(row.result, repository.ErrPortfolioAlreadyExists)
+				continue
+			}
+
+			create := row.operation.Create
+			row.operation = repository.PortfolioOperation{
+				Type: repository.OperationUpdate,
+				Update: &models.Portfolio{
+					Id:         stored.Id,
+					Name:       create.Name,
+					IsInternal: create.IsInternal,
+					IsFinance:  create.IsFinance,
+					IsActive:   create.IsActive,
+					Version:    stored.Version,
+				},
+			}
+		}
+
+		row.result.Action = string(row.operation.Type)
+		row.result.Status = batchSuccessStatus(row.operation.Type)
+		operations = append(operations, row.operation)
+		applied = append(applied, row.result)
+	}
+
+	if !query.DryRun && len(operations) > 0 {
+		results, err := s.portfolioRepository.ApplyPortfolioOperations(ctx, operations, false)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i, result := range results {
+			if result.Err != nil {
+				setImportError(applied[i], result.Err)
+				continue
+			}
+
+			applied[i].Portfolio = result.Portfolio
+		}
+	}
+
+	for _, row := range response.Rows {
+		switch {
+		case row.Error != "":
+			response.Failed++
+		case row.Action == string(repository.OperationCreate):
+			response.Created++
+		default:
+			response.Updated++
+		}
+	}
+
+	return response, nil
+}
+
+// parseImport reads the header and turns every row into a create operation,
+// or into a result with the status and error of an invalid row.
+func (s *portfolioService) parseImport(file io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if errors.Is(err, io.EOF) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "CSV file must start with a header row")
+	}
+
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	columns, err := parseImportHeader(header)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]*importRow, 0)
+	// names maps names to the line that uses them first.
+	names := map[string]int{}
+
+	for {
+		record, err := reader.Read()
+
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+
+		if err != nil {
+			return nil, csvError(err)
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("CSV file must not have more than %d rows", maxImportRows))
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := &importRow{result: &responses.ImportPortfolioRow{Line: line}}
+		rows = append(rows, row)
+
+		if len(record) != len(header) {
+			setImportError(row.result, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected %d fields, got %d", len(header), len(record))))
+			continue
+		}
+
+		body, err := s.parseImportRecord(columns, record)
+		row.result.Name = body.Name
+
+		if err != nil {
+			setImportError(row.result, err)
+			continue
+		}
+
+		if first, ok := names[body.Name]; ok {
+			setImportError(row.result, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Name is already used on line %d", first)))
+			continue
+		}
+
+		names[body.Name] = line
+		row.operation = repository.PortfolioOperation{Type: repository.OperationCreate, Create: body}
+	}
+}
+
+// parseImportHeader maps the writable columns to their positions.
+func parseImportHeader(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	seen := map[string]bool{}
+
+	for i, column := range header {
+		if i == 0 {
+			// Spreadsheet applications often start UTF-8 files with a byte order mark.
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+
+		column = strings.TrimSpace(column)
+
+		if seen[column] {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Duplicate column %q", column))
+		}
+
+		seen[column] = true
+
+		switch {
+		case readOnlyCSVColumns[column]:
+			continue
+		case column == "name" || column == "isInternal" || column == "isFinance" || column == "isActive":
+			columns[column] = i
+		default:
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown column %q", column))
+		}
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Column name is required")
+	}
+
+	return columns, nil
+}
+
+func (s *portfolioService) parseImportRecord(columns map[string]int, record []string) (*requests.CreatePortfolioRequest, error) {
+	body := &requests.CreatePortfolioRequest{Name: unescapeCSVFormula(record[columns["name"]])}
+	flags := []struct {
+		column string
+		value  *bool
+	}{
+		{"isInternal", &body.IsInternal},
+		{"isFinance", &body.IsFinance},
+		{"isActive", &body.IsActive},
+	}
+
+	for _, flag := range flags {
+		i, ok := columns[flag.column]
+
+		if !ok || strings.TrimSpace(record[i]) == "" {
+			continue
+		}
+
+		value, err := strconv.ParseBool(strings.TrimSpace(record[i]))
+
+		if err != nil {
+			return body, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be true or false", flag.column))
+		}
+
+		*flag.value = value
+	}
+
+	if err := s.validatePortfolioCreateRequest(body); err != nil {
+		return body, err
+	}
+
+	return body, nil
+}
+
+func (s *portfolioService) portfoliosByName(ctx context.Context) (map[string]*models.Portfolio, error) {
+	portfolios, err := s.portfolioRepository.GetPortfolios(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*models.Portfolio, len(portfolios))
+
+	for _, portfolio := range portfolios {
+		byName[portfolio.Name] = portfolio
+	}
+
+	return byName, nil
+}
+
+// csvError responds with 400 to malformed files, errors of the reader itself are returned as they are.
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+
+	if errors.As(err, &parseErr) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid CSV: "+parseErr.Error())
+	}
+
+	return err
+}
+
+func setImportError(row *responses.ImportPortfolioRow, err error) {
+	result := batchErrorResult(err)
+	row.Action = ""
+	row.Status = result.Status
+	row.Error = result.Error
+}
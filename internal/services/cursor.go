@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// portfolioCursor is the position after the last item of a page: its id and
+// the values of the fields the page is sorted by. Clients receive it as an
+// opaque base64 string.
+type portfolioCursor struct {
+	Sort       string     `json:"sort,omitempty"`
+	Id         int        `json:"id"`
+	Name       *string    `json:"name,omitempty"`
+	IsInternal *bool      `json:"isInternal,omitempty"`
+	IsFinance  *bool      `json:"isFinance,omitempty"`
+	IsActive   *bool      `json:"isActive,omitempty"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+}
+
+func newPortfolioCursor(keys []repository.PortfolioSortKey, last *models.Portfolio) *portfolioCursor {
+	cursor := &portfolioCursor{Sort: formatSort(keys), Id: last.Id}
+
+	for _, key := range keys {
+		switch key.Field {
+		case repository.SortByName:
+			cursor.Name = &last.Name
+		case repository.SortByIsInternal:
+			cursor.IsInternal = &last.IsInternal
+		case repository.SortByIsFinance:
+			cursor.IsFinance = &last.IsFinance
+		case repository.SortByIsActive:
+			cursor.IsActive = &last.IsActive
+		case repository.SortByCreatedAt:
+			cursor.CreatedAt = last.CreatedAt
+		case repository.SortByUpdatedAt:
+			cursor.UpdatedAt = last.UpdatedAt
+		}
+	}
+
+	return cursor
+}
+
+// after returns the portfolio to continue after. The cursor must have been
+// issued for the same sort order and carry every sorted field.
+func (c *portfolioCursor) after(keys []repository.PortfolioSortKey) (*models.Portfolio, error) {
+	if c.Sort != formatSort(keys) {
+		return nil, errInvalidCursor
+	}
+
+	for _, key := range keys {
+		if !c.has(key.Field) {
+			return nil, errInvalidCursor
+		}
+	}
+
+	after := &models.Portfolio{Id: c.Id, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+
+	if c.Name != nil {
+		after.Name = *c.Name
+	}
+
+	if c.IsInternal != nil {
+		after.IsInternal = *c.IsInternal
+	}
+
+	if c.IsFinance != nil {
+		after.IsFinance = *c.IsFinance
+	}
+
+	if c.IsActive != nil {
+		after.IsActive = *c.IsActive
+	}
+
+	return after, nil
+}
+
+func (c *portfolioCursor) has(field repository.PortfolioSortField) bool {
+	switch field {
+	case repository.SortByName:
+		return c.Name != nil
+	case repository.SortByIsInternal:
+		return c.IsInternal != nil
+	case repository.SortByIsFinance:
+		return c.IsFinance != nil
+	case repository.SortByIsActive:
+		return c.IsActive != nil
+	case repository.SortByCreatedAt:
+		return c.CreatedAt != nil
+	case repository.SortByUpdatedAt:
+		return c.UpdatedAt != nil
+	}
+
+	return true
+}
+
+func encodeCursor(cursor *portfolioCursor) string {
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*portfolioCursor, error) {
+	cursor := &portfolioCursor{}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
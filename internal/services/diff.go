@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/patch"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/go-playground/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// DiffPortfolio compares two versions of a portfolio field by field. A field that changed
+// in between and changed back is not listed, the others carry the time of their last change.
+func (s *portfolioService) DiffPortfolio(ctx context.Context, id string, query *requests.GetPortfolioDiffRequest) (*responses.PortfolioDiffResponse, error) {
+	if err := s.validatePortfolioId(id); err != nil {
+		return nil, err
+	}
+
+	validate := validator.New()
+
+	if err := validate.Struct(query); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors[0])
+	}
+
+	idInt, _ := strconv.Atoi(id)
+	versions, err := s.portfolioRepository.GetPortfolioVersions(ctx, idInt, query.From, query.To)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, version := range []int{query.From, query.To} {
+		if len(versions) == 0 || versions[0].Version > version || versions[len(versions)-1].Version < version {
+			return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Portfolio version %d not found", version))
+		}
+	}
+
+	// changedAt maps every changed field to the index of the last version that changed it.
+	changedAt := map[string]int{}
+
+	for i := 1; i < len(versions); i++ {
+		for _, change := range repository.DiffPortfolios(versions[i-1], versions[i]) {
+			changedAt[change.Field] = i
+		}
+	}
+
+	from, to := versions[0], versions[len(versions)-1]
+	response := &responses.PortfolioDiffResponse{
+		PortfolioId: idInt,
+		From:        from.Version,
+		To:          to.Version,
+		Changes:     make([]*responses.PortfolioFieldChange, 0),
+	}
+
+	for _, change := range repository.DiffPortfolios(from, to) {
+		response.Changes = append(response.Changes, &responses.PortfolioFieldChange{
+			Field:     change.Field,
+			From:      change.From,
+			To:        change.To,
+			ChangedAt: versions[changedAt[change.Field]].UpdatedAt.UTC(),
+		})
+	}
+
+	return response, nil
+}
+
+// PortfolioDiffPatch renders the diff as a JSON patch (RFC 6902) that turns the JSON of version From
+// into version To. Fields that are null in a version are missing from its JSON.
+func PortfolioDiffPatch(diff *responses.PortfolioDiffResponse) patch.JSONPatch {
+	operations := make(patch.JSONPatch, 0, len(diff.Changes))
+
+	for _, change := range diff.Changes {
+		operation := patch.Operation{Op: patch.OpReplace, Path: "/" + change.Field, Value: change.To}
+
+		switch {
+		case string(change.To) == "null":
+			operation = patch.Operation{Op: patch.OpRemove, Path: "/" + change.Field}
+		case string(change.From) == "null":
+			operation.Op = patch.OpAdd
+		}
+
+		operations = append(operations, operation)
+	}
+
+	return operations
+}
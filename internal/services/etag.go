@@ -0,0 +1,38 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+)
+
+var errInvalidIfMatch = errors.New("If-Match must be * or a single strong ETag returned by this API")
+
+// PortfolioETag is the strong entity tag of the portfolio, which changes with its version.
+func PortfolioETag(portfolio *models.Portfolio) string {
+	return strconv.Quote(strconv.Itoa(portfolio.Version))
+}
+
+// parseIfMatch returns the version an If-Match header requires.
+// An empty header and * are unconditional and return 0.
+func parseIfMatch(value string) (int, error) {
+	value = strings.TrimSpace(value)
+
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+
+	if err != nil || version < 1 {
+		return 0, errInvalidIfMatch
+	}
+
+	return version, nil
+}
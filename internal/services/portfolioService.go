@@ -1,64 +1,178 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/patch"
 	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
 	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 )
 
 type PortfolioService interface {
-	CreatePortfolio(*requests.CreatePortfolioRequest) (*models.Portfolio, error)
-	UpdatePortfolio(string, *requests.UpdatePortfolioRequest) (*models.Portfolio, error)
-	GetPortfolios() ([]*models.Portfolio, error)
-	GetPortfolioById(string) (*models.Portfolio, error)
-	DeletePortfolio(string) error
+	CreatePortfolio(context.Context, *requests.CreatePortfolioRequest) (*models.Portfolio, error)
+	// AuthorizeCreatePortfolio responds with 403 if the caller may not create portfolios, so that
+	// stored responses of CreatePortfolio are only replayed to callers that may still create them.
+	AuthorizeCreatePortfolio(context.Context) error
+	// UpdatePortfolio, MergePatchPortfolio, JSONPatchPortfolio and DeletePortfolio take
+	// the If-Match header after the id and fail with 412 when it does not match the portfolio.
+	UpdatePortfolio(context.Context, string, string, *requests.UpdatePortfolioRequest) (*models.Portfolio, error)
+	MergePatchPortfolio(context.Context, string, string, []byte) (*models.Portfolio, error)
+	JSONPatchPortfolio(context.Context, string, string, []byte) (*models.Portfolio, error)
+	GetPortfolios(context.Context, *requests.GetPortfoliosRequest) (*responses.PortfoliosResponse, error)
+	StreamPortfolios(context.Context, *requests.GetPortfoliosRequest, func(*models.Portfolio) error) error
+	GetPortfolioById(context.Context, string) (*models.Portfolio, error)
+	// GetPortfolioAsOf responds with 404 if the portfolio did not exist or was deleted at the given time.
+	GetPortfolioAsOf(context.Context, string, time.Time) (*models.Portfolio, error)
+	// DeletePortfolio soft-deletes the portfolio, or permanently deletes it when hard is set.
+	DeletePortfolio(ctx context.Context, id string, ifMatch string, hard bool) error
+	RestorePortfolio(context.Context, string) (*models.Portfolio, error)
+	GetPortfolioHistory(context.Context, string, *requests.GetPortfolioHistoryRequest) (*responses.PortfolioHistoryResponse, error)
+	// DiffPortfolio responds with 404 if either version of the portfolio is not known.
+	DiffPortfolio(context.Context, string, *requests.GetPortfolioDiffRequest) (*responses.PortfolioDiffResponse, error)
+	BatchPortfolios(context.Context, *requests.BatchPortfoliosRequest) (*responses.BatchPortfoliosResponse, error)
+	ExportPortfolios(context.Context, *requests.ExportPortfoliosRequest, func([]*models.Portfolio) error) error
+	ImportPortfolios(context.Context, *requests.ImportPortfoliosRequest, io.Reader) (*responses.ImportPortfoliosResponse, error)
 }
 
+const defaultPageSize = 20
+
+var (
+	errPreconditionFailed  = echo.NewHTTPError(http.StatusPreconditionFailed, "Portfolio was changed, fetch it again to get the current ETag")
+	errPortfolioNotVisible = echo.NewHTTPError(http.StatusForbidden, "Not allowed to mark portfolios as internal or finance")
+)
+
 type portfolioService struct {
 	portfolioRepository repository.PortfolioRepository
 }
 
-func (s *portfolioService) CreatePortfolio(body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+// AuthorizeCreatePortfolio lets every caller create portfolios, authorizedPortfolioService checks the policy.
+func (s *portfolioService) AuthorizeCreatePortfolio(context.Context) error {
+	return nil
+}
+
+func (s *portfolioService) CreatePortfolio(ctx context.Context, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
 	if err := s.validatePortfolioCreateRequest(body); err != nil {
 		return nil, err
 	}
 
-	portfolio, err := s.portfolioRepository.CreatePortfolio(body)
+	portfolio, err := s.portfolioRepository.CreatePortfolio(ctx, body)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrPortfolioAlreadyExists) {
 			return nil, echo.NewHTTPError(http.StatusConflict, "Portfolio with this name already exists")
 		}
 
+		if errors.Is(err, repository.ErrPortfolioNotVisible) {
+			return nil, errPortfolioNotVisible
+		}
+
 		return nil, err
 	}
 
 	return portfolio, nil
 }
 
-func (s *portfolioService) GetPortfolios() ([]*models.Portfolio, error) {
-	portfolios, err := s.portfolioRepository.GetPortfolios()
+func (s *portfolioService) GetPortfolios(ctx context.Context, query *requests.GetPortfoliosRequest) (*responses.PortfoliosResponse, error) {
+	listQuery, err := s.listQuery(query)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return portfolios, nil
+	page, err := s.portfolioRepository.ListPortfolios(ctx, listQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &responses.PortfoliosResponse{Items: page.Items}
+
+	if page.HasMore && len(page.Items) > 0 {
+		last := page.Items[len(page.Items)-1]
+		response.NextCursor = encodeCursor(newPortfolioCursor(listQuery.Sort, last))
+	}
+
+	if query.WithTotalCount {
+		totalCount := page.TotalCount
+		response.TotalCount = &totalCount
+	}
+
+	return response, nil
 }
 
-func (s *portfolioService) GetPortfolioById(id string) (*models.Portfolio, error) {
+// StreamPortfolios passes every portfolio that matches the filters to yield, in the order
+// of sort and starting after the cursor. The limit and withTotalCount are ignored.
+func (s *portfolioService) StreamPortfolios(ctx context.Context, query *requests.GetPortfoliosRequest, yield func(*models.Portfolio) error) error {
+	listQuery, err := s.listQuery(query)
+
+	if err != nil {
+		return err
+	}
+
+	return s.portfolioRepository.StreamPortfolios(ctx, listQuery, yield)
+}
+
+// listQuery validates the request and turns it into a repository query.
+func (s *portfolioService) listQuery(query *requests.GetPortfoliosRequest) (*repository.PortfolioQuery, error) {
+	if err := s.validateGetPortfoliosRequest(query); err != nil {
+		return nil, err
+	}
+
+	keys, err := parseSort(query.Sort)
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid sort: "+err.Error())
+	}
+
+	var after *models.Portfolio
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+
+		if err == nil {
+			after, err = cursor.after(keys)
+		}
+
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
+	}
+
+	limit := query.Limit
+
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	return &repository.PortfolioQuery{
+		Filter:         portfolioFilter(&query.PortfolioFilterRequest),
+		Sort:           keys,
+		Limit:          limit,
+		After:          after,
+		WithTotalCount: query.WithTotalCount,
+		AsOf:           query.AsOf,
+	}, nil
+}
+
+func (s *portfolioService) GetPortfolioById(ctx context.Context, id string) (*models.Portfolio, error) {
 	if err := s.validatePortfolioId(id); err != nil {
 		return nil, err
 	}
 
 	idInt, _ := strconv.Atoi(id)
-	portfolio, err := s.portfolioRepository.GetPortfolioById(idInt)
+	portfolio, err := s.portfolioRepository.GetPortfolioById(ctx, idInt)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrPortfolioNotFound) {
@@ -71,17 +185,42 @@ func (s *portfolioService) GetPortfolioById(id string) (*models.Portfolio, error
 	return portfolio, nil
 }
 
-func (s *portfolioService) UpdatePortfolio(id string, body *requests.UpdatePortfolioRequest) (*models.Portfolio, error) {
+func (s *portfolioService) GetPortfolioAsOf(ctx context.Context, id string, asOf time.Time) (*models.Portfolio, error) {
 	if err := s.validatePortfolioId(id); err != nil {
 		return nil, err
 	}
 
+	idInt, _ := strconv.Atoi(id)
+	portfolio, err := s.portfolioRepository.GetPortfolioAsOf(ctx, idInt, asOf)
+
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Portfolio not found")
+		}
+
+		return nil, err
+	}
+
+	return portfolio, nil
+}
+
+func (s *portfolioService) UpdatePortfolio(ctx context.Context, id string, ifMatch string, body *requests.UpdatePortfolioRequest) (*models.Portfolio, error) {
+	if err := s.validatePortfolioId(id); err != nil {
+		return nil, err
+	}
+
+	version, err := s.validateIfMatch(ifMatch)
+
+	if err != nil {
+		return nil, err
+	}
+
 	if err := s.validatePortfolioUpdateRequest(id, body); err != nil {
 		return nil, err
 	}
 
 	idInt, _ := strconv.Atoi(id)
-	portfolio, err := s.portfolioRepository.GetPortfolioById(idInt)
+	portfolio, err := s.portfolioRepository.GetPortfolioById(ctx, idInt)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrPortfolioNotFound) {
@@ -91,47 +230,312 @@ func (s *portfolioService) UpdatePortfolio(id string, body *requests.UpdatePortf
 		return nil, err
 	}
 
+	portfolio.Version = version
+
+	return s.updatePortfolio(ctx, portfolio, body)
+}
+
+// MergePatchPortfolio applies a JSON merge patch (RFC 7396) to the portfolio.
+func (s *portfolioService) MergePatchPortfolio(ctx context.Context, id string, ifMatch string, mergePatch []byte) (*models.Portfolio, error) {
+	if err := s.validatePortfolioId(id); err != nil {
+		return nil, err
+	}
+
+	version, err := s.validateIfMatch(ifMatch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var members map[string]json.RawMessage
+
+	if err := json.Unmarshal(mergePatch, &members); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid merge patch: expected a JSON object")
+	}
+
+	for name := range members {
+		if isReadOnlyField(name) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Field %s is read-only", name))
+		}
+	}
+
+	return s.patchPortfolio(ctx, id, version, func(document []byte) ([]byte, error) {
+		patched, err := patch.MergePatch(document, mergePatch)
+
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid merge patch")
+		}
+
+		return patched, nil
+	})
+}
+
+// JSONPatchPortfolio applies a JSON patch (RFC 6902) to the portfolio.
+// Test operations can read every field, other operations can only change
+// the fields that UpdatePortfolio changes.
+func (s *portfolioService) JSONPatchPortfolio(ctx context.Context, id string, ifMatch string, jsonPatch []byte) (*models.Portfolio, error) {
+	if err := s.validatePortfolioId(id); err != nil {
+		return nil, err
+	}
+
+	version, err := s.validateIfMatch(ifMatch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	operations, err := patch.DecodeJSONPatch(jsonPatch)
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	for _, operation := range operations {
+		if operation.Op == patch.OpTest {
+			continue
+		}
+
+		paths := []string{operation.Path}
+
+		if operation.Op == patch.OpMove {
+			paths = append(paths, operation.From)
+		}
+
+		for _, path := range paths {
+			tokens, _ := patch.Pointer(path)
+
+			if len(tokens) == 0 || isReadOnlyField(tokens[0]) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Path %q is read-only", path))
+			}
+		}
+	}
+
+	return s.patchPortfolio(ctx, id, version, func(document []byte) ([]byte, error) {
+		patched, err := operations.Apply(document)
+
+		if errors.Is(err, patch.ErrTestFailed) {
+			return nil, echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		return patched, nil
+	})
+}
+
+// patchPortfolio applies a patch to the JSON document of the portfolio and saves the result
+// after the same validation as UpdatePortfolio. Reading, patching and saving happen atomically,
+// so the patch sees the portfolio exactly as it is when it is saved. Unless version is 0,
+// the portfolio is patched only if it still has that version.
+func (s *portfolioService) patchPortfolio(ctx context.Context, id string, version int, apply func([]byte) ([]byte, error)) (*models.Portfolio, error) {
+	idInt, _ := strconv.Atoi(id)
+
+	updatedPortfolio, err := s.portfolioRepository.UpdatePortfolioFunc(ctx, idInt, func(portfolio *models.Portfolio) error {
+		if version != 0 && version != portfolio.Version {
+			return repository.ErrPortfolioVersionMismatch
+		}
+
+		document, err := json.Marshal(portfolio)
+
+		if err != nil {
+			return err
+		}
+
+		patched, err := apply(document)
+
+		if err != nil {
+			return err
+		}
+
+		result := &models.Portfolio{}
+
+		if err := decodeStrict(patched, result); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid patch result: "+err.Error())
+		}
+
+		body := &requests.UpdatePortfolioRequest{
+			Id:         portfolio.Id,
+			Name:       result.Name,
+			IsInternal: result.IsInternal,
+			IsFinance:  result.IsFinance,
+			IsActive:   result.IsActive,
+		}
+
+		if err := s.validatePortfolioUpdateRequest(id, body); err != nil {
+			return err
+		}
+
+		portfolio.Name = body.Name
+		portfolio.IsInternal = body.IsInternal
+		portfolio.IsFinance = body.IsFinance
+		portfolio.IsActive = body.IsActive
+
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Portfolio not found")
+		}
+
+		if errors.Is(err, repository.ErrPortfolioAlreadyExists) {
+			return nil, echo.NewHTTPError(http.StatusConflict, "Portfolio with this name already exists")
+		}
+
+		if errors.Is(err, repository.ErrPortfolioNotVisible) {
+			return nil, errPortfolioNotVisible
+		}
+
+		if errors.Is(err, repository.ErrPortfolioVersionMismatch) {
+			return nil, errPreconditionFailed
+		}
+
+		return nil, err
+	}
+
+	return updatedPortfolio, nil
+}
+
+func (s *portfolioService) updatePortfolio(ctx context.Context, portfolio *models.Portfolio, body *requests.UpdatePortfolioRequest) (*models.Portfolio, error) {
 	portfolio.Name = body.Name
 	portfolio.IsActive = body.IsActive
 	portfolio.IsFinance = body.IsFinance
 	portfolio.IsInternal = body.IsInternal
 
-	updatedPortfolio, err := s.portfolioRepository.UpdatePortfolio(portfolio)
+	updatedPortfolio, err := s.portfolioRepository.UpdatePortfolio(ctx, portfolio)
 
 	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Portfolio not found")
+		}
+
 		if errors.Is(err, repository.ErrPortfolioAlreadyExists) {
 			return nil, echo.NewHTTPError(http.StatusConflict, "Portfolio with this name already exists")
 		}
 
+		if errors.Is(err, repository.ErrPortfolioNotVisible) {
+			return nil, errPortfolioNotVisible
+		}
+
+		if errors.Is(err, repository.ErrPortfolioVersionMismatch) {
+			return nil, errPreconditionFailed
+		}
+
 		return nil, err
 	}
 
 	return updatedPortfolio, nil
 }
 
-func (s *portfolioService) DeletePortfolio(id string) error {
+func (s *portfolioService) DeletePortfolio(ctx context.Context, id string, ifMatch string, hard bool) error {
 	if err := s.validatePortfolioId(id); err != nil {
 		return err
 	}
 
-	idInt, _ := strconv.Atoi(id)
-	_, err := s.portfolioRepository.GetPortfolioById(idInt)
+	version, err := s.validateIfMatch(ifMatch)
 
 	if err != nil {
+		return err
+	}
+
+	idInt, _ := strconv.Atoi(id)
+	deletePortfolio := s.portfolioRepository.DeletePortfolio
+
+	if hard {
+		deletePortfolio = s.portfolioRepository.PurgePortfolio
+	}
+
+	if err := deletePortfolio(ctx, idInt, version); err != nil {
 		if errors.Is(err, repository.ErrPortfolioNotFound) {
 			return echo.NewHTTPError(http.StatusNotFound, "Portfolio not found")
 		}
 
-		return err
-	}
+		if errors.Is(err, repository.ErrPortfolioVersionMismatch) {
+			return errPreconditionFailed
+		}
 
-	if err := s.portfolioRepository.DeletePortfolio(idInt); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+func (s *portfolioService) RestorePortfolio(ctx context.Context, id string) (*models.Portfolio, error) {
+	if err := s.validatePortfolioId(id); err != nil {
+		return nil, err
+	}
+
+	idInt, _ := strconv.Atoi(id)
+	portfolio, err := s.portfolioRepository.RestorePortfolio(ctx, idInt)
+
+	if err != nil {
+		if errors.Is(err, repository.ErrPortfolioNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Portfolio not found")
+		}
+
+		if errors.Is(err, repository.ErrPortfolioNotDeleted) {
+			return nil, echo.NewHTTPError(http.StatusConflict, "Portfolio is not deleted")
+		}
+
+		if errors.Is(err, repository.ErrPortfolioAlreadyExists) {
+			return nil, echo.NewHTTPError(http.StatusConflict, "Portfolio with this name already exists, rename it first")
+		}
+
+		return nil, err
+	}
+
+	return portfolio, nil
+}
+
+// GetPortfolioHistory pages through the audit entries of a portfolio, which are kept after it is purged.
+func (s *portfolioService) GetPortfolioHistory(ctx context.Context, id string, query *requests.GetPortfolioHistoryRequest) (*responses.PortfolioHistoryResponse, error) {
+	if err := s.validatePortfolioId(id); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateGetPortfolioHistoryRequest(query); err != nil {
+		return nil, err
+	}
+
+	historyQuery := &repository.AuditQuery{Limit: query.Limit}
+
+	if historyQuery.Limit == 0 {
+		historyQuery.Limit = defaultPageSize
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+
+		if err != nil || cursor.Sort != "" {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
+
+		historyQuery.After = cursor.Id
+	}
+
+	idInt, _ := strconv.Atoi(id)
+	page, err := s.portfolioRepository.GetPortfolioHistory(ctx, idInt, historyQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Every portfolio has at least the entry of its creation.
+	if len(page.Items) == 0 && query.Cursor == "" {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Portfolio not found")
+	}
+
+	response := &responses.PortfolioHistoryResponse{Items: page.Items}
+
+	if page.HasMore && len(page.Items) > 0 {
+		response.NextCursor = encodeCursor(&portfolioCursor{Id: page.Items[len(page.Items)-1].Id})
+	}
+
+	return response, nil
+}
+
 func (s *portfolioService) validatePortfolioCreateRequest(body *requests.CreatePortfolioRequest) error {
 	validate := validator.New()
 
@@ -160,6 +564,80 @@ func (s *portfolioService) validatePortfolioUpdateRequest(id string, body *reque
 	return nil
 }
 
+func (s *portfolioService) validateIfMatch(ifMatch string) (int, error) {
+	version, err := parseIfMatch(ifMatch)
+
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return version, nil
+}
+
+func (s *portfolioService) validateGetPortfoliosRequest(query *requests.GetPortfoliosRequest) error {
+	validate := validator.New()
+
+	if err := validate.Struct(query); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return echo.NewHTTPError(http.StatusBadRequest, errors[0])
+	}
+
+	return validatePortfolioFilter(&query.PortfolioFilterRequest)
+}
+
+func (s *portfolioService) validateGetPortfolioHistoryRequest(query *requests.GetPortfolioHistoryRequest) error {
+	validate := validator.New()
+
+	if err := validate.Struct(query); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return echo.NewHTTPError(http.StatusBadRequest, errors[0])
+	}
+
+	return nil
+}
+
+func validatePortfolioFilter(filter *requests.PortfolioFilterRequest) error {
+	if !isValidRange(filter.CreatedFrom, filter.CreatedTo) {
+		return echo.NewHTTPError(http.StatusBadRequest, "createdFrom must be before createdTo")
+	}
+
+	if !isValidRange(filter.UpdatedFrom, filter.UpdatedTo) {
+		return echo.NewHTTPError(http.StatusBadRequest, "updatedFrom must be before updatedTo")
+	}
+
+	return nil
+}
+
+func portfolioFilter(filter *requests.PortfolioFilterRequest) repository.PortfolioFilter {
+	return repository.PortfolioFilter{
+		IsActive:    filter.IsActive,
+		IsFinance:   filter.IsFinance,
+		IsInternal:  filter.IsInternal,
+		CreatedFrom: filter.CreatedFrom,
+		CreatedTo:   filter.CreatedTo,
+		UpdatedFrom: filter.UpdatedFrom,
+		UpdatedTo:   filter.UpdatedTo,
+		NamePrefix:  filter.NamePrefix,
+	}
+}
+
+// isReadOnlyField reports whether a JSON field of models.Portfolio is managed by the repository.
+func isReadOnlyField(name string) bool {
+	return name == "id" || name == "createdAt" || name == "updatedAt" || name == "deletedAt" || name == "version"
+}
+
+// decodeStrict rejects unknown fields, so patches cannot add fields that would be silently dropped.
+func decodeStrict(data []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(value)
+}
+
+func isValidRange(from, to *time.Time) bool {
+	return from == nil || to == nil || from.Before(*to)
+}
+
 func (s *portfolioService) validatePortfolioId(id string) error {
 	validate := validator.New()
 
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/labstack/gommon/log"
+)
+
+// portfolioPurger permanently deletes portfolios of all tenants that were soft-deleted longer than retention ago.
+type portfolioPurger struct {
+	portfolioStore repository.PortfolioStore
+	retention      time.Duration
+	interval       time.Duration
+	now            func() time.Time
+}
+
+// Purge deletes the portfolios that are past retention, one tenant after another, and returns
+// how many there were. It stops at the first tenant that fails.
+func (p *portfolioPurger) Purge(ctx context.Context) (int, error) {
+	before := p.now().Add(-p.retention)
+	tenants, err := p.portfolioStore.Tenants(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+
+	for _, tenant := range tenants {
+		portfolioRepository, err := p.portfolioStore.ForTenant(tenant)
+
+		if err != nil {
+			return purged, err
+		}
+
+		count, err := portfolioRepository.PurgeDeletedPortfolios(ctx, before)
+		purged += count
+
+		if err != nil {
+			return purged, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+
+	return purged, nil
+}
+
+// Run purges right away and then every interval until the context is done.
+// Failed purges are logged and retried with the next one.
+func (p *portfolioPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		purged, err := p.Purge(ctx)
+
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("purge deleted portfolios: %v", err)
+		}
+
+		if purged > 0 {
+			log.Infof("purged %d deleted portfolios", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewPortfolioPurger(portfolioStore repository.PortfolioStore, retention time.Duration, interval time.Duration) *portfolioPurger {
+	return &portfolioPurger{
+		portfolioStore: portfolioStore,
+		retention:      retention,
+		interval:       interval,
+		now:            time.Now,
+	}
+}
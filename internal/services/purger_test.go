@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	mocks "github.com/alekseyshevchenko93/go-crud-api-example/internal/repository/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PortfolioPurgerSuite struct {
+	suite.Suite
+	portfolioStore      *mocks.PortfolioStore
+	portfolioRepository *mocks.PortfolioRepository
+	purger              *portfolioPurger
+	now                 time.Time
+}
+
+func TestPortfolioPurgerSuite(t *testing.T) {
+	suite.Run(t, new(PortfolioPurgerSuite))
+}
+
+func (suite *PortfolioPurgerSuite) SetupTest() {
+	suite.portfolioStore = mocks.NewPortfolioStore(suite.T())
+	suite.portfolioRepository = mocks.NewPortfolioRepository(suite.T())
+	suite.now = time.Date(2023, 5, 31, 12, 0, 0, 0, time.UTC)
+	suite.purger = NewPortfolioPurger(suite.portfolioStore, 30*24*time.Hour, time.Millisecond)
+	suite.purger.now = func() time.Time { return suite.now }
+}
+
+// expectTenants makes the store list the tenants and return the repository mock for each of them.
+func (suite *PortfolioPurgerSuite) expectTenants(tenants ...string) {
+	suite.portfolioStore.EXPECT().Tenants(mock.Anything).Return(tenants, nil)
+
+	for _, tenant := range tenants {
+		suite.portfolioStore.EXPECT().ForTenant(tenant).Return(suite.portfolioRepository, nil)
+	}
+}
+
+func (suite *PortfolioPurgerSuite) TestPurgeUsesRetention() {
+	r := suite.Require()
+	before := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	suite.expectTenants(repository.DefaultTenant)
+	suite.portfolioRepository.EXPECT().PurgeDeletedPortfolios(mock.Anything, before).Return(3, nil).Once()
+
+	purged, err := suite.purger.Purge(context.Background())
+
+	r.NoError(err)
+	r.Equal(3, purged)
+}
+
+func (suite *PortfolioPurgerSuite) TestRunRepeatsUntilDone() {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+
+	suite.expectTenants(repository.DefaultTenant)
+
+	suite.portfolioRepository.EXPECT().
+		PurgeDeletedPortfolios(mock.Anything, mock.Anything).
+		RunAndReturn(func(context.Context, time.Time) (int, error) {
+			calls++
+
+			if calls == 3 {
+				cancel()
+			}
+
+			if calls == 1 {
+				return 0, errors.New("database is unavailable")
+			}
+
+			return 1, nil
+		})
+
+	done := make(chan struct{})
+
+	go func() {
+		suite.purger.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("purger did not stop")
+	}
+
+	suite.Equal(3, calls)
+}
+
+func (suite *PortfolioPurgerSuite) TestPurgeEveryTenant() {
+	r := suite.Require()
+	suite.expectTenants("tenant-a", "tenant-b")
+	suite.portfolioRepository.EXPECT().PurgeDeletedPortfolios(mock.Anything, mock.Anything).Return(2, nil).Twice()
+
+	purged, err := suite.purger.Purge(context.Background())
+
+	r.NoError(err)
+	r.Equal(4, purged)
+}
+
+func (suite *PortfolioPurgerSuite) TestPurgeStopsAtFailedTenant() {
+	r := suite.Require()
+	failed := errors.New("database is unavailable")
+	suite.portfolioStore.EXPECT().Tenants(mock.Anything).Return([]string{"tenant-a", "tenant-b"}, nil)
+	suite.portfolioStore.EXPECT().ForTenant("tenant-a").Return(suite.portfolioRepository, nil)
+	suite.portfolioRepository.EXPECT().PurgeDeletedPortfolios(mock.Anything, mock.Anything).Return(0, failed).Once()
+
+	_, err := suite.purger.Purge(context.Background())
+
+	r.ErrorIs(err, failed)
+	r.ErrorContains(err, "tenant-a")
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+)
+
+// parseSort parses a comma separated list of portfolio fields,
+// each optionally prefixed with "-" for descending order, e.g. "-updatedAt,name".
+func parseSort(value string) ([]repository.PortfolioSortKey, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	keys := make([]repository.PortfolioSortKey, 0, len(parts))
+	seen := map[repository.PortfolioSortField]bool{}
+
+	for _, part := range parts {
+		key := repository.PortfolioSortKey{}
+		name := strings.TrimSpace(part)
+
+		if strings.HasPrefix(name, "-") {
+			key.Desc = true
+			name = name[1:]
+		}
+
+		key.Field = repository.PortfolioSortField(name)
+
+		if !key.Field.IsValid() {
+			return nil, fmt.Errorf("unknown sort field %q", name)
+		}
+
+		if seen[key.Field] {
+			return nil, fmt.Errorf("duplicate sort field %q", name)
+		}
+
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// formatSort is the inverse of parseSort. It gives the canonical form of a sort
+// parameter, which cursors remember to reject being used with another order.
+func formatSort(keys []repository.PortfolioSortKey) string {
+	parts := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if key.Desc {
+			parts = append(parts, "-"+string(key.Field))
+		} else {
+			parts = append(parts, string(key.Field))
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/models"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/requests"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/domain/responses"
+	"github.com/alekseyshevchenko93/go-crud-api-example/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+var errMissingTenant = errors.New("context has no tenant")
+
+// tenantPortfolioService runs every operation with a portfolioService over the repository of the
+// tenant in the context, so an operation cannot reach the portfolios of other tenants.
+type tenantPortfolioService struct {
+	portfolioStore repository.PortfolioStore
+}
+
+func (s *tenantPortfolioService) service(ctx context.Context) (PortfolioService, error) {
+	tenant, ok := repository.TenantFromContext(ctx)
+
+	if !ok {
+		return nil, errMissingTenant
+	}
+
+	portfolioRepository, err := s.portfolioStore.ForTenant(tenant)
+
+	if errors.Is(err, repository.ErrInvalidTenant) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPortfolioService(portfolioRepository), nil
+}
+
+func (s *tenantPortfolioService) CreatePortfolio(ctx context.Context, body *requests.CreatePortfolioRequest) (*models.Portfolio, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.CreatePortfolio(ctx, body)
+}
+
+func (s *tenantPortfolioService) AuthorizeCreatePortfolio(ctx context.Context) error {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return service.AuthorizeCreatePortfolio(ctx)
+}
+
+func (s *tenantPortfolioService) UpdatePortfolio(ctx context.Context, id string, ifMatch string, body *requests.UpdatePortfolioRequest) (*models.Portfolio, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.UpdatePortfolio(ctx, id, ifMatch, body)
+}
+
+func (s *tenantPortfolioService) MergePatchPortfolio(ctx context.Context, id string, ifMatch string, body []byte) (*models.Portfolio, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.MergePatchPortfolio(ctx, id, ifMatch, body)
+}
+
+func (s *tenantPortfolioService) JSONPatchPortfolio(ctx context.Context, id string, ifMatch string, body []byte) (*models.Portfolio, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.JSONPatchPortfolio(ctx, id, ifMatch, body)
+}
+
+func (s *tenantPortfolioService) GetPortfolios(ctx context.Context, query *requests.GetPortfoliosRequest) (*responses.PortfoliosResponse, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.GetPortfolios(ctx, query)
+}
+
+func (s *tenantPortfolioService) StreamPortfolios(ctx context.Context, query *requests.GetPortfoliosRequest, yield func(*models.Portfolio) error) error {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return service.StreamPortfolios(ctx, query, yield)
+}
+
+func (s *tenantPortfolioService) GetPortfolioById(ctx context.Context, id string) (*models.Portfolio, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.GetPortfolioById(ctx, id)
+}
+
+func (s *tenantPortfolioService) GetPortfolioAsOf(ctx context.Context, id string, asOf time.Time) (*models.Portfolio, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.GetPortfolioAsOf(ctx, id, asOf)
+}
+
+func (s *tenantPortfolioService) DeletePortfolio(ctx context.Context, id string, ifMatch string, hard bool) error {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return service.DeletePortfolio(ctx, id, ifMatch, hard)
+}
+
+func (s *tenantPortfolioService) RestorePortfolio(ctx context.Context, id string) (*models.Portfolio, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.RestorePortfolio(ctx, id)
+}
+
+func (s *tenantPortfolioService) GetPortfolioHistory(ctx context.Context, id string, query *requests.GetPortfolioHistoryRequest) (*responses.PortfolioHistoryResponse, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.GetPortfolioHistory(ctx, id, query)
+}
+
+func (s *tenantPortfolioService) DiffPortfolio(ctx context.Context, id string, query *requests.GetPortfolioDiffRequest) (*responses.PortfolioDiffResponse, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.DiffPortfolio(ctx, id, query)
+}
+
+func (s *tenantPortfolioService) BatchPortfolios(ctx context.Context, body *requests.BatchPortfoliosRequest) (*responses.BatchPortfoliosResponse, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.BatchPortfolios(ctx, body)
+}
+
+func (s *tenantPortfolioService) ExportPortfolios(ctx context.Context, query *requests.ExportPortfoliosRequest, write func([]*models.Portfolio) error) error {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return service.ExportPortfolios(ctx, query, write)
+}
+
+func (s *tenantPortfolioService) ImportPortfolios(ctx context.Context, query *requests.ImportPortfoliosRequest, file io.Reader) (*responses.ImportPortfoliosResponse, error) {
+	service, err := s.service(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service.ImportPortfolios(ctx, query, file)
+}
+
+// NewTenantPortfolioService returns a service over the portfolios of the tenant that
+// repository.ContextWithTenant puts in the context of each operation.
+func NewTenantPortfolioService(portfolioStore repository.PortfolioStore) *tenantPortfolioService {
+	return &tenantPortfolioService{
+		portfolioStore: portfolioStore,
+	}
+}
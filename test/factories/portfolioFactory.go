@@ -33,6 +33,7 @@ func GetPortfolio() *models.Portfolio {
 		IsInternal: GetRandomBool(),
 		CreatedAt:  &now,
 		UpdatedAt:  &now,
+		Version:    1,
 	}
 
 	return &portfolio